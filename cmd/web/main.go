@@ -1,28 +1,43 @@
 package main
 
 import (
+	"context"
+	"crypto/rand"
 	"fmt"
 	"github.com/rs/zerolog"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/rs/zerolog/log"
 
+	"github.com/vlad19930514/webApp/internal/app/auth"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"github.com/vlad19930514/webApp/internal/app/migrations"
+	sqlitemigrations "github.com/vlad19930514/webApp/internal/app/migrations/sqlite"
+	"github.com/vlad19930514/webApp/internal/app/outbox"
+	"github.com/vlad19930514/webApp/internal/app/repository/memrepo"
 	"github.com/vlad19930514/webApp/internal/app/repository/pgrepo"
+	"github.com/vlad19930514/webApp/internal/app/repository/sqliterepo"
 	"github.com/vlad19930514/webApp/internal/app/services"
 	"github.com/vlad19930514/webApp/internal/app/transport/httpserver"
+	"github.com/vlad19930514/webApp/internal/app/webhook"
 	pg "github.com/vlad19930514/webApp/internal/pkg/pg"
+	"github.com/vlad19930514/webApp/internal/pkg/sqlite"
 	"github.com/vlad19930514/webApp/util"
-
-	_ "github.com/golang-migrate/migrate/v4/database/postgres"
-	_ "github.com/golang-migrate/migrate/v4/source/file"
 )
 
 func main() {
 	log.Logger = log.Output(zerolog.ConsoleWriter{Out: os.Stderr, TimeFormat: time.RFC3339}).With().Caller().Logger()
-	err := runApplication()
+	var err error
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		err = runMigrate(os.Args[2:])
+	} else {
+		err = runApplication()
+	}
 	if err != nil {
-		log.Fatal().Err(err)
+		log.Fatal().Err(err).Msg("application stopped")
 	}
 }
 
@@ -31,26 +46,238 @@ func runApplication() error {
 	if err != nil {
 		return fmt.Errorf("cannot load config: %w", err)
 	}
+	// the server and the background workers stop on SIGINT and SIGTERM
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
 
-	pgDB, err := pg.Dial(config.DSN)
-
+	// create repositories
+	store, err := newStorage(config)
 	if err != nil {
-		return fmt.Errorf("error creating connection pool: %w", err)
+		return fmt.Errorf("failed to create storage: %w", err)
 	}
+	// create services
+	userService := services.NewUserService(store.users, store.audit, store.outbox, store.tx)
+	webhookService := services.NewWebhookService(store.webhooks)
+	tokens, err := newTokens(config)
+	if err != nil {
+		return fmt.Errorf("failed to create token issuer: %w", err)
+	}
+	authService := services.NewAuthService(store.users, userService, store.credentials, store.sessions,
+		auth.NewHasher(auth.DefaultArgon2Params, config.PasswordHashConcurrency), tokens, store.tx, config.RefreshTokenTTL)
 
-	// create repositories
-	userRepo, err := pgrepo.NewUserRepo(pgDB)
+	publisher, err := newPublisher(config)
 	if err != nil {
-		return fmt.Errorf("failed to create userRepo: %w", err)
+		return fmt.Errorf("failed to create event publisher: %w", err)
 	}
-	// create services
-	userService := services.NewUserService(userRepo)
+	// webhook deliveries are queued by the relay and sent by the worker
+	publishers := outbox.FanOut{webhook.NewDispatcher(store.webhooks)}
+	if publisher != nil {
+		publishers = append(publishers, publisher)
+	}
+	var events *outbox.Broadcaster
+	if config.EventStream {
+		events = outbox.NewBroadcaster(store.outbox)
+		publishers = append(publishers, liveFeed(ctx, store, events))
+	}
+	relay := outbox.NewRelay(store.outbox, publishers, store.tx, config.OutboxInterval)
+	go relay.Run(ctx)
+	worker := webhook.NewWorker(store.webhooks, config.WebhookTimeout, config.WebhookMaxAttempts, config.WebhookInterval)
+	go worker.Run(ctx)
 
-	server := httpserver.NewHttpServer(userService)
+	go sweepIdempotencyKeys(ctx, store.idempotency, idempotencySweepInterval)
 
-	err = server.Start(config.ServerAddress)
+	options := httpserver.Options{
+		AdminToken:     config.AdminToken,
+		Idempotency:    store.idempotency,
+		IdempotencyTTL: config.IdempotencyTTL,
+		MaxBatchSize:   config.MaxBatchSize,
+		Webhooks:       webhookService,
+		Auth:           authService,
+		TrustedProxies: config.TrustedProxies,
+	}
+	// a nil *Broadcaster must not become a non-nil interface
+	if events != nil {
+		options.Events = events
+	}
+	server, err := httpserver.NewHttpServer(userService, options)
+	if err != nil {
+		return fmt.Errorf("cannot create server: %w", err)
+	}
+
+	err = server.Start(ctx, config.ServerAddress)
 	if err != nil {
 		return fmt.Errorf("cannot start server: %w", err)
 	}
 	return nil
 }
+
+// storage groups the repositories of one backend with its transaction manager
+type storage struct {
+	users       services.UserRepository
+	audit       services.AuditRepository
+	outbox      outboxStore
+	idempotency idempotencyStore
+	webhooks    webhookStore
+	credentials services.CredentialRepository
+	sessions    services.SessionRepository
+	tx          services.TxManager
+	// notifier fans events out to the other replicas, only Postgres has one
+	notifier *pgrepo.EventNotifier
+}
+
+// liveFeed returns the publisher that feeds events. Without a notifier the
+// relay hands them to events directly, with one every replica gets them
+// through LISTEN/NOTIFY, including this one. Listening stops with ctx.
+func liveFeed(ctx context.Context, store storage, events *outbox.Broadcaster) outbox.Publisher {
+	if store.notifier == nil {
+		return events
+	}
+	go store.notifier.Listen(ctx, func(event domain.OutboxEvent) {
+		_ = events.Publish(ctx, event)
+	})
+	return store.notifier
+}
+
+type outboxStore interface {
+	services.OutboxRepository
+	outbox.Store
+	outbox.History
+}
+
+type webhookStore interface {
+	services.WebhookRepository
+	webhook.Store
+}
+
+type idempotencyStore interface {
+	httpserver.IIdempotencyStore
+	DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error)
+}
+
+const idempotencySweepInterval = time.Hour
+
+// sweepIdempotencyKeys deletes expired keys, they are never replayed but would
+// otherwise stay in the store
+func sweepIdempotencyKeys(ctx context.Context, store idempotencyStore, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			deleted, err := store.DeleteExpiredIdempotencyKeys(ctx, now)
+			if err != nil {
+				log.Error().Err(err).Msg("failed to delete expired idempotency keys")
+				continue
+			}
+			log.Debug().Int64("deleted", deleted).Msg("expired idempotency keys deleted")
+		}
+	}
+}
+
+func newStorage(config util.Config) (storage, error) {
+	switch config.Storage {
+	case util.StorageMemory:
+		log.Warn().Msg("using in-memory storage, data will be lost on restart")
+		users, audit, outboxRepo := memrepo.NewUserRepo(), memrepo.NewAuditRepo(), memrepo.NewOutboxRepo()
+		webhooks, credentials, sessions := memrepo.NewWebhookRepo(), memrepo.NewCredentialRepo(), memrepo.NewSessionRepo()
+		return storage{
+			users:       users,
+			audit:       audit,
+			outbox:      outboxRepo,
+			idempotency: memrepo.NewIdempotencyRepo(),
+			webhooks:    webhooks,
+			credentials: credentials,
+			sessions:    sessions,
+			tx:          memrepo.NewTxManager(users, audit, outboxRepo, webhooks, credentials, sessions),
+		}, nil
+	case util.StoragePostgres, "":
+		migrate := checkMigrated
+		if config.MigrateOnStart {
+			migrate = migrateUp
+		}
+		if err := migrate(config); err != nil {
+			return storage{}, err
+		}
+		pgDB, err := pg.Dial(config.DSN, pg.Options{
+			ReplicaDSNs:          config.ReplicaDSNs,
+			ReadYourWritesWindow: config.ReadYourWritesWindow,
+			HealthCheckInterval:  config.ReplicaCheckInterval,
+			SessionKey: func(ctx context.Context) string {
+				return domain.RequestMetaFromContext(ctx).SessionID
+			},
+		})
+		if err != nil {
+			return storage{}, fmt.Errorf("error creating connection pool: %w", err)
+		}
+		return storage{
+			users:       pgrepo.NewUserRepo(pgDB),
+			audit:       pgrepo.NewAuditRepo(pgDB),
+			outbox:      pgrepo.NewOutboxRepo(pgDB),
+			idempotency: pgrepo.NewIdempotencyRepo(pgDB),
+			webhooks:    pgrepo.NewWebhookRepo(pgDB),
+			credentials: pgrepo.NewCredentialRepo(pgDB),
+			sessions:    pgrepo.NewSessionRepo(pgDB),
+			tx:          pg.NewTxManager(pgDB),
+			notifier:    pgrepo.NewEventNotifier(pgDB),
+		}, nil
+	case util.StorageSQLite:
+		db, err := sqlite.Open(config.SQLitePath)
+		if err != nil {
+			return storage{}, err
+		}
+		// the sqlite schema is always brought up to date, there is no migrate subcommand for it
+		if err := sqlite.Migrate(context.Background(), db, sqlitemigrations.FS); err != nil {
+			return storage{}, err
+		}
+		if err := migrations.NormalizeEmails(context.Background(), db.DB); err != nil {
+			return storage{}, err
+		}
+		return storage{
+			users:       sqliterepo.NewUserRepo(db),
+			audit:       sqliterepo.NewAuditRepo(db),
+			outbox:      sqliterepo.NewOutboxRepo(db),
+			idempotency: sqliterepo.NewIdempotencyRepo(db),
+			webhooks:    sqliterepo.NewWebhookRepo(db),
+			credentials: sqliterepo.NewCredentialRepo(db),
+			sessions:    sqliterepo.NewSessionRepo(db),
+			tx:          sqlite.NewTxManager(db),
+		}, nil
+	default:
+		return storage{}, fmt.Errorf("unknown storage %q", config.Storage)
+	}
+}
+
+// newTokens signs access tokens with the configured keys. Without any a random
+// key is used, tokens then stop working on restart and are not accepted by
+// other replicas.
+func newTokens(config util.Config) (*auth.Tokens, error) {
+	keys, err := auth.ParseKeys(config.AuthSigningKeys)
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		log.Warn().Msg("AUTH_SIGNING_KEYS is empty, access tokens are signed with a random key")
+		secret := make([]byte, auth.MinKeySize)
+		if _, err := rand.Read(secret); err != nil {
+			return nil, err
+		}
+		keys = []auth.Key{{ID: "ephemeral", Secret: secret}}
+	}
+	return auth.NewTokens(keys, config.AccessTokenTTL)
+}
+
+// newPublisher returns nil when events go nowhere outside this service
+func newPublisher(config util.Config) (outbox.Publisher, error) {
+	switch config.OutboxPublisher {
+	case util.PublisherNone, "":
+		return nil, nil
+	case util.PublisherMemory:
+		return outbox.NewMemoryPublisher(), nil
+	case util.PublisherNDJSON:
+		return outbox.NewNDJSONFilePublisher(config.OutboxFile)
+	default:
+		return nil, fmt.Errorf("unknown outbox publisher %q", config.OutboxPublisher)
+	}
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/vlad19930514/webApp/internal/app/migrations"
+	pg "github.com/vlad19930514/webApp/internal/pkg/pg"
+	"github.com/vlad19930514/webApp/util"
+)
+
+var errMigrateUsage = errors.New("usage: migrate up | down [N | all] | status | force VERSION")
+
+// runMigrate handles the "migrate" subcommand
+func runMigrate(args []string) error {
+	if len(args) == 0 {
+		return errMigrateUsage
+	}
+
+	config, err := util.LoadConfig(".")
+	if err != nil {
+		return fmt.Errorf("cannot load config: %w", err)
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		err = migrateUp(config)
+	case "down":
+		// one step unless asked for more, only "all" drops every table
+		all, steps := len(args) > 1 && args[1] == "all", 1
+		if len(args) > 1 && !all {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				return fmt.Errorf("invalid number of steps %q", args[1])
+			}
+		}
+		err = withMigrator(config, func(migrator *pg.Migrator) error {
+			if all {
+				return migrator.DownAll(ctx)
+			}
+			return migrator.Down(ctx, steps)
+		})
+	case "force":
+		if len(args) < 2 {
+			return errMigrateUsage
+		}
+		version, convErr := strconv.Atoi(args[1])
+		if convErr != nil {
+			return fmt.Errorf("invalid version %q", args[1])
+		}
+		err = withMigrator(config, func(migrator *pg.Migrator) error {
+			return migrator.Force(ctx, version)
+		})
+	case "status":
+		err = withMigrator(config, func(migrator *pg.Migrator) error {
+			status, err := migrator.Status(ctx)
+			if err != nil {
+				return err
+			}
+			if !status.Applied {
+				log.Info().Msg("no migrations applied")
+			} else {
+				log.Info().Uint("version", status.Version).Uint("latest", status.Latest).Bool("dirty", status.Dirty).Msg("migration status")
+			}
+			return nil
+		})
+	default:
+		return errMigrateUsage
+	}
+	if err != nil {
+		return fmt.Errorf("migrate %s: %w", args[0], err)
+	}
+	return nil
+}
+
+// withMigrator runs fn with a migrator of the configured database
+func withMigrator(config util.Config, fn func(migrator *pg.Migrator) error) error {
+	migrator, err := pg.NewMigrator(config.DSN, migrations.FS)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+	return fn(migrator)
+}
+
+func migrateUp(config util.Config) error {
+	migrator, err := pg.NewMigrator(config.DSN, migrations.FS)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	if err := migrator.Up(context.Background()); err != nil {
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+
+	db, err := pg.Dial(config.DSN, pg.Options{})
+	if err != nil {
+		return fmt.Errorf("error creating connection pool: %w", err)
+	}
+	defer db.Close()
+	if err := migrations.NormalizeEmails(context.Background(), db.DB); err != nil {
+		return fmt.Errorf("failed to apply migrations: %w", err)
+	}
+	return nil
+}
+
+// checkMigrated fails unless every migration is applied, the repositories
+// rely on the schema they create, e.g. user search on the search_vector
+// column and the pg_trgm extension
+func checkMigrated(config util.Config) error {
+	migrator, err := pg.NewMigrator(config.DSN, migrations.FS)
+	if err != nil {
+		return err
+	}
+	defer migrator.Close()
+
+	status, err := migrator.Status(context.Background())
+	if err != nil {
+		return fmt.Errorf("failed to read the migration status: %w", err)
+	}
+	if !status.Current() {
+		return fmt.Errorf("database schema is at version %d (dirty: %t) but %d is required, run the migrate up subcommand or set MIGRATE_ON_START",
+			status.Version, status.Dirty, status.Latest)
+	}
+	return nil
+}
@@ -0,0 +1,131 @@
+package auth
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"golang.org/x/crypto/bcrypt"
+)
+
+var testParams = Argon2Params{Memory: 1024, Time: 1, Threads: 1, SaltLen: 16, KeyLen: 32}
+
+func TestHasher(t *testing.T) {
+	hasher := NewHasher(testParams, 0)
+	hash, err := hasher.Hash("correct horse")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hash, "$argon2id$v=19$m=1024,t=1,p=1$"))
+
+	ok, rehash, err := hasher.Verify(hash, "correct horse")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.False(t, rehash)
+
+	ok, _, err = hasher.Verify(hash, "wrong horse")
+	require.NoError(t, err)
+	assert.False(t, ok)
+
+	// stronger parameters ask for the hash to be replaced
+	ok, rehash, err = NewHasher(DefaultArgon2Params, 0).Verify(hash, "correct horse")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, rehash)
+
+	_, _, err = hasher.Verify("$argon2id$v=19$garbage", "correct horse")
+	assert.Error(t, err)
+}
+
+func TestHasher_Bcrypt(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("correct horse"), bcrypt.MinCost)
+	require.NoError(t, err)
+	hasher := NewHasher(testParams, 0)
+
+	ok, rehash, err := hasher.Verify(string(hash), "correct horse")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, rehash)
+
+	ok, _, err = hasher.Verify(string(hash), "wrong horse")
+	require.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestHasher_Concurrency(t *testing.T) {
+	hasher := NewHasher(testParams, 1)
+	// take the only slot, hashing waits until it is given back
+	hasher.slots <- struct{}{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_, err := hasher.Hash("correct horse")
+		assert.NoError(t, err)
+	}()
+
+	select {
+	case <-done:
+		t.Fatal("hashed without a free slot")
+	case <-time.After(50 * time.Millisecond):
+	}
+	<-hasher.slots
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("hashing did not resume after the slot was freed")
+	}
+}
+
+func testKey(id string) string {
+	return id + ":" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat(id, MinKeySize)))
+}
+
+func TestParseKeys(t *testing.T) {
+	keys, err := ParseKeys([]string{testKey("b"), " ", testKey("a")})
+	require.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, "b", keys[0].ID)
+
+	_, err = ParseKeys([]string{"a:" + base64.StdEncoding.EncodeToString([]byte("short"))})
+	assert.Error(t, err)
+	_, err = ParseKeys([]string{testKey("a"), testKey("a")})
+	assert.Error(t, err)
+	_, err = ParseKeys([]string{"no-secret"})
+	assert.Error(t, err)
+}
+
+func TestTokens_Rotation(t *testing.T) {
+	oldKeys, err := ParseKeys([]string{testKey("old")})
+	require.NoError(t, err)
+	rotated, err := ParseKeys([]string{testKey("new"), testKey("old")})
+	require.NoError(t, err)
+
+	now := time.Now()
+	principal := domain.Principal{UserId: uuid.New(), SessionId: uuid.New()}
+	before, err := NewTokens(oldKeys, time.Minute)
+	require.NoError(t, err)
+	token, err := before.Issue(principal, now)
+	require.NoError(t, err)
+	assert.Equal(t, now.Add(time.Minute), token.ExpiresAt)
+
+	// tokens of the old key still verify after the rotation
+	after, err := NewTokens(rotated, time.Minute)
+	require.NoError(t, err)
+	got, err := after.Verify(token.Token, now)
+	require.NoError(t, err)
+	assert.Equal(t, principal, got)
+
+	// and stop once the key is dropped
+	newOnly, err := NewTokens(rotated[:1], time.Minute)
+	require.NoError(t, err)
+	_, err = newOnly.Verify(token.Token, now)
+	assert.ErrorIs(t, err, domain.ErrUnauthenticated)
+
+	_, err = after.Verify(token.Token, now.Add(2*time.Minute))
+	assert.ErrorIs(t, err, domain.ErrUnauthenticated)
+	_, err = after.Verify(token.Token+"x", now)
+	assert.ErrorIs(t, err, domain.ErrUnauthenticated)
+}
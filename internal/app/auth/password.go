@@ -0,0 +1,117 @@
+// Package auth hashes passwords and signs access tokens
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Argon2Params are the argon2id cost parameters, hashes made with other
+// parameters still verify and are reported for rehashing
+type Argon2Params struct {
+	Memory  uint32 // KiB
+	Time    uint32
+	Threads uint8
+	SaltLen uint32
+	KeyLen  uint32
+}
+
+// DefaultArgon2Params follow the second recommendation of RFC 9106
+var DefaultArgon2Params = Argon2Params{Memory: 64 * 1024, Time: 3, Threads: 4, SaltLen: 16, KeyLen: 32}
+
+// DefaultHashConcurrency is how many passwords are hashed or verified at
+// once, with the default parameters that takes up to 256 MiB
+const DefaultHashConcurrency = 4
+
+var errMalformedHash = errors.New("malformed password hash")
+
+// Hasher hashes new passwords with argon2id. It verifies argon2id and bcrypt
+// hashes, the latter so imported accounts can sign in and get rehashed.
+// Every argon2id run takes Memory KiB, so at most concurrency of them run at
+// once and the others wait for a slot. Otherwise a burst of logins could take
+// the process out of memory.
+type Hasher struct {
+	params Argon2Params
+	slots  chan struct{}
+}
+
+func NewHasher(params Argon2Params, concurrency int) Hasher {
+	if concurrency <= 0 {
+		concurrency = DefaultHashConcurrency
+	}
+	return Hasher{params: params, slots: make(chan struct{}, concurrency)}
+}
+
+func (h Hasher) idKey(password string, salt []byte, params Argon2Params) []byte {
+	h.slots <- struct{}{}
+	defer func() { <-h.slots }()
+	return argon2.IDKey([]byte(password), salt, params.Time, params.Memory, params.Threads, params.KeyLen)
+}
+
+// Hash returns the PHC string of the password, "$argon2id$v=19$m=..,t=..,p=..$salt$key"
+func (h Hasher) Hash(password string) (string, error) {
+	salt := make([]byte, h.params.SaltLen)
+	if _, err := rand.Read(salt); err != nil {
+		return "", fmt.Errorf("failed to generate salt: %w", err)
+	}
+	key := h.idKey(password, salt, h.params)
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s", argon2.Version,
+		h.params.Memory, h.params.Time, h.params.Threads,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+// Verify compares the password with the hash in constant time. rehash tells
+// that the hash is outdated and should be replaced after a successful check.
+func (h Hasher) Verify(hash, password string) (ok, rehash bool, err error) {
+	if strings.HasPrefix(hash, "$2") {
+		err := bcrypt.CompareHashAndPassword([]byte(hash), []byte(password))
+		if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+			return false, false, nil
+		}
+		return err == nil, true, err
+	}
+
+	params, salt, key, err := decodeArgon2(hash)
+	if err != nil {
+		return false, false, err
+	}
+	got := h.idKey(password, salt, params)
+	if subtle.ConstantTimeCompare(got, key) != 1 {
+		return false, false, nil
+	}
+	params.SaltLen = uint32(len(salt))
+	return true, params != h.params, nil
+}
+
+func decodeArgon2(hash string) (Argon2Params, []byte, []byte, error) {
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", salt, key
+	parts := strings.Split(hash, "$")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return Argon2Params{}, nil, nil, errMalformedHash
+	}
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return Argon2Params{}, nil, nil, errMalformedHash
+	}
+	var params Argon2Params
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &params.Memory, &params.Time, &params.Threads); err != nil {
+		return Argon2Params{}, nil, nil, errMalformedHash
+	}
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return Argon2Params{}, nil, nil, errMalformedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return Argon2Params{}, nil, nil, errMalformedHash
+	}
+	params.KeyLen = uint32(len(key))
+	return params, salt, key, nil
+}
@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/google/uuid"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+const (
+	DefaultAccessTokenTTL = 15 * time.Minute
+	// MinKeySize is the shortest HMAC key accepted, shorter ones are guessable
+	MinKeySize = 32
+
+	issuer = "webapp"
+)
+
+// Key is an HMAC-SHA256 signing key, its ID goes into the kid header of the tokens it signs
+type Key struct {
+	ID     string
+	Secret []byte
+}
+
+// ParseKeys reads "kid:base64secret" entries. The first key signs new tokens,
+// all of them verify, so a key is rotated by putting the new one first and
+// dropping the old one once its tokens have expired.
+func ParseKeys(specs []string) ([]Key, error) {
+	keys := make([]Key, 0, len(specs))
+	seen := make(map[string]bool)
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(spec, ":")
+		if !ok || id == "" {
+			return nil, fmt.Errorf("signing key %q: want kid:base64secret", id)
+		}
+		if seen[id] {
+			return nil, fmt.Errorf("signing key %q: duplicate kid", id)
+		}
+		secret, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("signing key %q: %w", id, err)
+		}
+		if len(secret) < MinKeySize {
+			return nil, fmt.Errorf("signing key %q: shorter than %d bytes", id, MinKeySize)
+		}
+		seen[id] = true
+		keys = append(keys, Key{ID: id, Secret: secret})
+	}
+	return keys, nil
+}
+
+// claims are the registered claims plus the session the token belongs to
+type claims struct {
+	jwt.RegisteredClaims
+	SessionID string `json:"sid,omitempty"`
+}
+
+// Tokens issues and verifies HS256 JWT access tokens
+type Tokens struct {
+	keys []Key
+	ttl  time.Duration
+}
+
+func NewTokens(keys []Key, ttl time.Duration) (*Tokens, error) {
+	if len(keys) == 0 {
+		return nil, errors.New("no signing keys")
+	}
+	if ttl <= 0 {
+		ttl = DefaultAccessTokenTTL
+	}
+	return &Tokens{keys: keys, ttl: ttl}, nil
+}
+
+// Issue signs a token for the principal with the current key
+func (t *Tokens) Issue(principal domain.Principal, now time.Time) (domain.AccessToken, error) {
+	expiresAt := now.Add(t.ttl)
+	c := claims{RegisteredClaims: jwt.RegisteredClaims{
+		Issuer:    issuer,
+		Subject:   principal.UserId.String(),
+		IssuedAt:  jwt.NewNumericDate(now),
+		NotBefore: jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(expiresAt),
+		ID:        uuid.NewString(),
+	}}
+	if principal.SessionId != uuid.Nil {
+		c.SessionID = principal.SessionId.String()
+	}
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, c)
+	key := t.keys[0]
+	token.Header["kid"] = key.ID
+	signed, err := token.SignedString(key.Secret)
+	if err != nil {
+		return domain.AccessToken{}, fmt.Errorf("failed to sign access token: %w", err)
+	}
+	return domain.AccessToken{Token: signed, ExpiresAt: expiresAt}, nil
+}
+
+// Verify checks the signature with the key named by kid and the time claims at now
+func (t *Tokens) Verify(token string, now time.Time) (domain.Principal, error) {
+	var c claims
+	_, err := jwt.ParseWithClaims(token, &c, t.key,
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg()}),
+		jwt.WithIssuer(issuer),
+		jwt.WithExpirationRequired(),
+		jwt.WithTimeFunc(func() time.Time { return now }),
+	)
+	if err != nil {
+		return domain.Principal{}, fmt.Errorf("%w: %v", domain.ErrUnauthenticated, err)
+	}
+	principal := domain.Principal{}
+	if principal.UserId, err = uuid.Parse(c.Subject); err != nil {
+		return domain.Principal{}, fmt.Errorf("%w: malformed subject", domain.ErrUnauthenticated)
+	}
+	if c.SessionID != "" {
+		if principal.SessionId, err = uuid.Parse(c.SessionID); err != nil {
+			return domain.Principal{}, fmt.Errorf("%w: malformed session", domain.ErrUnauthenticated)
+		}
+	}
+	return principal, nil
+}
+
+func (t *Tokens) key(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+	for _, key := range t.keys {
+		if key.ID == kid {
+			return key.Secret, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
@@ -0,0 +1,136 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type AuditOperation string
+
+const (
+	AuditCreate  AuditOperation = "create"
+	AuditUpdate  AuditOperation = "update"
+	AuditDelete  AuditOperation = "delete"
+	AuditRestore AuditOperation = "restore"
+	AuditPurge   AuditOperation = "purge"
+	AuditRole    AuditOperation = "role_assigned"
+)
+
+// FieldChange is the before/after value of one user field, nil means absent
+type FieldChange struct {
+	Field  string `json:"field"`
+	Before any    `json:"before"`
+	After  any    `json:"after"`
+}
+
+// AuditRecord is written for every user mutation in the same transaction
+type AuditRecord struct {
+	Id        uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	UserId    uuid.UUID `gorm:"type:uuid"`
+	Operation AuditOperation
+	Actor     string
+	RequestId string
+	ClientIp  string
+	Changes   []FieldChange `gorm:"type:jsonb;serializer:json"`
+	CreatedAt time.Time
+}
+
+type AuditQuery struct {
+	UserId uuid.UUID
+	Limit  int
+	Cursor string
+}
+
+type AuditPage struct {
+	Records    []AuditRecord
+	NextCursor string
+}
+
+// NewAuditRecord builds a record for the operation, before and after are nil
+// when the user did not exist on that side of the change.
+func NewAuditRecord(meta RequestMeta, op AuditOperation, userID uuid.UUID, before, after *User) AuditRecord {
+	return AuditRecord{
+		Id:        uuid.New(),
+		UserId:    userID,
+		Operation: op,
+		Actor:     meta.Actor,
+		RequestId: meta.RequestID,
+		ClientIp:  meta.ClientIP,
+		Changes:   DiffUsers(before, after),
+		CreatedAt: time.Now(),
+	}
+}
+
+// DiffUsers lists the fields that differ between two user states
+func DiffUsers(before, after *User) []FieldChange {
+	b, a := auditFields(before), auditFields(after)
+
+	changes := []FieldChange{}
+	for _, name := range auditFieldNames {
+		if b[name] != a[name] {
+			changes = append(changes, FieldChange{Field: name, Before: b[name], After: a[name]})
+		}
+	}
+	return changes
+}
+
+var auditFieldNames = []string{"first_name", "last_name", "email", "age", "role", "deleted"}
+
+func auditFields(u *User) map[string]any {
+	if u == nil {
+		// a missing user is not a deleted one, so "deleted" never shows up on create
+		return map[string]any{"deleted": false}
+	}
+	return map[string]any{
+		"first_name": u.FirstName,
+		"last_name":  u.LastName,
+		"email":      u.Email,
+		"age":        u.Age,
+		"role":       string(u.Role),
+		"deleted":    u.DeletedAt.Valid,
+	}
+}
+
+func (q AuditQuery) Normalize() (AuditQuery, error) {
+	switch {
+	case q.Limit <= 0:
+		q.Limit = DefaultListLimit
+	case q.Limit > MaxListLimit:
+		q.Limit = MaxListLimit
+	}
+	if _, err := q.After(); err != nil {
+		return q, err
+	}
+	return q, nil
+}
+
+type auditCursor struct {
+	CreatedAt time.Time `json:"c"`
+	Id        uuid.UUID `json:"id"`
+}
+
+// After returns the position of the cursor, records are listed newest first
+func (q AuditQuery) After() (*AuditRecord, error) {
+	if q.Cursor == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	var c auditCursor
+	if err := json.Unmarshal(raw, &c); err != nil || c.Id == uuid.Nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	return &AuditRecord{Id: c.Id, CreatedAt: c.CreatedAt}, nil
+}
+
+func NextAuditCursor(last AuditRecord) string {
+	raw, _ := json.Marshal(auditCursor{CreatedAt: last.CreatedAt, Id: last.Id})
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Limits of passwords, the upper one keeps hashing a request cheap enough
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 128
+)
+
+var (
+	// ErrUnauthenticated means the request carries no valid credentials
+	ErrUnauthenticated = errors.New("unauthenticated")
+	// ErrInvalidCredentials means the email or the password is wrong, which one is not told
+	ErrInvalidCredentials = fmt.Errorf("invalid credentials: %w", ErrUnauthenticated)
+	// ErrForbidden means the caller is known but not allowed to do this
+	ErrForbidden = errors.New("forbidden")
+)
+
+// Credential is the password of a user, stored as a self-describing hash
+type Credential struct {
+	UserId       uuid.UUID `gorm:"type:uuid;primaryKey"`
+	PasswordHash string
+	UpdatedAt    time.Time
+}
+
+// AccessToken is a signed short-lived token naming a principal
+type AccessToken struct {
+	Token     string
+	ExpiresAt time.Time
+}
+
+// Principal is who a request acts as. The admin token acts as nobody in
+// particular, it has RoleAdmin and no UserId. SessionId is the session the
+// access token was issued for.
+type Principal struct {
+	UserId    uuid.UUID
+	SessionId uuid.UUID
+	Role      Role
+}
+
+// AdminTokenPrincipal is the principal of requests sent with the admin token
+var AdminTokenPrincipal = Principal{Role: RoleAdmin}
+
+// Actor is how the principal is named in audit records
+func (p Principal) Actor() string {
+	if p.UserId == uuid.Nil {
+		return "admin"
+	}
+	return "user:" + p.UserId.String()
+}
+
+type principalKey struct{}
+
+func ContextWithPrincipal(ctx context.Context, principal Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, principal)
+}
+
+// PrincipalFromContext returns the authenticated principal, ok is false for anonymous requests
+func PrincipalFromContext(ctx context.Context) (Principal, bool) {
+	principal, ok := ctx.Value(principalKey{}).(Principal)
+	return principal, ok
+}
+
+// ValidatePassword checks the length of a new password
+func ValidatePassword(password string) error {
+	var violation *FieldViolation
+	switch n := utf8.RuneCountInString(password); {
+	case password == "":
+		violation = &FieldViolation{Field: "password", Rule: RuleRequired}
+	case n < MinPasswordLength:
+		violation = &FieldViolation{Field: "password", Rule: RuleMinLen, Param: fmt.Sprint(MinPasswordLength)}
+	case n > MaxPasswordLength:
+		violation = &FieldViolation{Field: "password", Rule: RuleMaxLen, Param: fmt.Sprint(MaxPasswordLength)}
+	}
+	if violation != nil {
+		return &ValidationError{Violations: []FieldViolation{*violation}}
+	}
+	return nil
+}
@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"strings"
+
+	"golang.org/x/net/idna"
+)
+
+// NormalizeEmail trims the address and brings its domain to the lowercase
+// ASCII (punycode) form, so Bob@Exämple.com becomes Bob@xn--exmple-cua.com.
+// The local part is kept as typed, uniqueness ignores its case anyway.
+// An address that can not be normalized is returned trimmed for Validate to reject.
+func NormalizeEmail(email string) string {
+	email = strings.TrimSpace(email)
+	at := strings.LastIndexByte(email, '@')
+	if at <= 0 || at == len(email)-1 {
+		return email
+	}
+
+	domain, err := idna.Lookup.ToASCII(strings.ToLower(email[at+1:]))
+	if err != nil {
+		return email
+	}
+	return email[:at+1] + domain
+}
+
+// EmailKey is what email uniqueness is checked on, like lower(email) in the
+// unique index of the SQL storages
+func EmailKey(email string) string {
+	return strings.ToLower(email)
+}
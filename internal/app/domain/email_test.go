@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalizeEmail(t *testing.T) {
+	testCases := []struct {
+		in, want string
+	}{
+		{"bob@example.com", "bob@example.com"},
+		{"  Bob@Example.COM ", "Bob@example.com"},
+		{"anna@ПРИМЕР.рф", "anna@xn--e1afmkfd.xn--p1ai"},
+		{"user@Bücher.de", "user@xn--bcher-kva.de"},
+		{"not-an-email", "not-an-email"},
+		{"trailing@", "trailing@"},
+	}
+	for _, tc := range testCases {
+		assert.Equal(t, tc.want, NormalizeEmail(tc.in), tc.in)
+	}
+	assert.Equal(t, EmailKey("Bob@example.com"), EmailKey("bob@EXAMPLE.com"))
+}
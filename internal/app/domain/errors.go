@@ -0,0 +1,24 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// The error taxonomy of the domain. Repositories translate storage errors into
+// these, the transport layer maps them to statuses without knowing the storage.
+var (
+	// ErrNotFound means the requested entity does not exist or is deleted
+	ErrNotFound = errors.New("not found")
+	// ErrEmailTaken means another active user already has the email
+	ErrEmailTaken = errors.New("email already taken")
+	// ErrConflict means the change clashes with the current state
+	ErrConflict = errors.New("conflict")
+	// ErrValidation means the input breaks a domain rule
+	ErrValidation = errors.New("validation failed")
+	// ErrUnavailable means the storage can not be reached, retrying later may help
+	ErrUnavailable = errors.New("storage unavailable")
+)
+
+// ErrVersionConflict means the user was changed since the caller read it
+var ErrVersionConflict = fmt.Errorf("user version %w", ErrConflict)
@@ -0,0 +1,77 @@
+package domain
+
+import (
+	"slices"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+type UserEventType string
+
+const (
+	UserCreated UserEventType = "UserCreated"
+	UserUpdated UserEventType = "UserUpdated"
+	UserDeleted UserEventType = "UserDeleted"
+)
+
+// UserEventPayload is the public contract of user events, keep it backwards compatible
+type UserEventPayload struct {
+	UserId    uuid.UUID `json:"user_id"`
+	FirstName string    `json:"first_name,omitempty"`
+	LastName  string    `json:"last_name,omitempty"`
+	Email     string    `json:"email,omitempty"`
+	Age       uint8     `json:"age,omitempty"`
+	Version   int64     `json:"version,omitempty"`
+	Purged    bool      `json:"purged,omitempty"`
+	// ChangedFields names the fields an update changed, events without it concern the whole user
+	ChangedFields []string `json:"changed_fields,omitempty"`
+}
+
+// OutboxEvent is stored together with the user change and published later by the relay
+type OutboxEvent struct {
+	Id          int64            `gorm:"primaryKey;autoIncrement" json:"id"`
+	AggregateId uuid.UUID        `gorm:"type:uuid" json:"aggregate_id"`
+	Type        UserEventType    `json:"type"`
+	Payload     UserEventPayload `gorm:"type:jsonb;serializer:json" json:"payload"`
+	CreatedAt   time.Time        `json:"occurred_at"`
+	PublishedAt *time.Time       `json:"-"`
+}
+
+func NewUserEvent(eventType UserEventType, user User) OutboxEvent {
+	return OutboxEvent{
+		AggregateId: user.Id,
+		Type:        eventType,
+		Payload: UserEventPayload{
+			UserId:    user.Id,
+			FirstName: user.FirstName,
+			LastName:  user.LastName,
+			Email:     user.Email,
+			Age:       user.Age,
+			Version:   user.Version,
+		},
+		CreatedAt: time.Now(),
+	}
+}
+
+// UserEventFilter selects user events, an empty list matches every event
+type UserEventFilter struct {
+	UserIds []uuid.UUID
+	Fields  []string
+}
+
+// Match tells whether the event concerns one of the users and one of the fields
+func (f UserEventFilter) Match(event OutboxEvent) bool {
+	if len(f.UserIds) > 0 && !slices.Contains(f.UserIds, event.AggregateId) {
+		return false
+	}
+	if len(f.Fields) == 0 || len(event.Payload.ChangedFields) == 0 {
+		return true
+	}
+	for _, field := range event.Payload.ChangedFields {
+		if slices.Contains(f.Fields, field) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,22 @@
+package domain
+
+import "time"
+
+// IdempotencyRecord remembers the response to a mutation sent with an
+// Idempotency-Key, so a retry gets the same answer instead of running again
+type IdempotencyRecord struct {
+	Key string `gorm:"primaryKey"`
+	// Fingerprint identifies the request, the key cannot be reused for another one
+	Fingerprint string
+	// Status is 0 while the first request is still being handled
+	Status    int
+	Headers   map[string]string `gorm:"type:jsonb;serializer:json"`
+	Body      []byte
+	CreatedAt time.Time
+	ExpiresAt time.Time `gorm:"index"`
+}
+
+// Completed reports whether the response has been saved
+func (r IdempotencyRecord) Completed() bool {
+	return r.Status != 0
+}
@@ -0,0 +1,34 @@
+package domain
+
+import "context"
+
+const AnonymousActor = "anonymous"
+
+// RequestMeta describes who triggered an operation, it ends up in audit records.
+// SessionID groups requests of one client so they can read their own writes.
+type RequestMeta struct {
+	Actor     string
+	RequestID string
+	SessionID string
+	ClientIP  string
+	UserAgent string
+}
+
+type requestMetaKey struct{}
+
+func ContextWithRequestMeta(ctx context.Context, meta RequestMeta) context.Context {
+	return context.WithValue(ctx, requestMetaKey{}, meta)
+}
+
+// RequestMetaFromContext returns the request metadata. The actor is the
+// authenticated principal when there is one and defaults to AnonymousActor.
+func RequestMetaFromContext(ctx context.Context) RequestMeta {
+	meta, _ := ctx.Value(requestMetaKey{}).(RequestMeta)
+	if principal, ok := PrincipalFromContext(ctx); ok {
+		meta.Actor = principal.Actor()
+	}
+	if meta.Actor == "" {
+		meta.Actor = AnonymousActor
+	}
+	return meta
+}
@@ -0,0 +1,98 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// Role decides what a principal may do with users, see Principal.Can
+type Role string
+
+const (
+	// RoleAdmin may do everything, including assigning roles
+	RoleAdmin Role = "admin"
+	// RoleSupport may read every user and edit them, except for their email
+	RoleSupport Role = "support"
+	// RoleUser is self-service, it may only read and edit its own record
+	RoleUser Role = "user"
+)
+
+// Roles lists the valid roles
+var Roles = []Role{RoleAdmin, RoleSupport, RoleUser}
+
+// ParseRole returns the role named s or a *ValidationError
+func ParseRole(s string) (Role, error) {
+	for _, role := range Roles {
+		if string(role) == s {
+			return role, nil
+		}
+	}
+	names := make([]string, len(Roles))
+	for i, role := range Roles {
+		names[i] = string(role)
+	}
+	violation := FieldViolation{Field: "role", Rule: RuleOneOf, Param: strings.Join(names, " "), Value: s}
+	return "", &ValidationError{Violations: []FieldViolation{violation}}
+}
+
+// Action is something done to a user that needs a permission
+type Action string
+
+const (
+	ActionReadUser    Action = "user:read"
+	ActionUpdateUser  Action = "user:update"
+	ActionChangeEmail Action = "user:change_email"
+	ActionDeleteUser  Action = "user:delete"
+	ActionPurgeUser   Action = "user:purge"
+	ActionReadAudit   Action = "user:read_audit"
+	ActionAssignRole  Action = "user:assign_role"
+	// ActionSetPassword on another user sets it without the current one
+	ActionSetPassword    Action = "user:set_password"
+	ActionRevokeSessions Action = "user:revoke_sessions"
+	// ActionListUsers covers every read that is not of one known user:
+	// listing, search, lookup by email and batch reads
+	ActionListUsers  Action = "users:list"
+	ActionCreateUser Action = "users:create"
+)
+
+// permissions are the actions a role may take on any user, ownPermissions
+// the ones it may take on its own record only. Admins are not listed, they
+// may do everything.
+var (
+	permissions = map[Role][]Action{
+		RoleSupport: {ActionReadUser, ActionUpdateUser, ActionReadAudit, ActionListUsers, ActionCreateUser},
+	}
+	ownPermissions = map[Role][]Action{
+		RoleSupport: {ActionSetPassword},
+		RoleUser:    {ActionReadUser, ActionUpdateUser, ActionChangeEmail, ActionSetPassword},
+	}
+)
+
+// Can tells whether the principal may take action on the user with the id,
+// uuid.Nil stands for no user in particular
+func (p Principal) Can(action Action, userID uuid.UUID) bool {
+	if p.Role == RoleAdmin {
+		return true
+	}
+	if slices.Contains(permissions[p.Role], action) {
+		return true
+	}
+	return userID != uuid.Nil && userID == p.UserId && slices.Contains(ownPermissions[p.Role], action)
+}
+
+// Authorize fails with ErrUnauthenticated for anonymous requests and with
+// ErrForbidden when the principal may not take action on the user
+func Authorize(ctx context.Context, action Action, userID uuid.UUID) error {
+	principal, ok := PrincipalFromContext(ctx)
+	if !ok {
+		return ErrUnauthenticated
+	}
+	if !principal.Can(action, userID) {
+		return fmt.Errorf("%w: %s", ErrForbidden, action)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ErrRefreshTokenReused means a rotated refresh token was presented again, its
+// session is revoked since either the client or a thief holds a stale copy
+var ErrRefreshTokenReused = fmt.Errorf("refresh token reused: %w", ErrUnauthenticated)
+
+// Session is one signed-in device. Its refresh tokens form a family, each one
+// is replaced on use and the session ends when any of them is replayed.
+type Session struct {
+	Id         uuid.UUID `gorm:"type:uuid;primaryKey"`
+	UserId     uuid.UUID `gorm:"type:uuid"`
+	UserAgent  string
+	ClientIp   string
+	CreatedAt  time.Time
+	LastUsedAt time.Time
+	// ExpiresAt moves forward on every refresh, idle sessions run out
+	ExpiresAt time.Time
+	RevokedAt *time.Time
+}
+
+// Active tells whether the session can still be refreshed at now
+func (s Session) Active(now time.Time) bool {
+	return s.RevokedAt == nil && now.Before(s.ExpiresAt)
+}
+
+// RefreshToken is stored as a hash, the token itself is only known to the client
+type RefreshToken struct {
+	Id        uuid.UUID `gorm:"type:uuid;primaryKey"`
+	SessionId uuid.UUID `gorm:"type:uuid"`
+	TokenHash string
+	CreatedAt time.Time
+	// UsedAt is set once the token was exchanged for a new one
+	UsedAt *time.Time
+}
+
+// TokenPair is what a login or a refresh hands out
+type TokenPair struct {
+	Access       AccessToken
+	RefreshToken string
+	Session      Session
+}
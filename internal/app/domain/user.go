@@ -1,15 +1,32 @@
 package domain
 
 import (
-	"github.com/google/uuid"
 	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
 )
 
 type User struct {
-	Id        uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	Id        uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey;index:idx_users_created_at_id,priority:2"`
 	FirstName string
 	LastName  string
-	Email     string `gorm:"type:text;unique"`
+	Email     string `gorm:"type:text;index:idx_users_email,unique,expression:lower(email),where:deleted_at IS NULL"` // case-insensitive, soft-deleted users free their email
 	Age       uint8
-	CreatedAt time.Time
+	Role      Role           `gorm:"not null;default:user"` // changed only through UserService.AssignRole
+	CreatedAt time.Time      `gorm:"index:idx_users_created_at_id,priority:1"`
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+	Version   int64          `gorm:"not null;default:1"` // bumped on every update, see UpdateUser
+}
+
+// ChangedUserFields lists the client editable fields that differ between two
+// states of a user, named like the columns they are stored in
+func ChangedUserFields(before, after User) []string {
+	var fields []string
+	for _, change := range DiffUsers(&before, &after) {
+		if change.Field != "deleted" {
+			fields = append(fields, change.Field)
+		}
+	}
+	return fields
 }
@@ -0,0 +1,55 @@
+package domain
+
+import (
+	"fmt"
+
+	"github.com/google/uuid"
+)
+
+// BatchMode tells a batch whether one failed item fails all of them
+type BatchMode string
+
+const (
+	// BatchAtomic applies every item or none
+	BatchAtomic BatchMode = "atomic"
+	// BatchBestEffort applies the items that can be applied and reports the others
+	BatchBestEffort BatchMode = "best_effort"
+)
+
+// UserResult is the outcome of one item of a batch, Err is set when it failed
+type UserResult struct {
+	User User
+	Err  error
+}
+
+// UserPatch is one item of a batch update, Apply gets the stored user and
+// returns its new state
+type UserPatch struct {
+	Id      uuid.UUID
+	Version int64
+	Apply   func(User) (User, error)
+}
+
+// BatchItemError names the item that failed an atomic batch
+type BatchItemError struct {
+	Index int
+	Err   error
+}
+
+func (e *BatchItemError) Error() string {
+	return fmt.Sprintf("item %d: %v", e.Index, e.Err)
+}
+
+func (e *BatchItemError) Unwrap() error {
+	return e.Err
+}
+
+// FirstFailed returns the index of the first failed result, -1 if none failed
+func FirstFailed(results []UserResult) int {
+	for i, result := range results {
+		if result.Err != nil {
+			return i
+		}
+	}
+	return -1
+}
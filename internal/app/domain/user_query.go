@@ -0,0 +1,278 @@
+package domain
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+const (
+	DefaultListLimit = 20
+	MaxListLimit     = 100
+)
+
+var ErrInvalidQuery = errors.New("invalid query")
+
+// UserSortField is a sortable user column, values match the column names
+type UserSortField string
+
+const (
+	SortByCreatedAt UserSortField = "created_at"
+	SortByFirstName UserSortField = "first_name"
+	SortByLastName  UserSortField = "last_name"
+	SortByEmail     UserSortField = "email"
+	SortByAge       UserSortField = "age"
+)
+
+var userSortFields = map[UserSortField]bool{
+	SortByCreatedAt: true,
+	SortByFirstName: true,
+	SortByLastName:  true,
+	SortByEmail:     true,
+	SortByAge:       true,
+}
+
+type UserSort struct {
+	Field UserSortField
+	Desc  bool
+}
+
+// UserFilter narrows a user listing, zero values mean "no filter"
+type UserFilter struct {
+	Name          string // case-insensitive substring of the first or last name
+	EmailDomain   string
+	MinAge        uint8
+	MaxAge        uint8
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+}
+
+type ListUsersQuery struct {
+	Filter UserFilter
+	Sort   []UserSort
+	Limit  int
+	Cursor string
+}
+
+type UserPage struct {
+	Users      []User
+	NextCursor string
+}
+
+// ParseUserSort parses a comma separated list like "-created_at,age",
+// a leading minus means descending order.
+func ParseUserSort(s string) ([]UserSort, error) {
+	if strings.TrimSpace(s) == "" {
+		return nil, nil
+	}
+
+	var sort []UserSort
+	seen := make(map[UserSortField]bool)
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		desc := strings.HasPrefix(part, "-")
+		field := UserSortField(strings.TrimPrefix(part, "-"))
+		if !userSortFields[field] {
+			return nil, fmt.Errorf("%w: unknown sort field %q", ErrInvalidQuery, field)
+		}
+		if seen[field] {
+			return nil, fmt.Errorf("%w: duplicate sort field %q", ErrInvalidQuery, field)
+		}
+		seen[field] = true
+		sort = append(sort, UserSort{Field: field, Desc: desc})
+	}
+	return sort, nil
+}
+
+// Normalize fills in defaults and validates the query, including its cursor
+func (q ListUsersQuery) Normalize() (ListUsersQuery, error) {
+	if len(q.Sort) == 0 {
+		q.Sort = []UserSort{{Field: SortByCreatedAt, Desc: true}}
+	}
+	for _, s := range q.Sort {
+		if !userSortFields[s.Field] {
+			return q, fmt.Errorf("%w: unknown sort field %q", ErrInvalidQuery, s.Field)
+		}
+	}
+
+	switch {
+	case q.Limit <= 0:
+		q.Limit = DefaultListLimit
+	case q.Limit > MaxListLimit:
+		q.Limit = MaxListLimit
+	}
+
+	f := q.Filter
+	if f.MinAge != 0 && f.MaxAge != 0 && f.MinAge > f.MaxAge {
+		return q, fmt.Errorf("%w: min age is greater than max age", ErrInvalidQuery)
+	}
+	if !f.CreatedAfter.IsZero() && !f.CreatedBefore.IsZero() && !f.CreatedAfter.Before(f.CreatedBefore) {
+		return q, fmt.Errorf("%w: empty created at window", ErrInvalidQuery)
+	}
+
+	if _, err := q.After(); err != nil {
+		return q, err
+	}
+	return q, nil
+}
+
+// userCursor is the keyset position of the last user on a page.
+// It remembers the sort it was made for so it cannot be reused with another one.
+type userCursor struct {
+	Sort      string    `json:"s"`
+	ID        uuid.UUID `json:"id"`
+	CreatedAt time.Time `json:"c"`
+	FirstName string    `json:"f,omitempty"`
+	LastName  string    `json:"l,omitempty"`
+	Email     string    `json:"e,omitempty"`
+	Age       uint8     `json:"a,omitempty"`
+}
+
+// After returns the position encoded in the cursor, nil for the first page
+func (q ListUsersQuery) After() (*User, error) {
+	if q.Cursor == "" {
+		return nil, nil
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	var c userCursor
+	dec := json.NewDecoder(bytes.NewReader(raw))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&c); err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	if c.Sort != sortKey(q.Sort) {
+		return nil, fmt.Errorf("%w: cursor does not match the sort order", ErrInvalidQuery)
+	}
+
+	return &User{
+		Id:        c.ID,
+		CreatedAt: c.CreatedAt,
+		FirstName: c.FirstName,
+		LastName:  c.LastName,
+		Email:     c.Email,
+		Age:       c.Age,
+	}, nil
+}
+
+// NextUserCursor builds an opaque cursor pointing right after the given user
+func NextUserCursor(sort []UserSort, last User) string {
+	c := userCursor{Sort: sortKey(sort), ID: last.Id}
+	for _, s := range sort {
+		switch s.Field {
+		case SortByCreatedAt:
+			c.CreatedAt = last.CreatedAt
+		case SortByFirstName:
+			c.FirstName = last.FirstName
+		case SortByLastName:
+			c.LastName = last.LastName
+		case SortByEmail:
+			c.Email = last.Email
+		case SortByAge:
+			c.Age = last.Age
+		}
+	}
+
+	raw, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(raw)
+}
+
+// SortValue returns the value of the user column the sort field refers to
+func SortValue(u User, field UserSortField) any {
+	switch field {
+	case SortByCreatedAt:
+		return u.CreatedAt
+	case SortByFirstName:
+		return u.FirstName
+	case SortByLastName:
+		return u.LastName
+	case SortByEmail:
+		return u.Email
+	case SortByAge:
+		return u.Age
+	}
+	return nil
+}
+
+// CompareUsers orders users by the sort fields, ties are broken by id
+// in the direction of the last sort field.
+func CompareUsers(a, b User, sort []UserSort) int {
+	for _, s := range sort {
+		c := compareField(a, b, s.Field)
+		if s.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+	}
+
+	c := bytes.Compare(a.Id[:], b.Id[:])
+	if len(sort) > 0 && sort[len(sort)-1].Desc {
+		c = -c
+	}
+	return c
+}
+
+func compareField(a, b User, field UserSortField) int {
+	switch field {
+	case SortByCreatedAt:
+		return a.CreatedAt.Compare(b.CreatedAt)
+	case SortByFirstName:
+		return strings.Compare(a.FirstName, b.FirstName)
+	case SortByLastName:
+		return strings.Compare(a.LastName, b.LastName)
+	case SortByEmail:
+		return strings.Compare(a.Email, b.Email)
+	case SortByAge:
+		return int(a.Age) - int(b.Age)
+	}
+	return 0
+}
+
+// Matches reports whether the user passes the filter
+func (f UserFilter) Matches(u User) bool {
+	if f.Name != "" {
+		name := strings.ToLower(f.Name)
+		if !strings.Contains(strings.ToLower(u.FirstName), name) &&
+			!strings.Contains(strings.ToLower(u.LastName), name) {
+			return false
+		}
+	}
+	if f.EmailDomain != "" && !strings.HasSuffix(strings.ToLower(u.Email), "@"+strings.ToLower(f.EmailDomain)) {
+		return false
+	}
+	if f.MinAge != 0 && u.Age < f.MinAge {
+		return false
+	}
+	if f.MaxAge != 0 && u.Age > f.MaxAge {
+		return false
+	}
+	if !f.CreatedAfter.IsZero() && u.CreatedAt.Before(f.CreatedAfter) {
+		return false
+	}
+	if !f.CreatedBefore.IsZero() && !u.CreatedAt.Before(f.CreatedBefore) {
+		return false
+	}
+	return true
+}
+
+func sortKey(sort []UserSort) string {
+	parts := make([]string, len(sort))
+	for i, s := range sort {
+		parts[i] = string(s.Field)
+		if s.Desc {
+			parts[i] = "-" + parts[i]
+		}
+	}
+	return strings.Join(parts, ",")
+}
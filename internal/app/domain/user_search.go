@@ -0,0 +1,141 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+const (
+	maxSearchQueryLength = 100
+
+	// same default as pg_trgm.similarity_threshold
+	similarityThreshold = 0.3
+	// roughly what ts_rank gives a full prefix match in pgrepo
+	prefixMatchScore = 0.1
+)
+
+type SearchUsersQuery struct {
+	Query string
+	Limit int
+}
+
+// UserMatch is a search hit, a higher score means a better match
+type UserMatch struct {
+	User  User    `json:"user"`
+	Score float64 `json:"score"`
+}
+
+func (q SearchUsersQuery) Normalize() (SearchUsersQuery, error) {
+	q.Query = strings.ToLower(strings.TrimSpace(q.Query))
+	if len(SearchTerms(q.Query)) == 0 {
+		return q, fmt.Errorf("%w: empty search query", ErrInvalidQuery)
+	}
+	if len([]rune(q.Query)) > maxSearchQueryLength {
+		return q, fmt.Errorf("%w: search query is too long", ErrInvalidQuery)
+	}
+
+	switch {
+	case q.Limit <= 0:
+		q.Limit = DefaultListLimit
+	case q.Limit > MaxListLimit:
+		q.Limit = MaxListLimit
+	}
+	return q, nil
+}
+
+// SearchTerms splits a search string into lower-case words the same way
+// pg_trgm does: anything that is not a letter or a digit is a separator.
+func SearchTerms(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// ScoreUser approximates the Postgres full-text and trigram ranking of pgrepo
+// for storages without those indexes. ok is false when the user does not match.
+func ScoreUser(query string, user User) (score float64, ok bool) {
+	terms := SearchTerms(query)
+	q := trigrams(strings.Join(terms, " "))
+
+	best := max(
+		similarity(q, trigrams(user.FirstName)),
+		similarity(q, trigrams(user.LastName)),
+		similarity(q, trigrams(user.Email)),
+	)
+	prefix := prefixMatch(terms, user)
+	if !prefix && best < similarityThreshold {
+		return 0, false
+	}
+
+	if prefix {
+		best += prefixMatchScore
+	}
+	return best, true
+}
+
+// RankUserMatches orders matches best first and cuts them to limit
+func RankUserMatches(matches []UserMatch, limit int) []UserMatch {
+	slices.SortFunc(matches, func(a, b UserMatch) int {
+		if a.Score != b.Score {
+			if a.Score > b.Score {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a.User.Id.String(), b.User.Id.String())
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+	return matches
+}
+
+// prefixMatch reports whether every term is a prefix of some word of the user,
+// like a to_tsquery('simple', 'term:* & ...') match.
+func prefixMatch(terms []string, user User) bool {
+	words := SearchTerms(user.FirstName + " " + user.LastName)
+	words = append(words, strings.ToLower(user.Email))
+
+	for _, term := range terms {
+		found := false
+		for _, word := range words {
+			if strings.HasPrefix(word, term) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// trigrams follows pg_trgm: every word is lower-cased and padded with
+// two spaces in front and one behind before being cut into trigrams.
+func trigrams(s string) map[string]struct{} {
+	set := make(map[string]struct{})
+	for _, word := range SearchTerms(s) {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = struct{}{}
+		}
+	}
+	return set
+}
+
+func similarity(a, b map[string]struct{}) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+
+	shared := 0
+	for t := range a {
+		if _, ok := b[t]; ok {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(a)+len(b)-shared)
+}
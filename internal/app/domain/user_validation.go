@@ -0,0 +1,162 @@
+package domain
+
+import (
+	"fmt"
+	"net/mail"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/google/uuid"
+)
+
+// Limits of the user invariants
+const (
+	MaxNameLength  = 100
+	MaxEmailLength = 254
+	MinAge         = 1
+	MaxAge         = 130
+)
+
+// Validation rules, they double as translation keys of the transport
+const (
+	RuleRequired = "required"
+	RuleName     = "name"
+	RuleEmail    = "email"
+	RuleMin      = "min"
+	RuleMax      = "max"
+	RuleMinLen   = "min_len"
+	RuleMaxLen   = "max_len"
+	RuleOneOf    = "oneof"
+)
+
+// FieldViolation is one broken rule. Field uses the snake_case names of the
+// audit log, Param is the rule argument such as the max age.
+type FieldViolation struct {
+	Field string
+	Rule  string
+	Param string
+	Value any
+}
+
+// ValidationError lists every broken rule of an entity, it matches ErrValidation
+type ValidationError struct {
+	Violations []FieldViolation
+}
+
+func (e *ValidationError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = v.Field + ": " + v.Rule
+		if v.Param != "" {
+			parts[i] += "=" + v.Param
+		}
+	}
+	return fmt.Sprintf("%s: %s", ErrValidation, strings.Join(parts, ", "))
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrValidation
+}
+
+// NewUser builds a valid user with a fresh id, names are trimmed and the email normalized
+func NewUser(firstName, lastName, email string, age uint8) (User, error) {
+	user := User{
+		Id:        uuid.New(),
+		FirstName: strings.TrimSpace(firstName),
+		LastName:  strings.TrimSpace(lastName),
+		Email:     NormalizeEmail(email),
+		Age:       age,
+		Role:      RoleUser,
+	}
+	if err := user.Validate(); err != nil {
+		return User{}, err
+	}
+	return user, nil
+}
+
+// Validate checks the invariants every stored user has to hold,
+// UserService enforces them whatever transport the user came from
+func (u User) Validate() error {
+	var violations []FieldViolation
+	check := func(field string, value any, rule, param string, ok bool) {
+		if !ok {
+			violations = append(violations, FieldViolation{Field: field, Rule: rule, Param: param, Value: value})
+		}
+	}
+
+	for _, name := range []struct {
+		field string
+		value string
+	}{{"first_name", u.FirstName}, {"last_name", u.LastName}} {
+		switch {
+		case name.value == "":
+			check(name.field, name.value, RuleRequired, "", false)
+		case utf8.RuneCountInString(name.value) > MaxNameLength:
+			check(name.field, name.value, RuleMaxLen, fmt.Sprint(MaxNameLength), false)
+		default:
+			check(name.field, name.value, RuleName, "", ValidName(name.value))
+		}
+	}
+
+	switch {
+	case u.Email == "":
+		check("email", u.Email, RuleRequired, "", false)
+	case len(u.Email) > MaxEmailLength:
+		check("email", u.Email, RuleMaxLen, fmt.Sprint(MaxEmailLength), false)
+	default:
+		check("email", u.Email, RuleEmail, "", ValidEmail(u.Email))
+	}
+
+	check("age", u.Age, RuleMin, fmt.Sprint(MinAge), u.Age >= MinAge)
+	check("age", u.Age, RuleMax, fmt.Sprint(MaxAge), u.Age <= MaxAge)
+
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+	return nil
+}
+
+// ValidName accepts human names of any script: letters with combining marks,
+// joined by single hyphens, apostrophes or spaces, e.g. "Анна", "Mary-Jane",
+// "O'Brien" or "van der Berg". The length limit is checked by Validate.
+func ValidName(name string) bool {
+	if name == "" {
+		return false
+	}
+	prevLetter := false
+	for _, r := range name {
+		switch {
+		case unicode.IsLetter(r):
+			prevLetter = true
+		case unicode.Is(unicode.Mn, r) || unicode.Is(unicode.Mc, r):
+			// a combining mark belongs to the letter before it
+			if !prevLetter {
+				return false
+			}
+		case isNameSeparator(r):
+			if !prevLetter {
+				return false
+			}
+			prevLetter = false
+		default:
+			return false
+		}
+	}
+	// the name can not end with a separator
+	return prevLetter
+}
+
+func isNameSeparator(r rune) bool {
+	switch r {
+	case '-', '\'', '’', ' ':
+		return true
+	}
+	return false
+}
+
+// ValidEmail accepts a bare address like user@example.com, without a display name
+func ValidEmail(email string) bool {
+	addr, err := mail.ParseAddress(email)
+	return err == nil && addr.Address == email && addr.Name == ""
+}
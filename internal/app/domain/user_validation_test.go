@@ -0,0 +1,44 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidName(t *testing.T) {
+	valid := []string{"Anna", "Анна", "Mary-Jane", "O'Brien", "D’Angelo", "van der Berg", "José", "Zoë", "李"}
+	for _, name := range valid {
+		assert.True(t, ValidName(name), name)
+	}
+
+	invalid := []string{"", " Anna", "Anna ", "-Anna", "Anna-", "Mary--Jane", "O' Brien", "John3", "R2-D2", "Anna!", "́Anna"}
+	for _, name := range invalid {
+		assert.False(t, ValidName(name), name)
+	}
+}
+
+func TestUser_Validate(t *testing.T) {
+	user, err := NewUser(" Анна ", "O'Brien", "anna@example.com", 30)
+	require.NoError(t, err)
+	assert.Equal(t, "Анна", user.FirstName)
+
+	_, err = NewUser("John3", strings.Repeat("a", MaxNameLength+1), "Anna <anna@example.com>", 0)
+	require.Error(t, err)
+	assert.ErrorIs(t, err, ErrValidation)
+
+	var validationErr *ValidationError
+	require.True(t, errors.As(err, &validationErr))
+	assert.Equal(t, []FieldViolation{
+		{Field: "first_name", Rule: RuleName, Value: "John3"},
+		{Field: "last_name", Rule: RuleMaxLen, Param: "100", Value: strings.Repeat("a", MaxNameLength+1)},
+		{Field: "email", Rule: RuleEmail, Value: "Anna <anna@example.com>"},
+		{Field: "age", Rule: RuleMin, Param: "1", Value: uint8(0)},
+	}, validationErr.Violations)
+
+	user.Age = MaxAge + 1
+	assert.ErrorIs(t, user.Validate(), ErrValidation)
+}
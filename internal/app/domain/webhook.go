@@ -0,0 +1,206 @@
+package domain
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/netip"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// DeliveryStatus is where a webhook delivery stands
+type DeliveryStatus string
+
+const (
+	// DeliveryPending waits for its next attempt
+	DeliveryPending DeliveryStatus = "pending"
+	// DeliverySucceeded got a 2xx answer
+	DeliverySucceeded DeliveryStatus = "succeeded"
+	// DeliveryDead failed its last attempt and is only sent again on request
+	DeliveryDead DeliveryStatus = "dead"
+)
+
+const (
+	// RuleHTTPURL is the validation rule of webhook URLs
+	RuleHTTPURL = "http_url"
+	// RulePublicURL rejects webhook URLs that point into the internal network
+	RulePublicURL = "public_url"
+)
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598, private in
+// all but name
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// PublicAddr tells whether webhooks may be sent to ip. Loopback, private,
+// link-local (cloud metadata endpoints among them), multicast and unspecified
+// addresses may not, so a subscription can not reach into the internal network.
+func PublicAddr(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	return ip.IsValid() && ip.IsGlobalUnicast() && !ip.IsPrivate() && !sharedAddressSpace.Contains(ip)
+}
+
+// WebhookSubscription sends the user events it matches to URL, signed with Secret
+type WebhookSubscription struct {
+	Id  uuid.UUID `gorm:"type:uuid;primaryKey"`
+	URL string
+	// Secret is the HMAC-SHA256 key of the signatures
+	Secret string
+	// EventTypes and Fields filter the events, empty lists match every event
+	EventTypes []UserEventType `gorm:"type:jsonb;serializer:json"`
+	Fields     []string        `gorm:"type:jsonb;serializer:json"`
+	Active     bool
+	CreatedAt  time.Time
+	UpdatedAt  time.Time
+}
+
+// Match tells whether the event goes to the subscription
+func (s WebhookSubscription) Match(event OutboxEvent) bool {
+	if !s.Active {
+		return false
+	}
+	if len(s.EventTypes) > 0 && !slices.Contains(s.EventTypes, event.Type) {
+		return false
+	}
+	return UserEventFilter{Fields: s.Fields}.Match(event)
+}
+
+// Validate checks the invariants every stored subscription holds, callbacks go
+// to absolute http or https URLs only. A host given as an address has to be
+// public, the ones given as names are checked when they are dialed.
+func (s WebhookSubscription) Validate() error {
+	var violations []FieldViolation
+	if u, err := url.Parse(s.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		violations = append(violations, FieldViolation{Field: "url", Rule: RuleHTTPURL, Value: s.URL})
+	} else if !publicHost(u.Hostname()) {
+		violations = append(violations, FieldViolation{Field: "url", Rule: RulePublicURL, Value: s.URL})
+	}
+	if s.Secret == "" {
+		violations = append(violations, FieldViolation{Field: "secret", Rule: RuleRequired})
+	}
+	if len(violations) > 0 {
+		return &ValidationError{Violations: violations}
+	}
+	return nil
+}
+
+func publicHost(host string) bool {
+	host = strings.ToLower(strings.TrimSuffix(host, "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if ip, err := netip.ParseAddr(host); err == nil {
+		return PublicAddr(ip)
+	}
+	return true
+}
+
+// WebhookDelivery is one event on its way to one subscription
+type WebhookDelivery struct {
+	Id             int64     `gorm:"primaryKey;autoIncrement"`
+	SubscriptionId uuid.UUID `gorm:"type:uuid"`
+	EventId        int64
+	EventType      UserEventType
+	// Payload is the request body, the signature covers it byte for byte
+	Payload        []byte
+	Status         DeliveryStatus
+	Attempts       int
+	NextAttemptAt  time.Time
+	LastStatusCode int
+	LastError      string
+	CreatedAt      time.Time
+	UpdatedAt      time.Time
+}
+
+// DeliveryAttempt is the outcome of sending a delivery once
+type DeliveryAttempt struct {
+	StatusCode int
+	Err        error
+}
+
+// Succeeded tells whether the receiver accepted the delivery
+func (a DeliveryAttempt) Succeeded() bool {
+	return a.Err == nil && a.StatusCode >= 200 && a.StatusCode < 300
+}
+
+// Record applies the attempt to the delivery. A failed attempt is retried at
+// now+backoff, or the delivery is dead once it used up maxAttempts.
+func (d *WebhookDelivery) Record(attempt DeliveryAttempt, now time.Time, maxAttempts int, backoff time.Duration) {
+	d.Attempts++
+	d.LastStatusCode = attempt.StatusCode
+	d.LastError = ""
+	d.UpdatedAt = now
+	switch {
+	case attempt.Succeeded():
+		d.Status = DeliverySucceeded
+		return
+	case attempt.Err != nil:
+		d.LastError = attempt.Err.Error()
+	default:
+		d.LastError = fmt.Sprintf("receiver answered %d", attempt.StatusCode)
+	}
+	if d.Attempts >= maxAttempts {
+		d.Status = DeliveryDead
+		return
+	}
+	d.Status = DeliveryPending
+	d.NextAttemptAt = now.Add(backoff)
+}
+
+// Redeliver queues the delivery for an immediate attempt with a fresh set of retries
+func (d *WebhookDelivery) Redeliver(now time.Time) {
+	d.Status = DeliveryPending
+	d.Attempts = 0
+	d.NextAttemptAt = now
+	d.UpdatedAt = now
+}
+
+// DeliveryQuery lists the deliveries of a subscription, newest first
+type DeliveryQuery struct {
+	SubscriptionId uuid.UUID
+	Status         DeliveryStatus
+	Limit          int
+	Cursor         string
+}
+
+type DeliveryPage struct {
+	Deliveries []WebhookDelivery
+	NextCursor string
+}
+
+func (q DeliveryQuery) Normalize() (DeliveryQuery, error) {
+	switch {
+	case q.Limit <= 0:
+		q.Limit = DefaultListLimit
+	case q.Limit > MaxListLimit:
+		q.Limit = MaxListLimit
+	}
+	if _, err := q.Before(); err != nil {
+		return q, err
+	}
+	return q, nil
+}
+
+// Before returns the id the next page starts below, 0 for the first page
+func (q DeliveryQuery) Before() (int64, error) {
+	if q.Cursor == "" {
+		return 0, nil
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(q.Cursor)
+	if err != nil {
+		return 0, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	id, err := strconv.ParseInt(string(raw), 10, 64)
+	if err != nil || id <= 0 {
+		return 0, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	return id, nil
+}
+
+func NextDeliveryCursor(last WebhookDelivery) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(last.Id, 10)))
+}
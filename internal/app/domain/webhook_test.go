@@ -0,0 +1,52 @@
+package domain
+
+import (
+	"net/netip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPublicAddr(t *testing.T) {
+	for addr, public := range map[string]bool{
+		"93.184.216.34":    true,
+		"2606:4700::1111":  true,
+		"127.0.0.1":        false,
+		"::1":              false,
+		"10.1.2.3":         false,
+		"172.16.0.1":       false,
+		"192.168.1.1":      false,
+		"169.254.169.254":  false,
+		"fe80::1":          false,
+		"fd00::1":          false,
+		"100.64.0.1":       false,
+		"0.0.0.0":          false,
+		"224.0.0.1":        false,
+		"::ffff:127.0.0.1": false,
+	} {
+		assert.Equal(t, public, PublicAddr(netip.MustParseAddr(addr)), addr)
+	}
+}
+
+func TestWebhookSubscription_ValidateURL(t *testing.T) {
+	for url, rule := range map[string]string{
+		"https://hooks.example.com/users": "",
+		"https://93.184.216.34/users":     "",
+		"ftp://hooks.example.com":         RuleHTTPURL,
+		"http://localhost:8080/hook":      RulePublicURL,
+		"http://127.0.0.1/hook":           RulePublicURL,
+		"http://[::1]/hook":               RulePublicURL,
+		"http://169.254.169.254/latest":   RulePublicURL,
+		"http://10.0.0.5/hook":            RulePublicURL,
+	} {
+		err := WebhookSubscription{URL: url, Secret: "secret"}.Validate()
+		if rule == "" {
+			assert.NoError(t, err, url)
+			continue
+		}
+		var verr *ValidationError
+		if assert.ErrorAs(t, err, &verr, url) {
+			assert.Equal(t, rule, verr.Violations[0].Rule, url)
+		}
+	}
+}
@@ -0,0 +1,24 @@
+CREATE EXTENSION IF NOT EXISTS "uuid-ossp";
+
+CREATE TABLE IF NOT EXISTS users (
+    id         uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    first_name text,
+    last_name  text,
+    email      text,
+    age        smallint,
+    created_at timestamptz,
+    deleted_at timestamptz
+);
+
+-- databases from before migrations already have the table AutoMigrate created,
+-- without the soft delete column and with a plain unique email constraint that
+-- would keep soft-deleted users holding their email
+ALTER TABLE users ADD COLUMN IF NOT EXISTS created_at timestamptz;
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at timestamptz;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS users_email_key;
+ALTER TABLE users DROP CONSTRAINT IF EXISTS uni_users_email;
+
+-- soft-deleted users free their email
+CREATE UNIQUE INDEX IF NOT EXISTS idx_users_email ON users (email) WHERE deleted_at IS NULL;
+CREATE INDEX IF NOT EXISTS idx_users_deleted_at ON users (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_users_created_at_id ON users (created_at, id);
@@ -0,0 +1,13 @@
+CREATE TABLE IF NOT EXISTS audit_records (
+    id         uuid PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id    uuid        NOT NULL,
+    operation  text        NOT NULL,
+    actor      text        NOT NULL,
+    request_id text        NOT NULL DEFAULT '',
+    client_ip  text        NOT NULL DEFAULT '',
+    changes    jsonb       NOT NULL DEFAULT '[]',
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+-- no foreign key on purpose: the history outlives a purged user
+CREATE INDEX IF NOT EXISTS idx_audit_records_user_created ON audit_records (user_id, created_at DESC, id DESC);
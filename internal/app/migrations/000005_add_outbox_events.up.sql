@@ -0,0 +1,10 @@
+CREATE TABLE IF NOT EXISTS outbox_events (
+    id           bigserial PRIMARY KEY,
+    aggregate_id uuid        NOT NULL,
+    type         text        NOT NULL,
+    payload      jsonb       NOT NULL,
+    created_at   timestamptz NOT NULL DEFAULT now(),
+    published_at timestamptz
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_events_unpublished ON outbox_events (id) WHERE published_at IS NULL;
@@ -0,0 +1,8 @@
+-- emails are unique regardless of case, Bob@Example.com and bob@example.com are one account.
+-- Fails if such duplicates already exist, they have to be merged by hand first.
+-- Unicode domains are brought to punycode by the NormalizeEmails step that
+-- runs after the SQL migrations, SQL has no IDNA conversion.
+UPDATE users SET email = trim(email) WHERE email <> trim(email);
+
+DROP INDEX IF EXISTS idx_users_email;
+CREATE UNIQUE INDEX idx_users_email ON users (lower(email)) WHERE deleted_at IS NULL;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS idempotency_records (
+    key         text        PRIMARY KEY,
+    fingerprint text        NOT NULL,
+    status      integer     NOT NULL DEFAULT 0,
+    headers     jsonb,
+    body        bytea,
+    created_at  timestamptz NOT NULL DEFAULT now(),
+    expires_at  timestamptz NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_idempotency_records_expires_at ON idempotency_records (expires_at);
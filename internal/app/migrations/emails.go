@@ -0,0 +1,52 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+// nonASCIIEmail matches the stored emails that may not be normalized yet, per
+// SQL dialect. Everything else was trimmed and is ASCII already, its case does
+// not matter to the lower(email) unique index.
+var nonASCIIEmail = map[string]string{
+	"postgres": "email ~ '[^ -~]'",
+	"sqlite":   "email GLOB '*[^ -~]*'",
+}
+
+// NormalizeEmails rewrites the emails stored before domain.NormalizeEmail
+// existed, e.g. with a Unicode domain. SQL can not convert a domain to
+// punycode, so this step runs after the SQL migrations. It is safe to run
+// again and fails when a normalized email is taken by another active user,
+// such accounts have to be merged by hand like the ones 000006 rejects.
+func NormalizeEmails(ctx context.Context, db *gorm.DB) error {
+	filter, ok := nonASCIIEmail[db.Dialector.Name()]
+	if !ok {
+		return fmt.Errorf("unable to normalize emails: unsupported dialect %s", db.Dialector.Name())
+	}
+
+	var rows []struct {
+		Id    uuid.UUID
+		Email string
+	}
+	if err := db.WithContext(ctx).Table("users").Select("id, email").Where(filter).Scan(&rows).Error; err != nil {
+		return fmt.Errorf("unable to read emails: %w", err)
+	}
+	for _, row := range rows {
+		email := domain.NormalizeEmail(row.Email)
+		if email == row.Email {
+			continue
+		}
+		err := db.WithContext(ctx).Table("users").
+			Where("id = ?", row.Id).
+			Updates(map[string]any{"email": email, "version": gorm.Expr("version + 1")}).Error
+		if err != nil {
+			return fmt.Errorf("unable to normalize email of user %s to %s: %w", row.Id, email, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package migrations
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"github.com/vlad19930514/webApp/internal/app/migrations/sqlite"
+	sqlitedb "github.com/vlad19930514/webApp/internal/pkg/sqlite"
+)
+
+func TestNormalizeEmails(t *testing.T) {
+	db, err := sqlitedb.Open(":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB.DB()
+		sqlDB.Close()
+	})
+	ctx := context.Background()
+	require.NoError(t, sqlitedb.Migrate(ctx, db, sqlite.FS))
+
+	// rows written before emails were normalized
+	legacy, ascii := uuid.New(), uuid.New()
+	insert := "INSERT INTO users (id, first_name, last_name, email, age, created_at, version) VALUES (?, 'Bob', 'Brown', ?, 33, CURRENT_TIMESTAMP, 1)"
+	require.NoError(t, db.Exec(insert, legacy, "Bob@Exämple.com").Error)
+	require.NoError(t, db.Exec(insert, ascii, "alice@Example.com").Error)
+
+	require.NoError(t, NormalizeEmails(ctx, db.DB))
+	require.NoError(t, NormalizeEmails(ctx, db.DB))
+
+	var users []domain.User
+	require.NoError(t, db.Order("email").Find(&users).Error)
+	require.Len(t, users, 2)
+	assert.Equal(t, "Bob@xn--exmple-cua.com", users[0].Email)
+	assert.Equal(t, int64(2), users[0].Version)
+	assert.Equal(t, "alice@Example.com", users[1].Email)
+	assert.Equal(t, int64(1), users[1].Version)
+
+	// the normalized email of another active user can not be taken over
+	require.NoError(t, db.Exec(insert, uuid.New(), "BOB@exämple.com").Error)
+	assert.ErrorContains(t, NormalizeEmails(ctx, db.DB), "unable to normalize email")
+}
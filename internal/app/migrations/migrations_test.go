@@ -0,0 +1,94 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/vlad19930514/webApp/internal/pkg/pg"
+)
+
+func TestMigrationsArePaired(t *testing.T) {
+	names, err := fs.Glob(FS, "*.sql")
+	require.NoError(t, err)
+	require.NotEmpty(t, names)
+
+	files := make(map[string]bool)
+	for _, name := range names {
+		files[name] = true
+	}
+	for _, name := range names {
+		if up, ok := strings.CutSuffix(name, ".up.sql"); ok {
+			assert.True(t, files[up+".down.sql"], "missing down migration for %s", name)
+		}
+		if down, ok := strings.CutSuffix(name, ".down.sql"); ok {
+			assert.True(t, files[down+".up.sql"], "missing up migration for %s", name)
+		}
+	}
+
+	source, err := iofs.New(FS, ".")
+	require.NoError(t, err)
+	defer source.Close()
+
+	version, err := source.First()
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), version)
+}
+
+// baselineUser is the users table as AutoMigrate created it before there were migrations
+type baselineUser struct {
+	Id        uuid.UUID `gorm:"type:uuid;default:uuid_generate_v4();primaryKey"`
+	FirstName string
+	LastName  string
+	Email     string `gorm:"type:text;unique"`
+	Age       uint8
+	CreatedAt time.Time
+}
+
+func (baselineUser) TableName() string { return "users" }
+
+// TestMigrationsUpgradeBaselineSchema applies the migrations on top of the
+// AutoMigrate schema. It needs a Postgres database, TEST_POSTGRES_DSN is a DSN
+// in the key=value form and the test works in a schema of its own.
+func TestMigrationsUpgradeBaselineSchema(t *testing.T) {
+	dsn := os.Getenv("TEST_POSTGRES_DSN")
+	if dsn == "" {
+		t.Skip("TEST_POSTGRES_DSN is not set")
+	}
+	ctx := context.Background()
+
+	root, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	schema := fmt.Sprintf("migrations_test_%d", time.Now().UnixNano())
+	require.NoError(t, root.Exec("CREATE SCHEMA "+schema).Error)
+	t.Cleanup(func() { root.Exec("DROP SCHEMA " + schema + " CASCADE") })
+
+	dsn += " search_path=" + schema + ",public"
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Exec(`CREATE EXTENSION IF NOT EXISTS "uuid-ossp"`).Error)
+	require.NoError(t, db.AutoMigrate(&baselineUser{}))
+	require.NoError(t, db.Create(&baselineUser{FirstName: "Bob", LastName: "Brown", Email: "bob@example.com", Age: 33}).Error)
+
+	migrator, err := pg.NewMigrator(dsn, FS)
+	require.NoError(t, err)
+	defer migrator.Close()
+	require.NoError(t, migrator.Up(ctx))
+
+	// the baseline unique constraint is gone, a soft-deleted user frees the email
+	require.NoError(t, db.Exec("UPDATE users SET deleted_at = now() WHERE email = 'bob@example.com'").Error)
+	require.NoError(t, db.Exec("INSERT INTO users (first_name, last_name, email, age, created_at) VALUES ('Bob', 'Brown', 'bob@example.com', 33, now())").Error)
+	err = db.Exec("INSERT INTO users (first_name, last_name, email, age, created_at) VALUES ('Bob', 'Brown', 'BOB@example.com', 33, now())").Error
+	assert.Error(t, err)
+}
@@ -0,0 +1,15 @@
+CREATE TABLE users (
+    id         TEXT PRIMARY KEY NOT NULL,
+    first_name TEXT     NOT NULL DEFAULT '',
+    last_name  TEXT     NOT NULL DEFAULT '',
+    email      TEXT     NOT NULL,
+    age        INTEGER  NOT NULL DEFAULT 0,
+    created_at DATETIME NOT NULL,
+    deleted_at DATETIME,
+    version    INTEGER  NOT NULL DEFAULT 1
+);
+
+-- soft-deleted users free their email
+CREATE UNIQUE INDEX idx_users_email ON users (email) WHERE deleted_at IS NULL;
+CREATE INDEX idx_users_deleted_at ON users (deleted_at);
+CREATE INDEX idx_users_created_at_id ON users (created_at, id);
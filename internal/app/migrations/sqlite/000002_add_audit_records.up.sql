@@ -0,0 +1,12 @@
+CREATE TABLE audit_records (
+    id         TEXT PRIMARY KEY NOT NULL,
+    user_id    TEXT     NOT NULL,
+    operation  TEXT     NOT NULL,
+    actor      TEXT     NOT NULL,
+    request_id TEXT     NOT NULL DEFAULT '',
+    client_ip  TEXT     NOT NULL DEFAULT '',
+    changes    TEXT     NOT NULL DEFAULT '[]',
+    created_at DATETIME NOT NULL
+);
+
+CREATE INDEX idx_audit_records_user_created ON audit_records (user_id, created_at DESC, id DESC);
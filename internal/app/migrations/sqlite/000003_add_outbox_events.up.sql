@@ -0,0 +1,10 @@
+CREATE TABLE outbox_events (
+    id           INTEGER PRIMARY KEY AUTOINCREMENT,
+    aggregate_id TEXT     NOT NULL,
+    type         TEXT     NOT NULL,
+    payload      TEXT     NOT NULL,
+    created_at   DATETIME NOT NULL,
+    published_at DATETIME
+);
+
+CREATE INDEX idx_outbox_events_unpublished ON outbox_events (id) WHERE published_at IS NULL;
@@ -0,0 +1,3 @@
+-- emails are unique regardless of case, see the postgres migration of the same name
+DROP INDEX IF EXISTS idx_users_email;
+CREATE UNIQUE INDEX idx_users_email ON users (lower(email)) WHERE deleted_at IS NULL;
@@ -0,0 +1,11 @@
+CREATE TABLE idempotency_records (
+    key         TEXT     PRIMARY KEY,
+    fingerprint TEXT     NOT NULL,
+    status      INTEGER  NOT NULL DEFAULT 0,
+    headers     TEXT,
+    body        BLOB,
+    created_at  DATETIME NOT NULL,
+    expires_at  DATETIME NOT NULL
+);
+
+CREATE INDEX idx_idempotency_records_expires_at ON idempotency_records (expires_at);
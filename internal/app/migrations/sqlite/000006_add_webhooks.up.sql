@@ -0,0 +1,28 @@
+CREATE TABLE webhook_subscriptions (
+    id          TEXT     PRIMARY KEY,
+    url         TEXT     NOT NULL,
+    secret      TEXT     NOT NULL,
+    event_types TEXT,
+    fields      TEXT,
+    active      BOOLEAN  NOT NULL DEFAULT 1,
+    created_at  DATETIME NOT NULL,
+    updated_at  DATETIME NOT NULL
+);
+
+CREATE TABLE webhook_deliveries (
+    id               INTEGER  PRIMARY KEY AUTOINCREMENT,
+    subscription_id  TEXT     NOT NULL REFERENCES webhook_subscriptions (id) ON DELETE CASCADE,
+    event_id         INTEGER  NOT NULL,
+    event_type       TEXT     NOT NULL,
+    payload          BLOB     NOT NULL,
+    status           TEXT     NOT NULL,
+    attempts         INTEGER  NOT NULL DEFAULT 0,
+    next_attempt_at  DATETIME NOT NULL,
+    last_status_code INTEGER  NOT NULL DEFAULT 0,
+    last_error       TEXT     NOT NULL DEFAULT '',
+    created_at       DATETIME NOT NULL,
+    updated_at       DATETIME NOT NULL
+);
+
+CREATE UNIQUE INDEX idx_webhook_deliveries_event ON webhook_deliveries (subscription_id, event_id);
+CREATE INDEX idx_webhook_deliveries_due ON webhook_deliveries (next_attempt_at) WHERE status = 'pending';
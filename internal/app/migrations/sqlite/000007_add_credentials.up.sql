@@ -0,0 +1,5 @@
+CREATE TABLE credentials (
+    user_id       TEXT     PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+    password_hash TEXT     NOT NULL,
+    updated_at    DATETIME NOT NULL
+);
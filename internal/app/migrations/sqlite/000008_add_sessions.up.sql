@@ -0,0 +1,22 @@
+CREATE TABLE sessions (
+    id           TEXT     PRIMARY KEY,
+    user_id      TEXT     NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    user_agent   TEXT     NOT NULL DEFAULT '',
+    client_ip    TEXT     NOT NULL DEFAULT '',
+    created_at   DATETIME NOT NULL,
+    last_used_at DATETIME NOT NULL,
+    expires_at   DATETIME NOT NULL,
+    revoked_at   DATETIME
+);
+
+CREATE INDEX idx_sessions_user_id ON sessions (user_id);
+
+CREATE TABLE refresh_tokens (
+    id         TEXT     PRIMARY KEY,
+    session_id TEXT     NOT NULL REFERENCES sessions (id) ON DELETE CASCADE,
+    token_hash TEXT     NOT NULL,
+    created_at DATETIME NOT NULL,
+    used_at    DATETIME
+);
+
+CREATE UNIQUE INDEX idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
@@ -0,0 +1,7 @@
+// Package sqlite holds the schema migrations of the SQLite storage, they are forward-only
+package sqlite
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
@@ -0,0 +1,33 @@
+package sqlite
+
+import (
+	"context"
+	"io/fs"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vlad19930514/webApp/internal/pkg/sqlite"
+)
+
+func TestMigrations(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlite.Open(":memory:")
+	require.NoError(t, err)
+
+	require.NoError(t, sqlite.Migrate(ctx, db, FS))
+	// applying again is a no-op
+	require.NoError(t, sqlite.Migrate(ctx, db, FS))
+
+	files, err := fs.Glob(FS, "*.up.sql")
+	require.NoError(t, err)
+	var version int
+	require.NoError(t, db.Raw("SELECT max(version) FROM schema_migrations").Scan(&version).Error)
+	assert.Equal(t, len(files), version)
+
+	for _, table := range []string{"users", "audit_records", "outbox_events", "webhook_subscriptions", "webhook_deliveries", "credentials", "sessions", "refresh_tokens"} {
+		var count int
+		require.NoError(t, db.Raw("SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?", table).Scan(&count).Error)
+		assert.Equal(t, 1, count, table)
+	}
+}
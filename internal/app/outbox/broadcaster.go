@@ -0,0 +1,138 @@
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/rs/zerolog/log"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+// DefaultSubscriberBuffer is how many events a subscriber may fall behind before it is dropped
+const DefaultSubscriberBuffer = 256
+
+// History reads the outbox, subscribers that resume from an event id replay it
+type History interface {
+	ListOutboxEvents(ctx context.Context, afterID int64, limit int) ([]domain.OutboxEvent, error)
+}
+
+// Broadcaster is a Publisher that hands every event to the subscribers of this
+// process. A subscriber that cannot keep up is dropped instead of holding the
+// relay back, it resumes from the last event it got.
+type Broadcaster struct {
+	history History
+	buffer  int
+
+	mu          sync.Mutex
+	subscribers map[chan domain.OutboxEvent]struct{}
+}
+
+func NewBroadcaster(history History) *Broadcaster {
+	return &Broadcaster{
+		history:     history,
+		buffer:      DefaultSubscriberBuffer,
+		subscribers: make(map[chan domain.OutboxEvent]struct{}),
+	}
+}
+
+// Publish never blocks and never fails
+func (b *Broadcaster) Publish(ctx context.Context, event domain.OutboxEvent) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			delete(b.subscribers, ch)
+			close(ch)
+		}
+	}
+	return nil
+}
+
+// Subscribe streams the events published from now on. With afterID > 0 the
+// outbox events after it come first, so a client reconnecting with the id of
+// the last event it got misses nothing. The channel is closed when ctx is done
+// or the subscriber fell too far behind.
+func (b *Broadcaster) Subscribe(ctx context.Context, afterID int64) (<-chan domain.OutboxEvent, error) {
+	// subscribing before the history is read leaves no gap between the two
+	live := make(chan domain.OutboxEvent, b.buffer)
+	b.mu.Lock()
+	b.subscribers[live] = struct{}{}
+	b.mu.Unlock()
+
+	var page []domain.OutboxEvent
+	if afterID > 0 {
+		var err error
+		page, err = b.history.ListOutboxEvents(ctx, afterID, DefaultBatchSize)
+		if err != nil {
+			b.unsubscribe(live)
+			return nil, fmt.Errorf("failed to replay user events: %w", err)
+		}
+	}
+
+	out := make(chan domain.OutboxEvent)
+	go func() {
+		defer close(out)
+		defer b.unsubscribe(live)
+
+		// events still waiting for the relay show up in both, they are sent once
+		replayed := make(map[int64]bool)
+		for len(page) > 0 {
+			for _, event := range page {
+				if !send(ctx, out, event) {
+					return
+				}
+				replayed[event.Id] = true
+			}
+			if len(page) < DefaultBatchSize {
+				break
+			}
+			var err error
+			page, err = b.history.ListOutboxEvents(ctx, page[len(page)-1].Id, DefaultBatchSize)
+			if err != nil {
+				log.Warn().Err(err).Msg("failed to replay user events")
+				return
+			}
+		}
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-live:
+				if !ok {
+					return
+				}
+				if replayed[event.Id] {
+					continue
+				}
+				if !send(ctx, out, event) {
+					return
+				}
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (b *Broadcaster) unsubscribe(ch chan domain.OutboxEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, ok := b.subscribers[ch]; ok {
+		delete(b.subscribers, ch)
+		close(ch)
+	}
+}
+
+func send(ctx context.Context, out chan<- domain.OutboxEvent, event domain.OutboxEvent) bool {
+	select {
+	case out <- event:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,75 @@
+package outbox
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"github.com/vlad19930514/webApp/internal/app/repository/memrepo"
+)
+
+func receive(t *testing.T, events <-chan domain.OutboxEvent) domain.OutboxEvent {
+	t.Helper()
+	select {
+	case event, ok := <-events:
+		require.True(t, ok, "subscription closed")
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("no event received")
+		return domain.OutboxEvent{}
+	}
+}
+
+func TestBroadcaster_ResumesFromHistory(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	store := memrepo.NewOutboxRepo()
+	user := domain.User{Id: uuid.New()}
+	for _, eventType := range []domain.UserEventType{domain.UserCreated, domain.UserUpdated, domain.UserUpdated} {
+		require.NoError(t, store.AddOutboxEvent(ctx, domain.NewUserEvent(eventType, user)))
+	}
+	stored, err := store.ListOutboxEvents(ctx, 0, 10)
+	require.NoError(t, err)
+	broadcaster := NewBroadcaster(store)
+
+	events, err := broadcaster.Subscribe(ctx, stored[0].Id)
+	require.NoError(t, err)
+
+	// the relay publishing replayed events again does not repeat them
+	require.NoError(t, broadcaster.Publish(ctx, stored[2]))
+	live := domain.NewUserEvent(domain.UserDeleted, user)
+	live.Id = stored[2].Id + 1
+	require.NoError(t, broadcaster.Publish(ctx, live))
+
+	assert.Equal(t, stored[1].Id, receive(t, events).Id)
+	assert.Equal(t, stored[2].Id, receive(t, events).Id)
+	assert.Equal(t, live.Id, receive(t, events).Id)
+
+	cancel()
+	_, ok := <-events
+	assert.False(t, ok)
+}
+
+func TestBroadcaster_DropsSlowSubscribers(t *testing.T) {
+	ctx := context.Background()
+	broadcaster := NewBroadcaster(memrepo.NewOutboxRepo())
+	broadcaster.buffer = 1
+
+	events, err := broadcaster.Subscribe(ctx, 0)
+	require.NoError(t, err)
+	// one event is in flight to the reader and one buffered, the rest overflow
+	for i := int64(1); i <= 4; i++ {
+		require.NoError(t, broadcaster.Publish(ctx, domain.OutboxEvent{Id: i}))
+	}
+
+	received := 0
+	for range events {
+		received++
+	}
+	assert.Less(t, received, 4)
+	assert.Empty(t, broadcaster.subscribers)
+}
@@ -0,0 +1,94 @@
+package outbox
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"sync"
+
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+// MemoryPublisher keeps published events in memory, for tests and local runs
+type MemoryPublisher struct {
+	mu     sync.Mutex
+	events []domain.OutboxEvent
+}
+
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+func (p *MemoryPublisher) Publish(ctx context.Context, event domain.OutboxEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.events = append(p.events, event)
+	return nil
+}
+
+// Events returns a copy of everything published so far
+func (p *MemoryPublisher) Events() []domain.OutboxEvent {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return slices.Clone(p.events)
+}
+
+// FanOut publishes every event to all of the publishers. An event that fails
+// on one of them is published to all of them again on the next relay run.
+type FanOut []Publisher
+
+func (f FanOut) Publish(ctx context.Context, event domain.OutboxEvent) error {
+	for _, publisher := range f {
+		if err := publisher.Publish(ctx, event); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// NDJSONPublisher writes every event as one JSON line
+type NDJSONPublisher struct {
+	mu  sync.Mutex
+	w   io.Writer
+	enc *json.Encoder
+}
+
+func NewNDJSONPublisher(w io.Writer) *NDJSONPublisher {
+	return &NDJSONPublisher{w: w, enc: json.NewEncoder(w)}
+}
+
+// NewNDJSONFilePublisher appends events to the file at path
+func NewNDJSONFilePublisher(path string) (*NDJSONPublisher, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open outbox file: %w", err)
+	}
+	return NewNDJSONPublisher(f), nil
+}
+
+func (p *NDJSONPublisher) Publish(ctx context.Context, event domain.OutboxEvent) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if err := p.enc.Encode(event); err != nil {
+		return fmt.Errorf("failed to write event: %w", err)
+	}
+	if f, ok := p.w.(*os.File); ok {
+		if err := f.Sync(); err != nil {
+			return fmt.Errorf("failed to sync outbox file: %w", err)
+		}
+	}
+	return nil
+}
+
+func (p *NDJSONPublisher) Close() error {
+	if c, ok := p.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+// Package outbox publishes the events that UserService stores in the outbox table
+package outbox
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+const (
+	DefaultBatchSize = 100
+	DefaultInterval  = time.Second
+)
+
+// Publisher delivers an event downstream. It may be called again for an event
+// it already got, consumers have to deduplicate by event id.
+type Publisher interface {
+	Publish(ctx context.Context, event domain.OutboxEvent) error
+}
+
+type Store interface {
+	TryLockRelay(ctx context.Context) (bool, error)
+	FetchUnpublished(ctx context.Context, limit int) ([]domain.OutboxEvent, error)
+	MarkPublished(ctx context.Context, ids []int64) error
+}
+
+type TxManager interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+// Relay moves events from the outbox to a publisher with at-least-once delivery.
+// Events are published in id order; when one fails the following events of the
+// same user are held back until the next run so per-user order is kept. What a
+// failed publish wrote in the relay transaction is rolled back.
+type Relay struct {
+	store     Store
+	publisher Publisher
+	tx        TxManager
+	batchSize int
+	interval  time.Duration
+}
+
+func NewRelay(store Store, publisher Publisher, tx TxManager, interval time.Duration) *Relay {
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	return &Relay{
+		store:     store,
+		publisher: publisher,
+		tx:        tx,
+		batchSize: DefaultBatchSize,
+		interval:  interval,
+	}
+}
+
+// Run relays events until ctx is cancelled
+func (r *Relay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := r.RelayOnce(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("outbox relay failed")
+		}
+		// a full batch means there is probably more waiting
+		if err == nil && n == r.batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayOnce publishes one batch and returns how many events were published
+func (r *Relay) RelayOnce(ctx context.Context) (int, error) {
+	published := 0
+	err := r.tx.WithinTx(ctx, func(ctx context.Context) error {
+		locked, err := r.store.TryLockRelay(ctx)
+		if err != nil || !locked {
+			return err
+		}
+
+		events, err := r.store.FetchUnpublished(ctx, r.batchSize)
+		if err != nil {
+			return err
+		}
+
+		var (
+			ids     []int64
+			blocked = make(map[uuid.UUID]bool)
+		)
+		for _, event := range events {
+			if blocked[event.AggregateId] {
+				continue
+			}
+			// a failed statement aborts a Postgres transaction, every publish gets
+			// a savepoint so one bad event does not take the batch down with it
+			err := r.tx.WithinTx(ctx, func(ctx context.Context) error {
+				return r.publisher.Publish(ctx, event)
+			})
+			if err != nil {
+				log.Warn().Err(err).Int64("event_id", event.Id).Msg("failed to publish outbox event")
+				blocked[event.AggregateId] = true
+				continue
+			}
+			ids = append(ids, event.Id)
+		}
+
+		if err := r.store.MarkPublished(ctx, ids); err != nil {
+			return err
+		}
+		published = len(ids)
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to relay outbox events: %w", err)
+	}
+	return published, nil
+}
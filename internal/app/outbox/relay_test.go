@@ -0,0 +1,143 @@
+package outbox
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	sqlitemigrations "github.com/vlad19930514/webApp/internal/app/migrations/sqlite"
+	"github.com/vlad19930514/webApp/internal/app/repository/memrepo"
+	"github.com/vlad19930514/webApp/internal/app/repository/sqliterepo"
+	"github.com/vlad19930514/webApp/internal/pkg/sqlite"
+)
+
+// flakyPublisher fails the first attempt for events of one user
+type flakyPublisher struct {
+	*MemoryPublisher
+	failFor uuid.UUID
+	failed  bool
+}
+
+func (p *flakyPublisher) Publish(ctx context.Context, event domain.OutboxEvent) error {
+	if event.AggregateId == p.failFor && !p.failed {
+		p.failed = true
+		return errors.New("broker unavailable")
+	}
+	return p.MemoryPublisher.Publish(ctx, event)
+}
+
+func TestRelay_KeepsPerUserOrder(t *testing.T) {
+	ctx := context.Background()
+	store := memrepo.NewOutboxRepo()
+	alice, bob := domain.User{Id: uuid.New()}, domain.User{Id: uuid.New()}
+
+	for _, event := range []domain.OutboxEvent{
+		domain.NewUserEvent(domain.UserCreated, alice),
+		domain.NewUserEvent(domain.UserCreated, bob),
+		domain.NewUserEvent(domain.UserUpdated, alice),
+		domain.NewUserEvent(domain.UserUpdated, bob),
+	} {
+		require.NoError(t, store.AddOutboxEvent(ctx, event))
+	}
+
+	publisher := &flakyPublisher{MemoryPublisher: NewMemoryPublisher(), failFor: alice.Id}
+	relay := NewRelay(store, publisher, memrepo.NewTxManager(), 0)
+
+	// alice's first event fails, her second one must wait for it
+	n, err := relay.RelayOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	n, err = relay.RelayOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 2, n)
+
+	n, err = relay.RelayOnce(ctx)
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	var aliceEvents []domain.UserEventType
+	for _, event := range publisher.Events() {
+		if event.AggregateId == alice.Id {
+			aliceEvents = append(aliceEvents, event.Type)
+		}
+	}
+	assert.Equal(t, []domain.UserEventType{domain.UserCreated, domain.UserUpdated}, aliceEvents)
+	assert.Len(t, publisher.Events(), 4)
+}
+
+// recordingPublisher writes an audit record for every event in the relay
+// transaction, then fails the events of one user
+type recordingPublisher struct {
+	audit   *sqliterepo.AuditRepo
+	failFor uuid.UUID
+}
+
+func (p recordingPublisher) Publish(ctx context.Context, event domain.OutboxEvent) error {
+	if err := p.audit.CreateAuditRecord(ctx, domain.NewAuditRecord(domain.RequestMeta{}, domain.AuditCreate, event.AggregateId, nil, nil)); err != nil {
+		return err
+	}
+	if event.AggregateId == p.failFor {
+		return errors.New("broker unavailable")
+	}
+	return nil
+}
+
+func TestRelay_FailedPublishIsRolledBack(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlite.Open(":memory:")
+	require.NoError(t, err)
+	require.NoError(t, sqlite.Migrate(ctx, db, sqlitemigrations.FS))
+	store, audit := sqliterepo.NewOutboxRepo(db), sqliterepo.NewAuditRepo(db)
+
+	alice, bob := domain.User{Id: uuid.New()}, domain.User{Id: uuid.New()}
+	require.NoError(t, store.AddOutboxEvent(ctx, domain.NewUserEvent(domain.UserCreated, alice)))
+	require.NoError(t, store.AddOutboxEvent(ctx, domain.NewUserEvent(domain.UserCreated, bob)))
+
+	relay := NewRelay(store, recordingPublisher{audit: audit, failFor: alice.Id}, sqlite.NewTxManager(db), 0)
+	n, err := relay.RelayOnce(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+
+	// bob's event went through, what alice's failed publish wrote did not stay
+	page, err := audit.ListAuditRecords(ctx, domain.AuditQuery{UserId: bob.Id, Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, page.Records, 1)
+	page, err = audit.ListAuditRecords(ctx, domain.AuditQuery{UserId: alice.Id, Limit: 10})
+	require.NoError(t, err)
+	assert.Empty(t, page.Records)
+	unpublished, err := store.FetchUnpublished(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, unpublished, 1)
+	assert.Equal(t, alice.Id, unpublished[0].AggregateId)
+}
+
+func TestNDJSONPublisher(t *testing.T) {
+	var buf bytes.Buffer
+	publisher := NewNDJSONPublisher(&buf)
+	user := domain.User{Id: uuid.New(), FirstName: "Anna", Email: "anna@example.com", Version: 1}
+
+	require.NoError(t, publisher.Publish(context.Background(), domain.NewUserEvent(domain.UserCreated, user)))
+	require.NoError(t, publisher.Publish(context.Background(), domain.NewUserEvent(domain.UserDeleted, user)))
+
+	lines := bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n"))
+	require.Len(t, lines, 2)
+
+	var decoded struct {
+		Type        string `json:"type"`
+		AggregateId string `json:"aggregate_id"`
+		Payload     struct {
+			Email string `json:"email"`
+		} `json:"payload"`
+	}
+	require.NoError(t, json.Unmarshal(lines[0], &decoded))
+	assert.Equal(t, "UserCreated", decoded.Type)
+	assert.Equal(t, user.Id.String(), decoded.AggregateId)
+	assert.Equal(t, "anna@example.com", decoded.Payload.Email)
+}
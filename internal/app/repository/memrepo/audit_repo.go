@@ -0,0 +1,87 @@
+package memrepo
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+type AuditRepo struct {
+	mu      sync.RWMutex
+	records map[uuid.UUID][]domain.AuditRecord
+}
+
+func NewAuditRepo() *AuditRepo {
+	return &AuditRepo{
+		records: make(map[uuid.UUID][]domain.AuditRecord),
+	}
+}
+
+func (r *AuditRepo) CreateAuditRecord(ctx context.Context, record domain.AuditRecord) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to create audit record: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.records[record.UserId] = append(r.records[record.UserId], record)
+	return nil
+}
+
+func (r *AuditRepo) CreateAuditRecords(ctx context.Context, records []domain.AuditRecord) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to create audit records: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, record := range records {
+		r.records[record.UserId] = append(r.records[record.UserId], record)
+	}
+	return nil
+}
+
+func (r *AuditRepo) ListAuditRecords(ctx context.Context, query domain.AuditQuery) (domain.AuditPage, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.AuditPage{}, fmt.Errorf("failed to list audit records: %w", err)
+	}
+	after, err := query.After()
+	if err != nil {
+		return domain.AuditPage{}, fmt.Errorf("failed to list audit records: %w", err)
+	}
+
+	r.mu.RLock()
+	var records []domain.AuditRecord
+	for _, record := range r.records[query.UserId] {
+		if after == nil || compareAudit(record, *after) < 0 {
+			records = append(records, record)
+		}
+	}
+	r.mu.RUnlock()
+
+	// newest first
+	slices.SortFunc(records, func(a, b domain.AuditRecord) int {
+		return compareAudit(b, a)
+	})
+
+	page := domain.AuditPage{Records: records}
+	if len(records) > query.Limit {
+		page.Records = records[:query.Limit]
+		page.NextCursor = domain.NextAuditCursor(page.Records[query.Limit-1])
+	}
+	return page, nil
+}
+
+func compareAudit(a, b domain.AuditRecord) int {
+	if c := a.CreatedAt.Compare(b.CreatedAt); c != 0 {
+		return c
+	}
+	return bytes.Compare(a.Id[:], b.Id[:])
+}
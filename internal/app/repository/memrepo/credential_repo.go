@@ -0,0 +1,49 @@
+package memrepo
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+type CredentialRepo struct {
+	mu          sync.RWMutex
+	credentials map[uuid.UUID]domain.Credential
+}
+
+func NewCredentialRepo() *CredentialRepo {
+	return &CredentialRepo{
+		credentials: make(map[uuid.UUID]domain.Credential),
+	}
+}
+
+func (r *CredentialRepo) GetCredential(ctx context.Context, userID uuid.UUID) (domain.Credential, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Credential{}, fmt.Errorf("failed to get credential: %w", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	credential, ok := r.credentials[userID]
+	if !ok {
+		return domain.Credential{}, fmt.Errorf("failed to get credential: %w", domain.ErrNotFound)
+	}
+	return credential, nil
+}
+
+// SaveCredential sets the password of the user, replacing the previous one
+func (r *CredentialRepo) SaveCredential(ctx context.Context, credential domain.Credential) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to save credential: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.credentials[credential.UserId] = credential
+	return nil
+}
@@ -0,0 +1,91 @@
+package memrepo
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+type IdempotencyRepo struct {
+	mu      sync.Mutex
+	records map[string]domain.IdempotencyRecord
+}
+
+func NewIdempotencyRepo() *IdempotencyRepo {
+	return &IdempotencyRepo{
+		records: make(map[string]domain.IdempotencyRecord),
+	}
+}
+
+// ReserveIdempotencyKey stores the record unless an unexpired one holds the key,
+// in that case the stored record is returned and reserved is false
+func (r *IdempotencyRepo) ReserveIdempotencyKey(ctx context.Context, record domain.IdempotencyRecord) (domain.IdempotencyRecord, bool, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.IdempotencyRecord{}, false, fmt.Errorf("failed to reserve idempotency key: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if stored, ok := r.records[record.Key]; ok && stored.ExpiresAt.After(record.CreatedAt) {
+		return stored, false, nil
+	}
+	r.records[record.Key] = record
+	return record, true, nil
+}
+
+// CompleteIdempotencyKey saves the response of the request that reserved the key
+func (r *IdempotencyRepo) CompleteIdempotencyKey(ctx context.Context, record domain.IdempotencyRecord) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.records[record.Key]
+	if !ok || stored.Fingerprint != record.Fingerprint {
+		return fmt.Errorf("failed to complete idempotency key: %w", domain.ErrNotFound)
+	}
+	stored.Status = record.Status
+	stored.Headers = record.Headers
+	stored.Body = record.Body
+	r.records[record.Key] = stored
+	return nil
+}
+
+// ReleaseIdempotencyKey drops a reservation that did not complete, so the key can be retried
+func (r *IdempotencyRepo) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if stored, ok := r.records[key]; ok && !stored.Completed() {
+		delete(r.records, key)
+	}
+	return nil
+}
+
+func (r *IdempotencyRepo) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var deleted int64
+	for key, stored := range r.records {
+		if !stored.ExpiresAt.After(now) {
+			delete(r.records, key)
+			deleted++
+		}
+	}
+	return deleted, nil
+}
@@ -0,0 +1,119 @@
+package memrepo
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+type OutboxRepo struct {
+	mu     sync.Mutex
+	events []domain.OutboxEvent
+	nextID int64
+}
+
+func NewOutboxRepo() *OutboxRepo {
+	return &OutboxRepo{
+		nextID: 1,
+	}
+}
+
+func (r *OutboxRepo) AddOutboxEvent(ctx context.Context, event domain.OutboxEvent) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to add outbox event: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	event.Id = r.nextID
+	r.nextID++
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *OutboxRepo) AddOutboxEvents(ctx context.Context, events []domain.OutboxEvent) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to add outbox events: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, event := range events {
+		event.Id = r.nextID
+		r.nextID++
+		r.events = append(r.events, event)
+	}
+	return nil
+}
+
+// TryLockRelay always succeeds, the relay itself never runs batches concurrently
+func (r *OutboxRepo) TryLockRelay(ctx context.Context) (bool, error) {
+	return true, nil
+}
+
+func (r *OutboxRepo) FetchUnpublished(ctx context.Context, limit int) ([]domain.OutboxEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to fetch outbox events: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var events []domain.OutboxEvent
+	for _, event := range r.events {
+		if event.PublishedAt == nil {
+			events = append(events, event)
+			if len(events) == limit {
+				break
+			}
+		}
+	}
+	return events, nil
+}
+
+func (r *OutboxRepo) MarkPublished(ctx context.Context, ids []int64) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to mark outbox events published: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	published := make(map[int64]bool, len(ids))
+	for _, id := range ids {
+		published[id] = true
+	}
+	for i := range r.events {
+		if published[r.events[i].Id] {
+			r.events[i].PublishedAt = &now
+		}
+	}
+	return nil
+}
+
+// ListOutboxEvents returns the events after afterID in id order, published or not
+func (r *OutboxRepo) ListOutboxEvents(ctx context.Context, afterID int64, limit int) ([]domain.OutboxEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list outbox events: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var events []domain.OutboxEvent
+	for _, event := range r.events {
+		if event.Id > afterID {
+			events = append(events, event)
+			if len(events) == limit {
+				break
+			}
+		}
+	}
+	return events, nil
+}
@@ -0,0 +1,188 @@
+package memrepo
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+type SessionRepo struct {
+	mu       sync.RWMutex
+	sessions map[uuid.UUID]domain.Session
+	tokens   map[string]domain.RefreshToken
+}
+
+func NewSessionRepo() *SessionRepo {
+	return &SessionRepo{
+		sessions: make(map[uuid.UUID]domain.Session),
+		tokens:   make(map[string]domain.RefreshToken),
+	}
+}
+
+func (r *SessionRepo) CreateSession(ctx context.Context, session domain.Session) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.sessions[session.Id]; ok {
+		return fmt.Errorf("failed to create session: %w", domain.ErrConflict)
+	}
+	r.sessions[session.Id] = session
+	return nil
+}
+
+func (r *SessionRepo) GetSession(ctx context.Context, id uuid.UUID) (domain.Session, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.Session{}, fmt.Errorf("failed to get session: %w", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	session, ok := r.sessions[id]
+	if !ok {
+		return domain.Session{}, fmt.Errorf("failed to get session: %w", domain.ErrNotFound)
+	}
+	return session, nil
+}
+
+// ListSessions returns the active sessions of the user, most recently used first
+func (r *SessionRepo) ListSessions(ctx context.Context, userID uuid.UUID, now time.Time) ([]domain.Session, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var sessions []domain.Session
+	for _, session := range r.sessions {
+		if session.UserId == userID && session.Active(now) {
+			sessions = append(sessions, session)
+		}
+	}
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].LastUsedAt.Equal(sessions[j].LastUsedAt) {
+			return sessions[i].LastUsedAt.After(sessions[j].LastUsedAt)
+		}
+		return sessions[i].Id.String() < sessions[j].Id.String()
+	})
+	return sessions, nil
+}
+
+// ExtendSession records a refresh of the session
+func (r *SessionRepo) ExtendSession(ctx context.Context, id uuid.UUID, lastUsedAt, expiresAt time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to extend session: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[id]
+	if !ok {
+		return nil
+	}
+	session.LastUsedAt = lastUsedAt
+	session.ExpiresAt = expiresAt
+	r.sessions[id] = session
+	return nil
+}
+
+// RevokeSession ends an active session of the user, other users' sessions are not found
+func (r *SessionRepo) RevokeSession(ctx context.Context, userID, id uuid.UUID, now time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	session, ok := r.sessions[id]
+	if !ok || session.UserId != userID || session.RevokedAt != nil {
+		return fmt.Errorf("failed to revoke session: %w", domain.ErrNotFound)
+	}
+	session.RevokedAt = &now
+	r.sessions[id] = session
+	return nil
+}
+
+// RevokeUserSessions ends every session of the user and returns how many were active
+func (r *SessionRepo) RevokeUserSessions(ctx context.Context, userID uuid.UUID, now time.Time) (int64, error) {
+	if err := ctx.Err(); err != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var revoked int64
+	for id, session := range r.sessions {
+		if session.UserId == userID && session.RevokedAt == nil {
+			session.RevokedAt = &now
+			r.sessions[id] = session
+			revoked++
+		}
+	}
+	return revoked, nil
+}
+
+func (r *SessionRepo) AddRefreshToken(ctx context.Context, token domain.RefreshToken) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to add refresh token: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.tokens[token.TokenHash]; ok {
+		return fmt.Errorf("failed to add refresh token: %w", domain.ErrConflict)
+	}
+	r.tokens[token.TokenHash] = token
+	return nil
+}
+
+func (r *SessionRepo) GetRefreshToken(ctx context.Context, tokenHash string) (domain.RefreshToken, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.RefreshToken{}, fmt.Errorf("failed to get refresh token: %w", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	token, ok := r.tokens[tokenHash]
+	if !ok {
+		return domain.RefreshToken{}, fmt.Errorf("failed to get refresh token: %w", domain.ErrNotFound)
+	}
+	return token, nil
+}
+
+// UseRefreshToken marks the token used, false means it already was
+func (r *SessionRepo) UseRefreshToken(ctx context.Context, id uuid.UUID, now time.Time) (bool, error) {
+	if err := ctx.Err(); err != nil {
+		return false, fmt.Errorf("failed to use refresh token: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for hash, token := range r.tokens {
+		if token.Id == id {
+			if token.UsedAt != nil {
+				return false, nil
+			}
+			token.UsedAt = &now
+			r.tokens[hash] = token
+			return true, nil
+		}
+	}
+	return false, fmt.Errorf("failed to use refresh token: %w", domain.ErrNotFound)
+}
@@ -0,0 +1,116 @@
+package memrepo
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"sync"
+)
+
+// Store is a repository of this package whose changes TxManager can roll back
+type Store interface {
+	// snapshot copies the stored data, restore puts the copy back
+	snapshot() (restore func())
+}
+
+type txKey struct{}
+
+// TxManager satisfies services.TxManager for the in-memory storage. A
+// transaction snapshots the stores it was given and restores them when fn
+// fails, a nested call does the same for its own changes like a savepoint.
+// Transactions run one at a time. A write made outside of one while another
+// rolls back is lost, so every write that has to survive goes through WithinTx.
+type TxManager struct {
+	mu     sync.Mutex
+	stores []Store
+}
+
+func NewTxManager(stores ...Store) *TxManager {
+	return &TxManager{stores: stores}
+}
+
+func (m *TxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	if ctx.Value(txKey{}) == nil {
+		m.mu.Lock()
+		defer m.mu.Unlock()
+		ctx = context.WithValue(ctx, txKey{}, m)
+	}
+
+	restores := make([]func(), len(m.stores))
+	for i, store := range m.stores {
+		restores[i] = store.snapshot()
+	}
+	err := fn(ctx)
+	if err != nil {
+		for _, restore := range restores {
+			restore()
+		}
+	}
+	return err
+}
+
+func (r *UserRepo) snapshot() func() {
+	r.mu.RLock()
+	users, emails := maps.Clone(r.users), maps.Clone(r.emails)
+	r.mu.RUnlock()
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.users, r.emails = users, emails
+	}
+}
+
+func (r *AuditRepo) snapshot() func() {
+	r.mu.RLock()
+	records := maps.Clone(r.records)
+	r.mu.RUnlock()
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.records = records
+	}
+}
+
+func (r *OutboxRepo) snapshot() func() {
+	r.mu.Lock()
+	events, nextID := slices.Clone(r.events), r.nextID
+	r.mu.Unlock()
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.events, r.nextID = events, nextID
+	}
+}
+
+func (r *WebhookRepo) snapshot() func() {
+	r.mu.RLock()
+	webhooks, deliveries, events, nextID := maps.Clone(r.webhooks), slices.Clone(r.deliveries), maps.Clone(r.events), r.nextID
+	r.mu.RUnlock()
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.webhooks, r.deliveries, r.events, r.nextID = webhooks, deliveries, events, nextID
+	}
+}
+
+func (r *CredentialRepo) snapshot() func() {
+	r.mu.RLock()
+	credentials := maps.Clone(r.credentials)
+	r.mu.RUnlock()
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.credentials = credentials
+	}
+}
+
+func (r *SessionRepo) snapshot() func() {
+	r.mu.RLock()
+	sessions, tokens := maps.Clone(r.sessions), maps.Clone(r.tokens)
+	r.mu.RUnlock()
+	return func() {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		r.sessions, r.tokens = sessions, tokens
+	}
+}
@@ -0,0 +1,328 @@
+package memrepo
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"gorm.io/gorm"
+)
+
+// ErrDuplicateID is returned when CreateUser gets an id that is already stored
+var ErrDuplicateID = fmt.Errorf("user id already exists: %w", domain.ErrConflict)
+
+// UserRepo is an in-process, concurrency-safe user storage.
+// It keeps the same case-insensitive email uniqueness rule as pgrepo.UserRepo.
+type UserRepo struct {
+	mu     sync.RWMutex
+	users  map[uuid.UUID]domain.User
+	emails map[string]uuid.UUID // domain.EmailKey of active users
+}
+
+func NewUserRepo() *UserRepo {
+	return &UserRepo{
+		users:  make(map[uuid.UUID]domain.User),
+		emails: make(map[string]uuid.UUID),
+	}
+}
+
+func (r *UserRepo) CreateUser(ctx context.Context, user domain.User) (domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.User{}, fmt.Errorf("failed to create domain user: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, err := r.create(user)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("failed to create domain user: %w", err)
+	}
+	return user, nil
+}
+
+// CreateUsers stores the users in one go, users whose id or email is already
+// taken are skipped like the database repositories do
+func (r *UserRepo) CreateUsers(ctx context.Context, users []domain.User) ([]domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to create users: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	created := make([]domain.User, 0, len(users))
+	for _, user := range users {
+		if user, err := r.create(user); err == nil {
+			created = append(created, user)
+		}
+	}
+	return created, nil
+}
+
+// create expects r.mu to be held
+func (r *UserRepo) create(user domain.User) (domain.User, error) {
+	if user.Id == uuid.Nil {
+		user.Id = uuid.New()
+	}
+	if _, ok := r.users[user.Id]; ok {
+		return domain.User{}, ErrDuplicateID
+	}
+	if _, ok := r.emails[domain.EmailKey(user.Email)]; ok {
+		return domain.User{}, domain.ErrEmailTaken
+	}
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now()
+	}
+	user.DeletedAt = gorm.DeletedAt{}
+	user.Version = 1
+	if user.Role == "" {
+		user.Role = domain.RoleUser
+	}
+
+	r.users[user.Id] = user
+	r.emails[domain.EmailKey(user.Email)] = user.Id
+	return user, nil
+}
+
+func (r *UserRepo) GetUser(ctx context.Context, id uuid.UUID) (domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.User{}, fmt.Errorf("failed to get a user: %w", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt.Valid {
+		return domain.User{}, fmt.Errorf("failed to get a user: %w", domain.ErrNotFound)
+	}
+	return user, nil
+}
+
+// GetUsers returns the active users among ids, unknown ids are left out
+func (r *UserRepo) GetUsers(ctx context.Context, ids []uuid.UUID) ([]domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to get users: %w", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := make([]domain.User, 0, len(ids))
+	for _, id := range ids {
+		if user, ok := r.users[id]; ok && !user.DeletedAt.Valid {
+			users = append(users, user)
+		}
+	}
+	return users, nil
+}
+
+// GetUserByEmail finds the active user with the email, ignoring its case
+func (r *UserRepo) GetUserByEmail(ctx context.Context, email string) (domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.User{}, fmt.Errorf("failed to get a user by email: %w", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	id, ok := r.emails[domain.EmailKey(email)]
+	if !ok {
+		return domain.User{}, fmt.Errorf("failed to get a user by email: %w", domain.ErrNotFound)
+	}
+	return r.users[id], nil
+}
+
+// UpdateUser writes the given fields if user.Version is still the stored one
+func (r *UserRepo) UpdateUser(ctx context.Context, user domain.User, fields []string) (domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.User{}, fmt.Errorf("failed to update a user: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old, ok := r.users[user.Id]
+	if !ok || old.DeletedAt.Valid {
+		return domain.User{}, fmt.Errorf("failed to update a user: %w", domain.ErrNotFound)
+	}
+	if old.Version != user.Version {
+		return domain.User{}, fmt.Errorf("failed to update a user: %w", domain.ErrVersionConflict)
+	}
+
+	updated := old
+	for _, field := range fields {
+		switch field {
+		case "first_name":
+			updated.FirstName = user.FirstName
+		case "last_name":
+			updated.LastName = user.LastName
+		case "email":
+			updated.Email = user.Email
+		case "age":
+			updated.Age = user.Age
+		case "role":
+			updated.Role = user.Role
+		default:
+			return domain.User{}, fmt.Errorf("failed to update a user: user field %q cannot be updated", field)
+		}
+	}
+	if owner, ok := r.emails[domain.EmailKey(updated.Email)]; ok && owner != user.Id {
+		return domain.User{}, fmt.Errorf("failed to update a user: %w", domain.ErrEmailTaken)
+	}
+	updated.Version++
+
+	delete(r.emails, domain.EmailKey(old.Email))
+	r.users[user.Id] = updated
+	r.emails[domain.EmailKey(updated.Email)] = user.Id
+	return updated, nil
+}
+
+// UpdateUsers writes the client editable fields of every user that still holds
+// user.Version. Users changed or deleted in the meantime are left out of the
+// result, a taken email fails the whole batch and nothing is written.
+func (r *UserRepo) UpdateUsers(ctx context.Context, users []domain.User) ([]domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to update users: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// check every email first, the batch is written all or nothing
+	batch := make([]domain.User, 0, len(users))
+	released := make(map[string]bool, len(users))
+	for _, user := range users {
+		old, ok := r.users[user.Id]
+		if !ok || old.DeletedAt.Valid || old.Version != user.Version {
+			continue
+		}
+		released[domain.EmailKey(old.Email)] = true
+		updated := old
+		updated.FirstName, updated.LastName, updated.Email, updated.Age = user.FirstName, user.LastName, user.Email, user.Age
+		updated.Version++
+		batch = append(batch, updated)
+	}
+	claimed := make(map[string]uuid.UUID, len(batch))
+	for _, user := range batch {
+		key := domain.EmailKey(user.Email)
+		owner, ok := claimed[key]
+		if !ok && !released[key] {
+			owner, ok = r.emails[key]
+		}
+		if ok && owner != user.Id {
+			return nil, fmt.Errorf("failed to update users: %w", domain.ErrEmailTaken)
+		}
+		claimed[key] = user.Id
+	}
+
+	for _, user := range batch {
+		delete(r.emails, domain.EmailKey(r.users[user.Id].Email))
+	}
+	for _, user := range batch {
+		r.users[user.Id] = user
+		r.emails[domain.EmailKey(user.Email)] = user.Id
+	}
+	return batch, nil
+}
+
+// DeleteUser marks the user as deleted and releases its email.
+func (r *UserRepo) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to delete a user: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || user.DeletedAt.Valid {
+		return fmt.Errorf("failed to delete a user: %w", domain.ErrNotFound)
+	}
+
+	user.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+	r.users[id] = user
+	delete(r.emails, domain.EmailKey(user.Email))
+	return nil
+}
+
+func (r *UserRepo) RestoreUser(ctx context.Context, id uuid.UUID) (domain.User, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.User{}, fmt.Errorf("failed to restore a user: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok || !user.DeletedAt.Valid {
+		return domain.User{}, fmt.Errorf("failed to restore a user: %w", domain.ErrNotFound)
+	}
+	if _, taken := r.emails[domain.EmailKey(user.Email)]; taken {
+		return domain.User{}, fmt.Errorf("failed to restore a user: %w", domain.ErrEmailTaken)
+	}
+
+	user.DeletedAt = gorm.DeletedAt{}
+	r.users[id] = user
+	r.emails[domain.EmailKey(user.Email)] = id
+	return user, nil
+}
+
+func (r *UserRepo) PurgeUser(ctx context.Context, id uuid.UUID) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to purge a user: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return fmt.Errorf("failed to purge a user: %w", domain.ErrNotFound)
+	}
+
+	delete(r.users, id)
+	if !user.DeletedAt.Valid {
+		delete(r.emails, domain.EmailKey(user.Email))
+	}
+	return nil
+}
+
+func (r *UserRepo) ListUsers(ctx context.Context, query domain.ListUsersQuery) (domain.UserPage, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.UserPage{}, fmt.Errorf("failed to list users: %w", err)
+	}
+	after, err := query.After()
+	if err != nil {
+		return domain.UserPage{}, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	r.mu.RLock()
+	users := make([]domain.User, 0, len(r.emails))
+	for _, user := range r.users {
+		if user.DeletedAt.Valid || !query.Filter.Matches(user) {
+			continue
+		}
+		if after != nil && domain.CompareUsers(user, *after, query.Sort) <= 0 {
+			continue
+		}
+		users = append(users, user)
+	}
+	r.mu.RUnlock()
+
+	slices.SortFunc(users, func(a, b domain.User) int {
+		return domain.CompareUsers(a, b, query.Sort)
+	})
+
+	page := domain.UserPage{Users: users}
+	if len(users) > query.Limit {
+		page.Users = users[:query.Limit]
+		page.NextCursor = domain.NextUserCursor(query.Sort, page.Users[query.Limit-1])
+	}
+	return page, nil
+}
@@ -0,0 +1,340 @@
+package memrepo
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"github.com/vlad19930514/webApp/util"
+)
+
+func randomUser() domain.User {
+	return domain.User{
+		Id:        uuid.New(),
+		FirstName: util.RandomName(),
+		LastName:  util.RandomName(),
+		Email:     util.RandomEmail(),
+		Age:       uint8(util.RandomAge()),
+	}
+}
+
+func TestUserRepo_CreateAndGet(t *testing.T) {
+	repo := NewUserRepo()
+	ctx := context.Background()
+
+	created, err := repo.CreateUser(ctx, randomUser())
+	require.NoError(t, err)
+	assert.False(t, created.CreatedAt.IsZero())
+
+	got, err := repo.GetUser(ctx, created.Id)
+	require.NoError(t, err)
+	assert.Equal(t, created, got)
+
+	_, err = repo.GetUser(ctx, uuid.New())
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestUserRepo_EmailUnique(t *testing.T) {
+	repo := NewUserRepo()
+	ctx := context.Background()
+
+	first, err := repo.CreateUser(ctx, randomUser())
+	require.NoError(t, err)
+
+	dup := randomUser()
+	dup.Email = first.Email
+	_, err = repo.CreateUser(ctx, dup)
+	assert.ErrorIs(t, err, domain.ErrEmailTaken)
+
+	second, err := repo.CreateUser(ctx, randomUser())
+	require.NoError(t, err)
+	second.Email = first.Email
+	_, err = repo.UpdateUser(ctx, second, []string{"email"})
+	assert.ErrorIs(t, err, domain.ErrEmailTaken)
+
+	// the old email is released after an update
+	first.Email = util.RandomEmail()
+	_, err = repo.UpdateUser(ctx, first, []string{"email"})
+	require.NoError(t, err)
+	_, err = repo.UpdateUser(ctx, second, []string{"email"})
+	assert.NoError(t, err)
+}
+
+func TestUserRepo_UpdateUserVersion(t *testing.T) {
+	repo := NewUserRepo()
+	ctx := context.Background()
+
+	user, err := repo.CreateUser(ctx, randomUser())
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), user.Version)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		success int
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func(age uint8) {
+			defer wg.Done()
+			update := user
+			update.Age = age
+			if _, err := repo.UpdateUser(ctx, update, []string{"age"}); err == nil {
+				mu.Lock()
+				success++
+				mu.Unlock()
+			}
+		}(uint8(20 + i))
+	}
+	wg.Wait()
+	assert.Equal(t, 1, success)
+
+	got, err := repo.GetUser(ctx, user.Id)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), got.Version)
+
+	_, err = repo.UpdateUser(ctx, randomUser(), []string{"age"})
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestUserRepo_ConcurrentCreateSameEmail(t *testing.T) {
+	repo := NewUserRepo()
+	ctx := context.Background()
+	email := util.RandomEmail()
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		success int
+	)
+	for i := 0; i < 50; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			user := randomUser()
+			user.Email = email
+			if _, err := repo.CreateUser(ctx, user); err == nil {
+				mu.Lock()
+				success++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, success)
+}
+
+func TestUserRepo_SoftDelete(t *testing.T) {
+	repo := NewUserRepo()
+	ctx := context.Background()
+
+	user, err := repo.CreateUser(ctx, randomUser())
+	require.NoError(t, err)
+
+	require.NoError(t, repo.DeleteUser(ctx, user.Id))
+	_, err = repo.GetUser(ctx, user.Id)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	assert.ErrorIs(t, repo.DeleteUser(ctx, user.Id), domain.ErrNotFound)
+
+	// the email of a deleted user can be registered again
+	other := randomUser()
+	other.Email = user.Email
+	other, err = repo.CreateUser(ctx, other)
+	require.NoError(t, err)
+
+	_, err = repo.RestoreUser(ctx, user.Id)
+	assert.ErrorIs(t, err, domain.ErrEmailTaken)
+
+	require.NoError(t, repo.PurgeUser(ctx, other.Id))
+	restored, err := repo.RestoreUser(ctx, user.Id)
+	require.NoError(t, err)
+	assert.False(t, restored.DeletedAt.Valid)
+
+	require.NoError(t, repo.PurgeUser(ctx, user.Id))
+	assert.ErrorIs(t, repo.PurgeUser(ctx, user.Id), domain.ErrNotFound)
+}
+
+func TestUserRepo_ListUsersPagination(t *testing.T) {
+	repo := NewUserRepo()
+	ctx := context.Background()
+
+	start := time.Now()
+	for i := 0; i < 25; i++ {
+		user := randomUser()
+		user.Age = uint8(20 + i%5)
+		user.CreatedAt = start.Add(time.Duration(i%7) * time.Minute)
+		_, err := repo.CreateUser(ctx, user)
+		require.NoError(t, err)
+	}
+
+	sort, err := domain.ParseUserSort("age,-created_at")
+	require.NoError(t, err)
+	query, err := domain.ListUsersQuery{Sort: sort, Limit: 4}.Normalize()
+	require.NoError(t, err)
+
+	var (
+		all   []domain.User
+		seen  = make(map[uuid.UUID]bool)
+		pages int
+	)
+	for {
+		page, err := repo.ListUsers(ctx, query)
+		require.NoError(t, err)
+		pages++
+		for _, user := range page.Users {
+			assert.False(t, seen[user.Id], "user returned twice")
+			seen[user.Id] = true
+		}
+		all = append(all, page.Users...)
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+
+	assert.Len(t, all, 25)
+	assert.Equal(t, 7, pages)
+	for i := 1; i < len(all); i++ {
+		assert.Negative(t, domain.CompareUsers(all[i-1], all[i], sort))
+	}
+
+	// a cursor is bound to the sort it was issued for
+	_, err = domain.ListUsersQuery{Cursor: query.Cursor}.Normalize()
+	assert.ErrorIs(t, err, domain.ErrInvalidQuery)
+}
+
+func TestUserRepo_ListUsersFilter(t *testing.T) {
+	repo := NewUserRepo()
+	ctx := context.Background()
+
+	users := []domain.User{
+		{FirstName: "Anna", LastName: "Smith", Email: "anna@example.com", Age: 25},
+		{FirstName: "Joanna", LastName: "Brown", Email: "joanna@corp.io", Age: 40},
+		{FirstName: "Bob", LastName: "Hannah", Email: "bob@Example.com", Age: 33},
+	}
+	for _, user := range users {
+		_, err := repo.CreateUser(ctx, user)
+		require.NoError(t, err)
+	}
+
+	query, err := domain.ListUsersQuery{Filter: domain.UserFilter{Name: "ANN"}}.Normalize()
+	require.NoError(t, err)
+	page, err := repo.ListUsers(ctx, query)
+	require.NoError(t, err)
+	assert.Len(t, page.Users, 3)
+
+	query.Filter = domain.UserFilter{EmailDomain: "example.com", MinAge: 30}
+	page, err = repo.ListUsers(ctx, query)
+	require.NoError(t, err)
+	require.Len(t, page.Users, 1)
+	assert.Equal(t, "Bob", page.Users[0].FirstName)
+}
+
+func TestUserRepo_SearchUsers(t *testing.T) {
+	repo := NewUserRepo()
+	ctx := context.Background()
+
+	users := []domain.User{
+		{FirstName: "Jonathan", LastName: "Smith", Email: "jsmith@example.com", Age: 30},
+		{FirstName: "Joanna", LastName: "Smythe", Email: "joanna@corp.io", Age: 40},
+		{FirstName: "Bob", LastName: "Brown", Email: "bob@example.com", Age: 33},
+	}
+	for _, user := range users {
+		_, err := repo.CreateUser(ctx, user)
+		require.NoError(t, err)
+	}
+
+	search := func(q string) []string {
+		query, err := domain.SearchUsersQuery{Query: q}.Normalize()
+		require.NoError(t, err)
+		matches, err := repo.SearchUsers(ctx, query)
+		require.NoError(t, err)
+		names := make([]string, len(matches))
+		for i, m := range matches {
+			names[i] = m.User.FirstName
+		}
+		return names
+	}
+
+	// partial prefix
+	assert.Equal(t, []string{"Jonathan"}, search("jonat"))
+	// typo
+	assert.Equal(t, "Jonathan", search("Jonathon")[0])
+	// several fields, the best match ranks first
+	assert.Equal(t, "Joanna", search("joanna smythe")[0])
+	assert.Empty(t, search("zzzz"))
+
+	_, err := domain.SearchUsersQuery{Query: " @@ "}.Normalize()
+	assert.ErrorIs(t, err, domain.ErrInvalidQuery)
+}
+
+func TestUserRepo_EmailCaseInsensitive(t *testing.T) {
+	repo := NewUserRepo()
+	ctx := context.Background()
+
+	user := randomUser()
+	user.Email = "Bob@example.com"
+	created, err := repo.CreateUser(ctx, user)
+	require.NoError(t, err)
+
+	dup := randomUser()
+	dup.Email = "bob@EXAMPLE.com"
+	_, err = repo.CreateUser(ctx, dup)
+	assert.ErrorIs(t, err, domain.ErrEmailTaken)
+
+	got, err := repo.GetUserByEmail(ctx, "BOB@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, created.Id, got.Id)
+	assert.Equal(t, "Bob@example.com", got.Email)
+
+	require.NoError(t, repo.DeleteUser(ctx, created.Id))
+	_, err = repo.GetUserByEmail(ctx, "bob@example.com")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestUserRepo_UpdateUsers(t *testing.T) {
+	repo := NewUserRepo()
+	ctx := context.Background()
+
+	first, err := repo.CreateUser(ctx, randomUser())
+	require.NoError(t, err)
+	second, err := repo.CreateUser(ctx, randomUser())
+	require.NoError(t, err)
+
+	// the stale version is skipped, the other user is written
+	first.Age, second.Age = 50, 60
+	stale := second
+	stale.Version++
+	updated, err := repo.UpdateUsers(ctx, []domain.User{first, stale})
+	require.NoError(t, err)
+	require.Len(t, updated, 1)
+	assert.Equal(t, first.Id, updated[0].Id)
+	assert.Equal(t, int64(2), updated[0].Version)
+
+	// users may swap their emails within one batch
+	first = updated[0]
+	first.Email, second.Email = second.Email, first.Email
+	_, err = repo.UpdateUsers(ctx, []domain.User{first, second})
+	require.NoError(t, err)
+	got, err := repo.GetUserByEmail(ctx, first.Email)
+	require.NoError(t, err)
+	assert.Equal(t, first.Id, got.Id)
+
+	// a taken email fails the batch and nothing is written
+	third, err := repo.CreateUser(ctx, randomUser())
+	require.NoError(t, err)
+	third.Email = first.Email
+	first.Version++
+	first.Age = 51
+	_, err = repo.UpdateUsers(ctx, []domain.User{first, third})
+	assert.ErrorIs(t, err, domain.ErrEmailTaken)
+	got, err = repo.GetUser(ctx, first.Id)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(50), got.Age)
+}
@@ -0,0 +1,29 @@
+package memrepo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+// SearchUsers approximates the Postgres full-text and trigram search of pgrepo
+func (r *UserRepo) SearchUsers(ctx context.Context, query domain.SearchUsersQuery) ([]domain.UserMatch, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to search users: %w", err)
+	}
+
+	r.mu.RLock()
+	var matches []domain.UserMatch
+	for _, user := range r.users {
+		if user.DeletedAt.Valid {
+			continue
+		}
+		if score, ok := domain.ScoreUser(query.Query, user); ok {
+			matches = append(matches, domain.UserMatch{User: user, Score: score})
+		}
+	}
+	r.mu.RUnlock()
+
+	return domain.RankUserMatches(matches, query.Limit), nil
+}
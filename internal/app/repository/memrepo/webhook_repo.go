@@ -0,0 +1,254 @@
+package memrepo
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+type deliveryKey struct {
+	webhookID uuid.UUID
+	eventID   int64
+}
+
+type WebhookRepo struct {
+	mu         sync.RWMutex
+	webhooks   map[uuid.UUID]domain.WebhookSubscription
+	deliveries []domain.WebhookDelivery
+	events     map[deliveryKey]bool
+	nextID     int64
+}
+
+func NewWebhookRepo() *WebhookRepo {
+	return &WebhookRepo{
+		webhooks: make(map[uuid.UUID]domain.WebhookSubscription),
+		events:   make(map[deliveryKey]bool),
+		nextID:   1,
+	}
+}
+
+func (r *WebhookRepo) CreateWebhook(ctx context.Context, webhook domain.WebhookSubscription) (domain.WebhookSubscription, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.WebhookSubscription{}, fmt.Errorf("failed to create webhook: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.webhooks[webhook.Id]; ok {
+		return domain.WebhookSubscription{}, fmt.Errorf("failed to create webhook: %w", domain.ErrConflict)
+	}
+	r.webhooks[webhook.Id] = webhook
+	return webhook, nil
+}
+
+func (r *WebhookRepo) GetWebhook(ctx context.Context, id uuid.UUID) (domain.WebhookSubscription, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.WebhookSubscription{}, fmt.Errorf("failed to get webhook: %w", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	webhook, ok := r.webhooks[id]
+	if !ok {
+		return domain.WebhookSubscription{}, fmt.Errorf("failed to get webhook: %w", domain.ErrNotFound)
+	}
+	return webhook, nil
+}
+
+func (r *WebhookRepo) ListWebhooks(ctx context.Context) ([]domain.WebhookSubscription, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	webhooks := make([]domain.WebhookSubscription, 0, len(r.webhooks))
+	for _, webhook := range r.webhooks {
+		webhooks = append(webhooks, webhook)
+	}
+	sort.Slice(webhooks, func(i, j int) bool {
+		if !webhooks[i].CreatedAt.Equal(webhooks[j].CreatedAt) {
+			return webhooks[i].CreatedAt.Before(webhooks[j].CreatedAt)
+		}
+		return webhooks[i].Id.String() < webhooks[j].Id.String()
+	})
+	return webhooks, nil
+}
+
+func (r *WebhookRepo) UpdateWebhook(ctx context.Context, webhook domain.WebhookSubscription) (domain.WebhookSubscription, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.WebhookSubscription{}, fmt.Errorf("failed to update webhook: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.webhooks[webhook.Id]
+	if !ok {
+		return domain.WebhookSubscription{}, fmt.Errorf("failed to update webhook: %w", domain.ErrNotFound)
+	}
+	webhook.CreatedAt = stored.CreatedAt
+	r.webhooks[webhook.Id] = webhook
+	return webhook, nil
+}
+
+// DeleteWebhook removes the subscription, its deliveries go with it
+func (r *WebhookRepo) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to delete webhook: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.webhooks[id]; !ok {
+		return fmt.Errorf("failed to delete webhook: %w", domain.ErrNotFound)
+	}
+	delete(r.webhooks, id)
+	r.deliveries = slices.DeleteFunc(r.deliveries, func(d domain.WebhookDelivery) bool {
+		if d.SubscriptionId == id {
+			delete(r.events, deliveryKey{d.SubscriptionId, d.EventId})
+			return true
+		}
+		return false
+	})
+	return nil
+}
+
+// AddWebhookDeliveries skips deliveries of an event the subscription already has
+func (r *WebhookRepo) AddWebhookDeliveries(ctx context.Context, deliveries []domain.WebhookDelivery) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to add webhook deliveries: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, delivery := range deliveries {
+		key := deliveryKey{delivery.SubscriptionId, delivery.EventId}
+		if r.events[key] {
+			continue
+		}
+		if _, ok := r.webhooks[delivery.SubscriptionId]; !ok {
+			return fmt.Errorf("failed to add webhook deliveries: %w", domain.ErrConflict)
+		}
+		delivery.Id = r.nextID
+		r.nextID++
+		r.events[key] = true
+		r.deliveries = append(r.deliveries, delivery)
+	}
+	return nil
+}
+
+// ClaimDueDeliveries returns the pending deliveries of active subscriptions
+// that are due at now and moves their next attempt to leaseUntil
+func (r *WebhookRepo) ClaimDueDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]domain.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var due []int
+	for i, d := range r.deliveries {
+		if d.Status == domain.DeliveryPending && !d.NextAttemptAt.After(now) && r.webhooks[d.SubscriptionId].Active {
+			due = append(due, i)
+		}
+	}
+	sort.SliceStable(due, func(a, b int) bool {
+		return r.deliveries[due[a]].NextAttemptAt.Before(r.deliveries[due[b]].NextAttemptAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	claimed := make([]domain.WebhookDelivery, len(due))
+	for i, index := range due {
+		r.deliveries[index].NextAttemptAt = leaseUntil
+		claimed[i] = r.deliveries[index]
+	}
+	return claimed, nil
+}
+
+func (r *WebhookRepo) GetWebhookDelivery(ctx context.Context, webhookID uuid.UUID, id int64) (domain.WebhookDelivery, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.WebhookDelivery{}, fmt.Errorf("failed to get webhook delivery: %w", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, d := range r.deliveries {
+		if d.Id == id && d.SubscriptionId == webhookID {
+			return d, nil
+		}
+	}
+	return domain.WebhookDelivery{}, fmt.Errorf("failed to get webhook delivery: %w", domain.ErrNotFound)
+}
+
+// UpdateWebhookDelivery stores the outcome of an attempt or a redelivery
+func (r *WebhookRepo) UpdateWebhookDelivery(ctx context.Context, delivery domain.WebhookDelivery) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, d := range r.deliveries {
+		if d.Id == delivery.Id {
+			d.Status = delivery.Status
+			d.Attempts = delivery.Attempts
+			d.NextAttemptAt = delivery.NextAttemptAt
+			d.LastStatusCode = delivery.LastStatusCode
+			d.LastError = delivery.LastError
+			d.UpdatedAt = delivery.UpdatedAt
+			r.deliveries[i] = d
+			return nil
+		}
+	}
+	return fmt.Errorf("failed to update webhook delivery: %w", domain.ErrNotFound)
+}
+
+func (r *WebhookRepo) ListWebhookDeliveries(ctx context.Context, query domain.DeliveryQuery) (domain.DeliveryPage, error) {
+	if err := ctx.Err(); err != nil {
+		return domain.DeliveryPage{}, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+	before, err := query.Before()
+	if err != nil {
+		return domain.DeliveryPage{}, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	// ids grow with the slice, walking it backwards lists newest first
+	var deliveries []domain.WebhookDelivery
+	for i := len(r.deliveries) - 1; i >= 0 && len(deliveries) <= query.Limit; i-- {
+		d := r.deliveries[i]
+		if d.SubscriptionId != query.SubscriptionId || (before > 0 && d.Id >= before) {
+			continue
+		}
+		if query.Status != "" && d.Status != query.Status {
+			continue
+		}
+		deliveries = append(deliveries, d)
+	}
+
+	page := domain.DeliveryPage{Deliveries: deliveries}
+	if len(deliveries) > query.Limit {
+		page.Deliveries = deliveries[:query.Limit]
+		page.NextCursor = domain.NextDeliveryCursor(page.Deliveries[query.Limit-1])
+	}
+	return page, nil
+}
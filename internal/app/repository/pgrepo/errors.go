@@ -0,0 +1,49 @@
+package pgrepo
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"gorm.io/gorm"
+)
+
+const (
+	uniqueViolation    = "23505"
+	tooManyConnections = "53300"
+
+	userEmailIndex = "idx_users_email"
+)
+
+// translateError maps GORM and pgx errors onto the domain error taxonomy.
+// The original error stays in the chain for logs.
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var pgErr *pgconn.PgError
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return domain.ErrNotFound
+	case errors.As(err, &pgErr):
+		switch {
+		case pgErr.Code == uniqueViolation && pgErr.ConstraintName == userEmailIndex:
+			return fmt.Errorf("%w: %w", domain.ErrEmailTaken, err)
+		case pgErr.Code == uniqueViolation:
+			return fmt.Errorf("%w: %w", domain.ErrConflict, err)
+		// class 08 is connection exceptions, 57P0x is a server shutting down
+		case strings.HasPrefix(pgErr.Code, "08"), strings.HasPrefix(pgErr.Code, "57P0"), pgErr.Code == tooManyConnections:
+			return fmt.Errorf("%w: %w", domain.ErrUnavailable, err)
+		}
+	case errors.Is(err, driver.ErrBadConn), errors.Is(err, context.DeadlineExceeded),
+		pgconn.Timeout(err), errors.As(err, new(*pgconn.ConnectError)), errors.As(err, new(net.Error)):
+		return fmt.Errorf("%w: %w", domain.ErrUnavailable, err)
+	}
+	return err
+}
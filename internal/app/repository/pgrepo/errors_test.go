@@ -0,0 +1,37 @@
+package pgrepo
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"gorm.io/gorm"
+)
+
+func TestTranslateError(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{"not found", fmt.Errorf("take: %w", gorm.ErrRecordNotFound), domain.ErrNotFound},
+		{"email taken", &pgconn.PgError{Code: "23505", ConstraintName: "idx_users_email"}, domain.ErrEmailTaken},
+		{"other unique", &pgconn.PgError{Code: "23505", ConstraintName: "users_pkey"}, domain.ErrConflict},
+		{"connection failure", &pgconn.PgError{Code: "08006"}, domain.ErrUnavailable},
+		{"shutdown", &pgconn.PgError{Code: "57P01"}, domain.ErrUnavailable},
+		{"connect error", &pgconn.ConnectError{}, domain.ErrUnavailable},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.ErrorIs(t, translateError(tc.err), tc.want)
+		})
+	}
+
+	other := errors.New("syntax error")
+	assert.Equal(t, other, translateError(other))
+	assert.NoError(t, translateError(nil))
+}
@@ -0,0 +1,77 @@
+package pgrepo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"github.com/vlad19930514/webApp/internal/pkg/pg"
+)
+
+const (
+	// userEventsChannel is the LISTEN/NOTIFY channel user events are fanned out on
+	userEventsChannel = "user_events"
+	catchUpBatchSize  = 100
+)
+
+// EventNotifier fans user events out to every replica. The relay publishes
+// with NOTIFY inside a savepoint of its transaction, so the events reach the
+// listeners only once they are marked published, a failed publish is not
+// notified at all, and every replica's Listen picks them up.
+type EventNotifier struct {
+	db *pg.DB
+}
+
+func NewEventNotifier(db *pg.DB) *EventNotifier {
+	return &EventNotifier{
+		db: db,
+	}
+}
+
+// Publish sends the event as JSON, a notification payload is limited to 8000 bytes
+func (n EventNotifier) Publish(ctx context.Context, event domain.OutboxEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode user event: %w", err)
+	}
+	result := n.db.Conn(ctx).Exec("SELECT pg_notify(?, ?)", userEventsChannel, string(payload))
+	if result.Error != nil {
+		return fmt.Errorf("failed to notify user event: %w", translateError(result.Error))
+	}
+	return nil
+}
+
+// Listen passes the events notified by any replica to handle until ctx is
+// done. After a lost connection the events it missed are read from the
+// outbox, handle may get an event twice.
+func (n EventNotifier) Listen(ctx context.Context, handle func(domain.OutboxEvent)) {
+	history := OutboxRepo{db: n.db}
+	var last int64
+	catchUp := func(ctx context.Context) {
+		for last > 0 {
+			events, err := history.ListOutboxEvents(ctx, last, catchUpBatchSize)
+			if err != nil {
+				log.Warn().Err(err).Msg("failed to catch up on user events")
+				return
+			}
+			for _, event := range events {
+				last = event.Id
+				handle(event)
+			}
+			if len(events) < catchUpBatchSize {
+				return
+			}
+		}
+	}
+	n.db.Listen(ctx, userEventsChannel, catchUp, func(payload string) {
+		var event domain.OutboxEvent
+		if err := json.Unmarshal([]byte(payload), &event); err != nil {
+			log.Warn().Err(err).Msg("failed to decode user event notification")
+			return
+		}
+		last = max(last, event.Id)
+		handle(event)
+	})
+}
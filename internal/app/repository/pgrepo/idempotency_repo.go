@@ -0,0 +1,87 @@
+package pgrepo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"github.com/vlad19930514/webApp/internal/pkg/pg"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepo struct {
+	db *pg.DB
+}
+
+func NewIdempotencyRepo(db *pg.DB) *IdempotencyRepo {
+	return &IdempotencyRepo{
+		db: db,
+	}
+}
+
+// ReserveIdempotencyKey stores the record unless an unexpired one holds the key,
+// in that case the stored record is returned and reserved is false. An expired
+// record is taken over in the same statement, so two racing requests cannot
+// both reserve the key.
+func (r IdempotencyRepo) ReserveIdempotencyKey(ctx context.Context, record domain.IdempotencyRecord) (domain.IdempotencyRecord, bool, error) {
+	result := r.db.Conn(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "key"}},
+			DoUpdates: clause.AssignmentColumns([]string{"fingerprint", "status", "headers", "body", "created_at", "expires_at"}),
+			Where: clause.Where{Exprs: []clause.Expression{
+				clause.Expr{SQL: "idempotency_records.expires_at <= excluded.created_at"},
+			}},
+		}).
+		Create(&record)
+	if result.Error != nil {
+		return domain.IdempotencyRecord{}, false, fmt.Errorf("failed to reserve idempotency key: %w", translateError(result.Error))
+	}
+	if result.RowsAffected == 1 {
+		return record, true, nil
+	}
+
+	// read the primary, the record was just written there
+	stored := domain.IdempotencyRecord{Key: record.Key}
+	if err := r.db.Conn(ctx).Take(&stored).Error; err != nil {
+		return domain.IdempotencyRecord{}, false, fmt.Errorf("failed to reserve idempotency key: %w", translateError(err))
+	}
+	return stored, false, nil
+}
+
+// CompleteIdempotencyKey saves the response of the request that reserved the key
+func (r IdempotencyRepo) CompleteIdempotencyKey(ctx context.Context, record domain.IdempotencyRecord) error {
+	result := r.db.Conn(ctx).
+		Model(&domain.IdempotencyRecord{}).
+		Where("key = ? AND fingerprint = ?", record.Key, record.Fingerprint).
+		Select("status", "headers", "body").
+		Updates(&record)
+	if result.Error != nil {
+		return fmt.Errorf("failed to complete idempotency key: %w", translateError(result.Error))
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("failed to complete idempotency key: %w", domain.ErrNotFound)
+	}
+	return nil
+}
+
+// ReleaseIdempotencyKey drops a reservation that did not complete, so the key can be retried
+func (r IdempotencyRepo) ReleaseIdempotencyKey(ctx context.Context, key string) error {
+	result := r.db.Conn(ctx).
+		Where("key = ? AND status = 0", key).
+		Delete(&domain.IdempotencyRecord{})
+	if result.Error != nil {
+		return fmt.Errorf("failed to release idempotency key: %w", translateError(result.Error))
+	}
+	return nil
+}
+
+func (r IdempotencyRepo) DeleteExpiredIdempotencyKeys(ctx context.Context, now time.Time) (int64, error) {
+	result := r.db.Conn(ctx).
+		Where("expires_at <= ?", now).
+		Delete(&domain.IdempotencyRecord{})
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to delete expired idempotency keys: %w", translateError(result.Error))
+	}
+	return result.RowsAffected, nil
+}
@@ -0,0 +1,96 @@
+package pgrepo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"github.com/vlad19930514/webApp/internal/pkg/pg"
+)
+
+// outboxLockID is the advisory lock that keeps a single relay publishing at a time,
+// so events of one user never overtake each other.
+const outboxLockID int64 = 7_301_946_206
+
+type OutboxRepo struct {
+	db *pg.DB
+}
+
+func NewOutboxRepo(db *pg.DB) *OutboxRepo {
+	return &OutboxRepo{
+		db: db,
+	}
+}
+
+func (r OutboxRepo) AddOutboxEvent(ctx context.Context, event domain.OutboxEvent) error {
+	result := r.db.Conn(ctx).Create(&event)
+	if result.Error != nil {
+		return fmt.Errorf("failed to add outbox event: %w", translateError(result.Error))
+	}
+	return nil
+}
+
+// AddOutboxEvents stores the events with a single multi-row insert, ids follow the slice order
+func (r OutboxRepo) AddOutboxEvents(ctx context.Context, events []domain.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	result := r.db.Conn(ctx).Create(&events)
+	if result.Error != nil {
+		return fmt.Errorf("failed to add outbox events: %w", translateError(result.Error))
+	}
+	return nil
+}
+
+// TryLockRelay takes a transaction level advisory lock, it must run inside WithinTx
+func (r OutboxRepo) TryLockRelay(ctx context.Context) (bool, error) {
+	var locked bool
+	result := r.db.Conn(ctx).Raw("SELECT pg_try_advisory_xact_lock(?)", outboxLockID).Scan(&locked)
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to lock outbox relay: %w", translateError(result.Error))
+	}
+	return locked, nil
+}
+
+func (r OutboxRepo) FetchUnpublished(ctx context.Context, limit int) ([]domain.OutboxEvent, error) {
+	var events []domain.OutboxEvent
+	result := r.db.Conn(ctx).
+		Where("published_at IS NULL").
+		Order("id").
+		Limit(limit).
+		Find(&events)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to fetch outbox events: %w", translateError(result.Error))
+	}
+	return events, nil
+}
+
+func (r OutboxRepo) MarkPublished(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	result := r.db.Conn(ctx).
+		Model(&domain.OutboxEvent{}).
+		Where("id IN ?", ids).
+		Update("published_at", time.Now())
+	if result.Error != nil {
+		return fmt.Errorf("failed to mark outbox events published: %w", translateError(result.Error))
+	}
+	return nil
+}
+
+// ListOutboxEvents returns the events after afterID in id order, published or
+// not. It reads the primary, a lagging replica would leave gaps in a resumed feed.
+func (r OutboxRepo) ListOutboxEvents(ctx context.Context, afterID int64, limit int) ([]domain.OutboxEvent, error) {
+	var events []domain.OutboxEvent
+	result := r.db.Conn(ctx).
+		Where("id > ?", afterID).
+		Order("id").
+		Limit(limit).
+		Find(&events)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list outbox events: %w", translateError(result.Error))
+	}
+	return events, nil
+}
@@ -0,0 +1,65 @@
+package pgrepo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"github.com/vlad19930514/webApp/internal/app/repository/sqlutil"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ListUsers pages through users with keyset pagination, the query is expected to be normalized
+func (r UserRepo) ListUsers(ctx context.Context, query domain.ListUsersQuery) (domain.UserPage, error) {
+	after, err := query.After()
+	if err != nil {
+		return domain.UserPage{}, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	tx := applyUserFilter(r.db.Reader(ctx).Model(&domain.User{}), query.Filter)
+	if after != nil {
+		cond, args := sqlutil.KeysetCondition(query.Sort, *after)
+		tx = tx.Where(cond, args...)
+	}
+	for _, s := range query.Sort {
+		tx = tx.Order(clause.OrderByColumn{Column: clause.Column{Name: string(s.Field)}, Desc: s.Desc})
+	}
+	tx = tx.Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: sqlutil.IDDesc(query.Sort)})
+
+	var users []domain.User
+	if err := tx.Limit(query.Limit + 1).Find(&users).Error; err != nil {
+		return domain.UserPage{}, fmt.Errorf("failed to list users: %w", translateError(err))
+	}
+
+	page := domain.UserPage{Users: users}
+	if len(users) > query.Limit {
+		page.Users = users[:query.Limit]
+		page.NextCursor = domain.NextUserCursor(query.Sort, page.Users[query.Limit-1])
+	}
+	return page, nil
+}
+
+func applyUserFilter(tx *gorm.DB, f domain.UserFilter) *gorm.DB {
+	if f.Name != "" {
+		pattern := "%" + sqlutil.EscapeLike(f.Name) + "%"
+		tx = tx.Where("(first_name ILIKE ? OR last_name ILIKE ?)", pattern, pattern)
+	}
+	if f.EmailDomain != "" {
+		tx = tx.Where("lower(email) LIKE ?", "%@"+sqlutil.EscapeLike(strings.ToLower(f.EmailDomain)))
+	}
+	if f.MinAge != 0 {
+		tx = tx.Where("age >= ?", f.MinAge)
+	}
+	if f.MaxAge != 0 {
+		tx = tx.Where("age <= ?", f.MaxAge)
+	}
+	if !f.CreatedAfter.IsZero() {
+		tx = tx.Where("created_at >= ?", f.CreatedAfter)
+	}
+	if !f.CreatedBefore.IsZero() {
+		tx = tx.Where("created_at < ?", f.CreatedBefore)
+	}
+	return tx
+}
@@ -5,55 +5,183 @@ import (
 	"fmt"
 	"github.com/google/uuid"
 	"github.com/vlad19930514/webApp/internal/app/domain"
+	"github.com/vlad19930514/webApp/internal/app/repository/sqlutil"
 	"github.com/vlad19930514/webApp/internal/pkg/pg"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"time"
 )
 
 type UserRepo struct {
 	db *pg.DB
 }
 
-func NewUserRepo(db *pg.DB) (*UserRepo, error) {
-
-	// Добавление расширения uuid-ossp
-	err := db.Exec("CREATE EXTENSION IF NOT EXISTS \"uuid-ossp\"").Error
-	if err != nil {
-		return nil, fmt.Errorf("failed to create uuid-ossp extension: %w", err)
-	}
-
-	// Миграция схемы для структуры User
-	err = db.AutoMigrate(&domain.User{})
-	if err != nil {
-		return nil, fmt.Errorf("failed to migrate user table: %w", err)
-	}
+// NewUserRepo expects the schema to be migrated, see the migrate subcommand.
+// The server checks that on start, SearchUsers for one needs the 000002
+// migration.
+func NewUserRepo(db *pg.DB) *UserRepo {
 	return &UserRepo{
 		db: db,
-	}, nil
+	}
 }
 
 func (r UserRepo) CreateUser(ctx context.Context, user domain.User) (domain.User, error) {
-	result := r.db.WithContext(ctx).Create(&user)
+	result := r.db.Conn(ctx).Create(&user)
 	if result.Error != nil {
-		return domain.User{}, fmt.Errorf("failed to create domain user: %w", result.Error)
+		return domain.User{}, fmt.Errorf("failed to create domain user: %w", translateError(result.Error))
 	}
 	return user, nil
 }
+
+// CreateUsers inserts the users with one multi-row statement. Users whose email
+// is already taken are skipped instead of failing the statement, the returned
+// slice holds only the users that were created. The ids must be new.
+func (r UserRepo) CreateUsers(ctx context.Context, users []domain.User) ([]domain.User, error) {
+	if len(users) == 0 {
+		return nil, nil
+	}
+	now := time.Now()
+	rows := make([]domain.User, len(users))
+	ids := make([]uuid.UUID, len(users))
+	for i, user := range users {
+		if user.Id == uuid.Nil {
+			user.Id = uuid.New()
+		}
+		if user.CreatedAt.IsZero() {
+			user.CreatedAt = now
+		}
+		user.Version = 1
+		rows[i] = user
+		ids[i] = user.Id
+	}
+
+	// DO NOTHING skips the rows that hit a unique index, what got inserted is read back by id
+	conn := r.db.Conn(ctx)
+	if err := conn.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to create users: %w", translateError(err))
+	}
+	var created []domain.User
+	if err := conn.Where("id IN ?", ids).Find(&created).Error; err != nil {
+		return nil, fmt.Errorf("failed to create users: %w", translateError(err))
+	}
+	return created, nil
+}
+
 func (r UserRepo) GetUser(ctx context.Context, id uuid.UUID) (domain.User, error) {
 	dbUser := domain.User{
 		Id: id,
 	}
-	result := r.db.WithContext(ctx).Take(&dbUser)
+	result := r.db.Reader(ctx).Take(&dbUser)
 	if result.Error != nil {
-		return domain.User{}, fmt.Errorf("failed to get a user: %w", result.Error)
+		return domain.User{}, fmt.Errorf("failed to get a user: %w", translateError(result.Error))
 	}
 	return dbUser, nil
 
 }
-func (r UserRepo) UpdateUser(ctx context.Context, user domain.User) (domain.User, error) {
 
-	result := r.db.WithContext(ctx).Save(&user)
+// GetUsers returns the active users among ids, unknown ids are left out
+func (r UserRepo) GetUsers(ctx context.Context, ids []uuid.UUID) ([]domain.User, error) {
+	var users []domain.User
+	result := r.db.Reader(ctx).Where("id IN ?", ids).Find(&users)
 	if result.Error != nil {
-		return domain.User{}, fmt.Errorf("failed to get a user: %w", result.Error)
+		return nil, fmt.Errorf("failed to get users: %w", translateError(result.Error))
 	}
-	return user, nil
+	return users, nil
+}
+
+// GetUserByEmail finds the active user with the email, ignoring its case.
+// The lookup matches the lower(email) unique index.
+func (r UserRepo) GetUserByEmail(ctx context.Context, email string) (domain.User, error) {
+	var dbUser domain.User
+	result := r.db.Reader(ctx).Where("lower(email) = lower(?)", email).Take(&dbUser)
+	if result.Error != nil {
+		return domain.User{}, fmt.Errorf("failed to get a user by email: %w", translateError(result.Error))
+	}
+	return dbUser, nil
+}
+
+// UpdateUser writes the given fields only if user.Version is still the stored
+// one. The check and the version bump happen in a single UPDATE, so of two
+// racing writers holding the same version only one can succeed.
+func (r UserRepo) UpdateUser(ctx context.Context, user domain.User, fields []string) (domain.User, error) {
+	columns, err := sqlutil.UserColumns(user, fields)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("failed to update a user: %w", err)
+	}
+	columns["version"] = gorm.Expr("version + 1")
+
+	updated := domain.User{Id: user.Id}
+	result := r.db.Conn(ctx).
+		Model(&updated).
+		Clauses(clause.Returning{}).
+		Where("version = ?", user.Version).
+		Updates(columns)
+	if result.Error != nil {
+		return domain.User{}, fmt.Errorf("failed to update a user: %w", translateError(result.Error))
+	}
+	if result.RowsAffected == 0 {
+		// read the primary, a lagging replica could still have the old version
+		if err := r.db.Conn(ctx).Take(&domain.User{Id: user.Id}).Error; err != nil {
+			return domain.User{}, fmt.Errorf("failed to update a user: %w", translateError(err))
+		}
+		return domain.User{}, fmt.Errorf("failed to update a user: %w", domain.ErrVersionConflict)
+	}
+	return updated, nil
+}
+
+// UpdateUsers writes the client editable fields of every user that still holds
+// user.Version, all in one UPDATE. Users changed or deleted in the meantime are
+// left out of the result. A taken email fails the whole statement.
+func (r UserRepo) UpdateUsers(ctx context.Context, users []domain.User) ([]domain.User, error) {
+	if len(users) == 0 {
+		return nil, nil
+	}
+	values, args := sqlutil.UserValues(users, "(?::uuid, ?::bigint, ?::text, ?::text, ?::text, ?::smallint)")
+	query := `UPDATE users AS u
+SET first_name = v.first_name, last_name = v.last_name, email = v.email, age = v.age, version = u.version + 1
+FROM (VALUES ` + values + `) AS v(id, version, first_name, last_name, email, age)
+WHERE u.id = v.id AND u.version = v.version AND u.deleted_at IS NULL
+RETURNING u.*`
+
+	var updated []domain.User
+	if err := r.db.Conn(ctx).Raw(query, args...).Scan(&updated).Error; err != nil {
+		return nil, fmt.Errorf("failed to update users: %w", translateError(err))
+	}
+	return updated, nil
+}
 
+func (r UserRepo) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	result := r.db.Conn(ctx).Delete(&domain.User{Id: id})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete a user: %w", translateError(result.Error))
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("failed to delete a user: %w", domain.ErrNotFound)
+	}
+	return nil
+}
+func (r UserRepo) RestoreUser(ctx context.Context, id uuid.UUID) (domain.User, error) {
+	restored := domain.User{Id: id}
+	result := r.db.Conn(ctx).Unscoped().
+		Model(&restored).
+		Clauses(clause.Returning{}).
+		Where("deleted_at IS NOT NULL").
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return domain.User{}, fmt.Errorf("failed to restore a user: %w", translateError(result.Error))
+	}
+	if result.RowsAffected == 0 {
+		return domain.User{}, fmt.Errorf("failed to restore a user: %w", domain.ErrNotFound)
+	}
+	return restored, nil
+}
+func (r UserRepo) PurgeUser(ctx context.Context, id uuid.UUID) error {
+	result := r.db.Conn(ctx).Unscoped().Delete(&domain.User{Id: id})
+	if result.Error != nil {
+		return fmt.Errorf("failed to purge a user: %w", translateError(result.Error))
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("failed to purge a user: %w", domain.ErrNotFound)
+	}
+	return nil
 }
@@ -0,0 +1,54 @@
+package pgrepo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+// searchUsersSQL ranks users by full-text prefix match plus the best trigram
+// similarity of a single column. Indexes come from the 000002 migration.
+const searchUsersSQL = `
+SELECT users.*,
+       ts_rank(search_vector, to_tsquery('simple', @tsquery)) +
+       greatest(similarity(lower(first_name), @q), similarity(lower(last_name), @q), similarity(lower(email), @q)) AS score
+FROM users
+WHERE deleted_at IS NULL
+  AND (search_vector @@ to_tsquery('simple', @tsquery)
+       OR lower(first_name) % @q
+       OR lower(last_name) % @q
+       OR lower(email) % @q)
+ORDER BY score DESC, id
+LIMIT @limit`
+
+type userMatchRow struct {
+	domain.User
+	Score float64
+}
+
+// SearchUsers expects a normalized query
+func (r UserRepo) SearchUsers(ctx context.Context, query domain.SearchUsersQuery) ([]domain.UserMatch, error) {
+	terms := domain.SearchTerms(query.Query)
+	prefixes := make([]string, len(terms))
+	for i, term := range terms {
+		prefixes[i] = term + ":*"
+	}
+
+	var rows []userMatchRow
+	result := r.db.Reader(ctx).Raw(searchUsersSQL, map[string]any{
+		"tsquery": strings.Join(prefixes, " & "),
+		"q":       strings.Join(terms, " "),
+		"limit":   query.Limit,
+	}).Scan(&rows)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to search users: %w", translateError(result.Error))
+	}
+
+	matches := make([]domain.UserMatch, len(rows))
+	for i, row := range rows {
+		matches[i] = domain.UserMatch{User: row.User, Score: row.Score}
+	}
+	return matches, nil
+}
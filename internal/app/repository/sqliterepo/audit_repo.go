@@ -0,0 +1,69 @@
+package sqliterepo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"github.com/vlad19930514/webApp/internal/pkg/sqlite"
+)
+
+type AuditRepo struct {
+	db *sqlite.DB
+}
+
+func NewAuditRepo(db *sqlite.DB) *AuditRepo {
+	return &AuditRepo{
+		db: db,
+	}
+}
+
+func (r AuditRepo) CreateAuditRecord(ctx context.Context, record domain.AuditRecord) error {
+	record.CreatedAt = record.CreatedAt.UTC()
+	result := r.db.Conn(ctx).Create(&record)
+	if result.Error != nil {
+		return fmt.Errorf("failed to create audit record: %w", translateError(result.Error))
+	}
+	return nil
+}
+
+// CreateAuditRecords stores the records with a single multi-row insert
+func (r AuditRepo) CreateAuditRecords(ctx context.Context, records []domain.AuditRecord) error {
+	if len(records) == 0 {
+		return nil
+	}
+	for i := range records {
+		records[i].CreatedAt = records[i].CreatedAt.UTC()
+	}
+	result := r.db.Conn(ctx).Create(&records)
+	if result.Error != nil {
+		return fmt.Errorf("failed to create audit records: %w", translateError(result.Error))
+	}
+	return nil
+}
+
+// ListAuditRecords returns the history of a user newest first, the query is expected to be normalized
+func (r AuditRepo) ListAuditRecords(ctx context.Context, query domain.AuditQuery) (domain.AuditPage, error) {
+	after, err := query.After()
+	if err != nil {
+		return domain.AuditPage{}, fmt.Errorf("failed to list audit records: %w", err)
+	}
+
+	tx := r.db.Conn(ctx).Where("user_id = ?", query.UserId)
+	if after != nil {
+		tx = tx.Where("(created_at, id) < (?, ?)", after.CreatedAt.UTC(), after.Id)
+	}
+
+	var records []domain.AuditRecord
+	result := tx.Order("created_at DESC, id DESC").Limit(query.Limit + 1).Find(&records)
+	if result.Error != nil {
+		return domain.AuditPage{}, fmt.Errorf("failed to list audit records: %w", translateError(result.Error))
+	}
+
+	page := domain.AuditPage{Records: records}
+	if len(records) > query.Limit {
+		page.Records = records[:query.Limit]
+		page.NextCursor = domain.NextAuditCursor(page.Records[query.Limit-1])
+	}
+	return page, nil
+}
@@ -0,0 +1,44 @@
+package sqliterepo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"github.com/vlad19930514/webApp/internal/pkg/sqlite"
+	"gorm.io/gorm/clause"
+)
+
+type CredentialRepo struct {
+	db *sqlite.DB
+}
+
+func NewCredentialRepo(db *sqlite.DB) *CredentialRepo {
+	return &CredentialRepo{
+		db: db,
+	}
+}
+
+func (r CredentialRepo) GetCredential(ctx context.Context, userID uuid.UUID) (domain.Credential, error) {
+	var credential domain.Credential
+	if err := r.db.Conn(ctx).Where("user_id = ?", userID).Take(&credential).Error; err != nil {
+		return domain.Credential{}, fmt.Errorf("failed to get credential: %w", translateError(err))
+	}
+	return credential, nil
+}
+
+// SaveCredential sets the password of the user, replacing the previous one
+func (r CredentialRepo) SaveCredential(ctx context.Context, credential domain.Credential) error {
+	credential.UpdatedAt = credential.UpdatedAt.UTC()
+	err := r.db.Conn(ctx).
+		Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}},
+			DoUpdates: clause.AssignmentColumns([]string{"password_hash", "updated_at"}),
+		}).
+		Create(&credential).Error
+	if err != nil {
+		return fmt.Errorf("failed to save credential: %w", translateError(err))
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package sqliterepo
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	sqlite "github.com/glebarez/go-sqlite"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"gorm.io/gorm"
+)
+
+// primary result codes, extended codes carry them in the low byte
+const (
+	sqliteBusy       = 5
+	sqliteLocked     = 6
+	sqliteConstraint = 19
+
+	sqliteConstraintUnique = 2067
+)
+
+// translateError maps GORM and SQLite errors onto the domain error taxonomy,
+// the same way pgrepo does for Postgres
+func translateError(err error) error {
+	if err == nil {
+		return nil
+	}
+
+	var sqliteErr *sqlite.Error
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return domain.ErrNotFound
+	case errors.As(err, &sqliteErr):
+		switch code := sqliteErr.Code(); {
+		case code == sqliteConstraintUnique && isUserEmailConstraint(sqliteErr.Error()):
+			return fmt.Errorf("%w: %w", domain.ErrEmailTaken, err)
+		case code&0xff == sqliteConstraint:
+			return fmt.Errorf("%w: %w", domain.ErrConflict, err)
+		case code&0xff == sqliteBusy, code&0xff == sqliteLocked:
+			return fmt.Errorf("%w: %w", domain.ErrUnavailable, err)
+		}
+	}
+	return err
+}
+
+// isUserEmailConstraint recognises both the column and the expression index form
+// of the message, "users.email" and "index 'idx_users_email'"
+func isUserEmailConstraint(msg string) bool {
+	return strings.Contains(msg, "users.email") || strings.Contains(msg, "'idx_users_email'")
+}
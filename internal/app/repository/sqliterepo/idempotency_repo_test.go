@@ -0,0 +1,57 @@
+package sqliterepo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+func TestIdempotencyRepo_Lifecycle(t *testing.T) {
+	repo := NewIdempotencyRepo(newTestDB(t))
+	ctx := context.Background()
+	now := time.Now()
+
+	record := domain.IdempotencyRecord{Key: "key-1", Fingerprint: "a", CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+	_, reserved, err := repo.ReserveIdempotencyKey(ctx, record)
+	require.NoError(t, err)
+	assert.True(t, reserved)
+
+	stored, reserved, err := repo.ReserveIdempotencyKey(ctx, record)
+	require.NoError(t, err)
+	assert.False(t, reserved)
+	assert.False(t, stored.Completed())
+
+	record.Status = 201
+	record.Headers = map[string]string{"Content-Type": "application/json"}
+	record.Body = []byte(`{"id":"1"}`)
+	require.NoError(t, repo.CompleteIdempotencyKey(ctx, record))
+
+	stored, reserved, err = repo.ReserveIdempotencyKey(ctx, domain.IdempotencyRecord{Key: "key-1", Fingerprint: "b", CreatedAt: now, ExpiresAt: now.Add(time.Hour)})
+	require.NoError(t, err)
+	assert.False(t, reserved)
+	assert.Equal(t, "a", stored.Fingerprint)
+	assert.Equal(t, 201, stored.Status)
+	assert.Equal(t, record.Headers, stored.Headers)
+	assert.Equal(t, record.Body, stored.Body)
+
+	// a completed key is not released, an expired one is taken over
+	require.NoError(t, repo.ReleaseIdempotencyKey(ctx, "key-1"))
+	later := now.Add(2 * time.Hour)
+	stored, reserved, err = repo.ReserveIdempotencyKey(ctx, domain.IdempotencyRecord{Key: "key-1", Fingerprint: "c", CreatedAt: later, ExpiresAt: later.Add(time.Hour)})
+	require.NoError(t, err)
+	assert.True(t, reserved)
+	assert.Equal(t, "c", stored.Fingerprint)
+
+	require.NoError(t, repo.ReleaseIdempotencyKey(ctx, "key-1"))
+	_, reserved, err = repo.ReserveIdempotencyKey(ctx, domain.IdempotencyRecord{Key: "key-1", Fingerprint: "d", CreatedAt: later, ExpiresAt: later.Add(time.Hour)})
+	require.NoError(t, err)
+	assert.True(t, reserved)
+
+	deleted, err := repo.DeleteExpiredIdempotencyKeys(ctx, later.Add(2*time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), deleted)
+}
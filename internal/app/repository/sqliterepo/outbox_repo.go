@@ -0,0 +1,97 @@
+package sqliterepo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"github.com/vlad19930514/webApp/internal/pkg/sqlite"
+)
+
+type OutboxRepo struct {
+	db *sqlite.DB
+}
+
+func NewOutboxRepo(db *sqlite.DB) *OutboxRepo {
+	return &OutboxRepo{
+		db: db,
+	}
+}
+
+func (r OutboxRepo) AddOutboxEvent(ctx context.Context, event domain.OutboxEvent) error {
+	if event.CreatedAt.IsZero() {
+		event.CreatedAt = time.Now()
+	}
+	event.CreatedAt = event.CreatedAt.UTC()
+	result := r.db.Conn(ctx).Create(&event)
+	if result.Error != nil {
+		return fmt.Errorf("failed to add outbox event: %w", translateError(result.Error))
+	}
+	return nil
+}
+
+// AddOutboxEvents stores the events with a single multi-row insert, ids follow the slice order
+func (r OutboxRepo) AddOutboxEvents(ctx context.Context, events []domain.OutboxEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	now := time.Now()
+	for i := range events {
+		if events[i].CreatedAt.IsZero() {
+			events[i].CreatedAt = now
+		}
+		events[i].CreatedAt = events[i].CreatedAt.UTC()
+	}
+	result := r.db.Conn(ctx).Create(&events)
+	if result.Error != nil {
+		return fmt.Errorf("failed to add outbox events: %w", translateError(result.Error))
+	}
+	return nil
+}
+
+// TryLockRelay always succeeds, an SQLite file is owned by a single process
+func (r OutboxRepo) TryLockRelay(ctx context.Context) (bool, error) {
+	return true, nil
+}
+
+func (r OutboxRepo) FetchUnpublished(ctx context.Context, limit int) ([]domain.OutboxEvent, error) {
+	var events []domain.OutboxEvent
+	result := r.db.Conn(ctx).
+		Where("published_at IS NULL").
+		Order("id").
+		Limit(limit).
+		Find(&events)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to fetch outbox events: %w", translateError(result.Error))
+	}
+	return events, nil
+}
+
+func (r OutboxRepo) MarkPublished(ctx context.Context, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	result := r.db.Conn(ctx).
+		Model(&domain.OutboxEvent{}).
+		Where("id IN ?", ids).
+		Update("published_at", time.Now().UTC())
+	if result.Error != nil {
+		return fmt.Errorf("failed to mark outbox events published: %w", translateError(result.Error))
+	}
+	return nil
+}
+
+// ListOutboxEvents returns the events after afterID in id order, published or not
+func (r OutboxRepo) ListOutboxEvents(ctx context.Context, afterID int64, limit int) ([]domain.OutboxEvent, error) {
+	var events []domain.OutboxEvent
+	result := r.db.Conn(ctx).
+		Where("id > ?", afterID).
+		Order("id").
+		Limit(limit).
+		Find(&events)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list outbox events: %w", translateError(result.Error))
+	}
+	return events, nil
+}
@@ -0,0 +1,120 @@
+package sqliterepo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"github.com/vlad19930514/webApp/internal/pkg/sqlite"
+)
+
+type SessionRepo struct {
+	db *sqlite.DB
+}
+
+func NewSessionRepo(db *sqlite.DB) *SessionRepo {
+	return &SessionRepo{
+		db: db,
+	}
+}
+
+func (r SessionRepo) CreateSession(ctx context.Context, session domain.Session) error {
+	session.CreatedAt = session.CreatedAt.UTC()
+	session.LastUsedAt = session.LastUsedAt.UTC()
+	session.ExpiresAt = session.ExpiresAt.UTC()
+	if err := r.db.Conn(ctx).Create(&session).Error; err != nil {
+		return fmt.Errorf("failed to create session: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r SessionRepo) GetSession(ctx context.Context, id uuid.UUID) (domain.Session, error) {
+	var session domain.Session
+	if err := r.db.Conn(ctx).Where("id = ?", id).Take(&session).Error; err != nil {
+		return domain.Session{}, fmt.Errorf("failed to get session: %w", translateError(err))
+	}
+	return session, nil
+}
+
+// ListSessions returns the active sessions of the user, most recently used first
+func (r SessionRepo) ListSessions(ctx context.Context, userID uuid.UUID, now time.Time) ([]domain.Session, error) {
+	var sessions []domain.Session
+	err := r.db.Conn(ctx).
+		Where("user_id = ? AND revoked_at IS NULL AND expires_at > ?", userID, now.UTC()).
+		Order("last_used_at DESC, id").
+		Find(&sessions).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to list sessions: %w", translateError(err))
+	}
+	return sessions, nil
+}
+
+// ExtendSession records a refresh of the session
+func (r SessionRepo) ExtendSession(ctx context.Context, id uuid.UUID, lastUsedAt, expiresAt time.Time) error {
+	err := r.db.Conn(ctx).
+		Model(&domain.Session{}).
+		Where("id = ?", id).
+		Updates(map[string]any{"last_used_at": lastUsedAt.UTC(), "expires_at": expiresAt.UTC()}).Error
+	if err != nil {
+		return fmt.Errorf("failed to extend session: %w", translateError(err))
+	}
+	return nil
+}
+
+// RevokeSession ends an active session of the user, other users' sessions are not found
+func (r SessionRepo) RevokeSession(ctx context.Context, userID, id uuid.UUID, now time.Time) error {
+	result := r.db.Conn(ctx).
+		Model(&domain.Session{}).
+		Where("id = ? AND user_id = ? AND revoked_at IS NULL", id, userID).
+		Update("revoked_at", now.UTC())
+	if result.Error != nil {
+		return fmt.Errorf("failed to revoke session: %w", translateError(result.Error))
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("failed to revoke session: %w", domain.ErrNotFound)
+	}
+	return nil
+}
+
+// RevokeUserSessions ends every session of the user and returns how many were active
+func (r SessionRepo) RevokeUserSessions(ctx context.Context, userID uuid.UUID, now time.Time) (int64, error) {
+	result := r.db.Conn(ctx).
+		Model(&domain.Session{}).
+		Where("user_id = ? AND revoked_at IS NULL", userID).
+		Update("revoked_at", now.UTC())
+	if result.Error != nil {
+		return 0, fmt.Errorf("failed to revoke sessions: %w", translateError(result.Error))
+	}
+	return result.RowsAffected, nil
+}
+
+func (r SessionRepo) AddRefreshToken(ctx context.Context, token domain.RefreshToken) error {
+	token.CreatedAt = token.CreatedAt.UTC()
+	if err := r.db.Conn(ctx).Create(&token).Error; err != nil {
+		return fmt.Errorf("failed to add refresh token: %w", translateError(err))
+	}
+	return nil
+}
+
+func (r SessionRepo) GetRefreshToken(ctx context.Context, tokenHash string) (domain.RefreshToken, error) {
+	var token domain.RefreshToken
+	if err := r.db.Conn(ctx).Where("token_hash = ?", tokenHash).Take(&token).Error; err != nil {
+		return domain.RefreshToken{}, fmt.Errorf("failed to get refresh token: %w", translateError(err))
+	}
+	return token, nil
+}
+
+// UseRefreshToken marks the token used, false means it already was. The check
+// and the update are one statement, so of two racing refreshes only one wins.
+func (r SessionRepo) UseRefreshToken(ctx context.Context, id uuid.UUID, now time.Time) (bool, error) {
+	result := r.db.Conn(ctx).
+		Model(&domain.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL", id).
+		Update("used_at", now.UTC())
+	if result.Error != nil {
+		return false, fmt.Errorf("failed to use refresh token: %w", translateError(result.Error))
+	}
+	return result.RowsAffected == 1, nil
+}
@@ -0,0 +1,69 @@
+package sqliterepo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+func TestSessionRepo(t *testing.T) {
+	db := newTestDB(t)
+	users, repo := NewUserRepo(db), NewSessionRepo(db)
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+
+	user, err := users.CreateUser(ctx, randomUser())
+	require.NoError(t, err)
+	session := func(lastUsed, expires time.Time) domain.Session {
+		s := domain.Session{Id: uuid.New(), UserId: user.Id, UserAgent: "curl", CreatedAt: now, LastUsedAt: lastUsed, ExpiresAt: expires}
+		require.NoError(t, repo.CreateSession(ctx, s))
+		return s
+	}
+	older := session(now.Add(-time.Hour), now.Add(time.Hour))
+	newer := session(now, now.Add(time.Hour))
+	session(now, now.Add(-time.Minute)) // expired
+
+	sessions, err := repo.ListSessions(ctx, user.Id, now)
+	require.NoError(t, err)
+	require.Len(t, sessions, 2)
+	assert.Equal(t, newer.Id, sessions[0].Id)
+	assert.Equal(t, "curl", sessions[0].UserAgent)
+
+	token := domain.RefreshToken{Id: uuid.New(), SessionId: older.Id, TokenHash: "hash", CreatedAt: now}
+	require.NoError(t, repo.AddRefreshToken(ctx, token))
+	got, err := repo.GetRefreshToken(ctx, "hash")
+	require.NoError(t, err)
+	assert.Nil(t, got.UsedAt)
+
+	// a token is used once
+	used, err := repo.UseRefreshToken(ctx, token.Id, now)
+	require.NoError(t, err)
+	assert.True(t, used)
+	used, err = repo.UseRefreshToken(ctx, token.Id, now)
+	require.NoError(t, err)
+	assert.False(t, used)
+
+	require.NoError(t, repo.ExtendSession(ctx, older.Id, now.Add(time.Minute), now.Add(2*time.Hour)))
+	sessions, err = repo.ListSessions(ctx, user.Id, now)
+	require.NoError(t, err)
+	assert.Equal(t, older.Id, sessions[0].Id)
+
+	assert.ErrorIs(t, repo.RevokeSession(ctx, uuid.New(), older.Id, now), domain.ErrNotFound)
+	require.NoError(t, repo.RevokeSession(ctx, user.Id, older.Id, now))
+	assert.ErrorIs(t, repo.RevokeSession(ctx, user.Id, older.Id, now), domain.ErrNotFound)
+	stored, err := repo.GetSession(ctx, older.Id)
+	require.NoError(t, err)
+	assert.False(t, stored.Active(now))
+
+	revoked, err := repo.RevokeUserSessions(ctx, user.Id, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), revoked)
+	sessions, err = repo.ListSessions(ctx, user.Id, now)
+	require.NoError(t, err)
+	assert.Empty(t, sessions)
+}
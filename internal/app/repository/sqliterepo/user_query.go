@@ -0,0 +1,67 @@
+package sqliterepo
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"github.com/vlad19930514/webApp/internal/app/repository/sqlutil"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// ListUsers pages through users with keyset pagination, the query is expected to be normalized
+func (r UserRepo) ListUsers(ctx context.Context, query domain.ListUsersQuery) (domain.UserPage, error) {
+	after, err := query.After()
+	if err != nil {
+		return domain.UserPage{}, fmt.Errorf("failed to list users: %w", err)
+	}
+
+	tx := applyUserFilter(r.db.Conn(ctx).Model(&domain.User{}), query.Filter)
+	if after != nil {
+		after.CreatedAt = after.CreatedAt.UTC()
+		cond, args := sqlutil.KeysetCondition(query.Sort, *after)
+		tx = tx.Where(cond, args...)
+	}
+	for _, s := range query.Sort {
+		tx = tx.Order(clause.OrderByColumn{Column: clause.Column{Name: string(s.Field)}, Desc: s.Desc})
+	}
+	tx = tx.Order(clause.OrderByColumn{Column: clause.Column{Name: "id"}, Desc: sqlutil.IDDesc(query.Sort)})
+
+	var users []domain.User
+	if err := tx.Limit(query.Limit + 1).Find(&users).Error; err != nil {
+		return domain.UserPage{}, fmt.Errorf("failed to list users: %w", translateError(err))
+	}
+
+	page := domain.UserPage{Users: users}
+	if len(users) > query.Limit {
+		page.Users = users[:query.Limit]
+		page.NextCursor = domain.NextUserCursor(query.Sort, page.Users[query.Limit-1])
+	}
+	return page, nil
+}
+
+// applyUserFilter mirrors pgrepo, note that SQLite lower() only folds ASCII letters
+func applyUserFilter(tx *gorm.DB, f domain.UserFilter) *gorm.DB {
+	if f.Name != "" {
+		pattern := "%" + sqlutil.EscapeLike(strings.ToLower(f.Name)) + "%"
+		tx = tx.Where(`(lower(first_name) LIKE ? ESCAPE '\' OR lower(last_name) LIKE ? ESCAPE '\')`, pattern, pattern)
+	}
+	if f.EmailDomain != "" {
+		tx = tx.Where(`lower(email) LIKE ? ESCAPE '\'`, "%@"+sqlutil.EscapeLike(strings.ToLower(f.EmailDomain)))
+	}
+	if f.MinAge != 0 {
+		tx = tx.Where("age >= ?", f.MinAge)
+	}
+	if f.MaxAge != 0 {
+		tx = tx.Where("age <= ?", f.MaxAge)
+	}
+	if !f.CreatedAfter.IsZero() {
+		tx = tx.Where("created_at >= ?", f.CreatedAfter.UTC())
+	}
+	if !f.CreatedBefore.IsZero() {
+		tx = tx.Where("created_at < ?", f.CreatedBefore.UTC())
+	}
+	return tx
+}
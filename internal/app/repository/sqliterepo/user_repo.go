@@ -0,0 +1,201 @@
+package sqliterepo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"github.com/vlad19930514/webApp/internal/app/repository/sqlutil"
+	"github.com/vlad19930514/webApp/internal/pkg/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UserRepo stores users in SQLite. Times are written in UTC because SQLite
+// compares them as text.
+type UserRepo struct {
+	db *sqlite.DB
+}
+
+// NewUserRepo expects the schema to be migrated with sqlite.Migrate
+func NewUserRepo(db *sqlite.DB) *UserRepo {
+	return &UserRepo{
+		db: db,
+	}
+}
+
+func (r UserRepo) CreateUser(ctx context.Context, user domain.User) (domain.User, error) {
+	// SQLite has no uuid_generate_v4(), the defaults of the gorm tags are filled in here
+	if user.Id == uuid.Nil {
+		user.Id = uuid.New()
+	}
+	if user.CreatedAt.IsZero() {
+		user.CreatedAt = time.Now()
+	}
+	user.CreatedAt = user.CreatedAt.UTC()
+	user.Version = 1
+	if user.Role == "" {
+		user.Role = domain.RoleUser
+	}
+
+	result := r.db.Conn(ctx).Create(&user)
+	if result.Error != nil {
+		return domain.User{}, fmt.Errorf("failed to create domain user: %w", translateError(result.Error))
+	}
+	return user, nil
+}
+
+// CreateUsers inserts the users with one multi-row statement. Users whose email
+// is already taken are skipped instead of failing the statement, the returned
+// slice holds only the users that were created. The ids must be new.
+func (r UserRepo) CreateUsers(ctx context.Context, users []domain.User) ([]domain.User, error) {
+	if len(users) == 0 {
+		return nil, nil
+	}
+	now := time.Now()
+	rows := make([]domain.User, len(users))
+	ids := make([]uuid.UUID, len(users))
+	for i, user := range users {
+		if user.Id == uuid.Nil {
+			user.Id = uuid.New()
+		}
+		if user.CreatedAt.IsZero() {
+			user.CreatedAt = now
+		}
+		user.CreatedAt = user.CreatedAt.UTC()
+		user.Version = 1
+		if user.Role == "" {
+			user.Role = domain.RoleUser
+		}
+		rows[i] = user
+		ids[i] = user.Id
+	}
+
+	// DO NOTHING skips the rows that hit a unique index, what got inserted is read back by id
+	conn := r.db.Conn(ctx)
+	if err := conn.Clauses(clause.OnConflict{DoNothing: true}).Create(&rows).Error; err != nil {
+		return nil, fmt.Errorf("failed to create users: %w", translateError(err))
+	}
+	var created []domain.User
+	if err := conn.Where("id IN ?", ids).Find(&created).Error; err != nil {
+		return nil, fmt.Errorf("failed to create users: %w", translateError(err))
+	}
+	return created, nil
+}
+func (r UserRepo) GetUser(ctx context.Context, id uuid.UUID) (domain.User, error) {
+	dbUser := domain.User{
+		Id: id,
+	}
+	result := r.db.Conn(ctx).Take(&dbUser)
+	if result.Error != nil {
+		return domain.User{}, fmt.Errorf("failed to get a user: %w", translateError(result.Error))
+	}
+	return dbUser, nil
+}
+
+// GetUsers returns the active users among ids, unknown ids are left out
+func (r UserRepo) GetUsers(ctx context.Context, ids []uuid.UUID) ([]domain.User, error) {
+	var users []domain.User
+	result := r.db.Conn(ctx).Where("id IN ?", ids).Find(&users)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to get users: %w", translateError(result.Error))
+	}
+	return users, nil
+}
+
+// GetUserByEmail finds the active user with the email, ignoring its case.
+// The lookup matches the lower(email) unique index.
+func (r UserRepo) GetUserByEmail(ctx context.Context, email string) (domain.User, error) {
+	var dbUser domain.User
+	result := r.db.Conn(ctx).Where("lower(email) = lower(?)", email).Take(&dbUser)
+	if result.Error != nil {
+		return domain.User{}, fmt.Errorf("failed to get a user by email: %w", translateError(result.Error))
+	}
+	return dbUser, nil
+}
+
+// UpdateUser writes the given fields only if user.Version is still the stored
+// one, the check and the bump are one UPDATE statement.
+func (r UserRepo) UpdateUser(ctx context.Context, user domain.User, fields []string) (domain.User, error) {
+	columns, err := sqlutil.UserColumns(user, fields)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("failed to update a user: %w", err)
+	}
+	columns["version"] = gorm.Expr("version + 1")
+
+	updated := domain.User{Id: user.Id}
+	result := r.db.Conn(ctx).
+		Model(&updated).
+		Clauses(clause.Returning{}).
+		Where("version = ?", user.Version).
+		Updates(columns)
+	if result.Error != nil {
+		return domain.User{}, fmt.Errorf("failed to update a user: %w", translateError(result.Error))
+	}
+	if result.RowsAffected == 0 {
+		// tell a missing user from a stale version
+		if err := r.db.Conn(ctx).Take(&domain.User{Id: user.Id}).Error; err != nil {
+			return domain.User{}, fmt.Errorf("failed to update a user: %w", translateError(err))
+		}
+		return domain.User{}, fmt.Errorf("failed to update a user: %w", domain.ErrVersionConflict)
+	}
+	return updated, nil
+}
+
+// UpdateUsers writes the client editable fields of every user that still holds
+// user.Version, all in one UPDATE. Users changed or deleted in the meantime are
+// left out of the result. A taken email fails the whole statement.
+func (r UserRepo) UpdateUsers(ctx context.Context, users []domain.User) ([]domain.User, error) {
+	if len(users) == 0 {
+		return nil, nil
+	}
+	values, args := sqlutil.UserValues(users, "(?, ?, ?, ?, ?, ?)")
+	query := `WITH v(id, version, first_name, last_name, email, age) AS (VALUES ` + values + `)
+UPDATE users
+SET first_name = v.first_name, last_name = v.last_name, email = v.email, age = v.age, version = users.version + 1
+FROM v
+WHERE users.id = v.id AND users.version = v.version AND users.deleted_at IS NULL
+RETURNING *`
+
+	var updated []domain.User
+	if err := r.db.Conn(ctx).Raw(query, args...).Scan(&updated).Error; err != nil {
+		return nil, fmt.Errorf("failed to update users: %w", translateError(err))
+	}
+	return updated, nil
+}
+
+func (r UserRepo) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	result := r.db.Conn(ctx).Delete(&domain.User{Id: id})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete a user: %w", translateError(result.Error))
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("failed to delete a user: %w", domain.ErrNotFound)
+	}
+	return nil
+}
+func (r UserRepo) RestoreUser(ctx context.Context, id uuid.UUID) (domain.User, error) {
+	result := r.db.Conn(ctx).Unscoped().
+		Model(&domain.User{}).
+		Where("id = ? AND deleted_at IS NOT NULL", id).
+		Update("deleted_at", nil)
+	if result.Error != nil {
+		return domain.User{}, fmt.Errorf("failed to restore a user: %w", translateError(result.Error))
+	}
+	if result.RowsAffected == 0 {
+		return domain.User{}, fmt.Errorf("failed to restore a user: %w", domain.ErrNotFound)
+	}
+	return r.GetUser(ctx, id)
+}
+func (r UserRepo) PurgeUser(ctx context.Context, id uuid.UUID) error {
+	result := r.db.Conn(ctx).Unscoped().Delete(&domain.User{Id: id})
+	if result.Error != nil {
+		return fmt.Errorf("failed to purge a user: %w", translateError(result.Error))
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("failed to purge a user: %w", domain.ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+package sqliterepo
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"github.com/vlad19930514/webApp/internal/app/migrations/sqlite"
+	sqlitedb "github.com/vlad19930514/webApp/internal/pkg/sqlite"
+	"github.com/vlad19930514/webApp/util"
+)
+
+func newTestDB(t *testing.T) *sqlitedb.DB {
+	t.Helper()
+	db, err := sqlitedb.Open(":memory:")
+	require.NoError(t, err)
+	require.NoError(t, sqlitedb.Migrate(context.Background(), db, sqlite.FS))
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB.DB()
+		sqlDB.Close()
+	})
+	return db
+}
+
+func randomUser() domain.User {
+	return domain.User{
+		FirstName: util.RandomName(),
+		LastName:  util.RandomName(),
+		Email:     util.RandomEmail(),
+		Age:       uint8(util.RandomAge()),
+	}
+}
+
+func TestUserRepo_CreateAndGet(t *testing.T) {
+	repo := NewUserRepo(newTestDB(t))
+	ctx := context.Background()
+
+	created, err := repo.CreateUser(ctx, randomUser())
+	require.NoError(t, err)
+	assert.NotEqual(t, uuid.Nil, created.Id)
+	assert.Equal(t, int64(1), created.Version)
+
+	got, err := repo.GetUser(ctx, created.Id)
+	require.NoError(t, err)
+	assert.Equal(t, created.Email, got.Email)
+	assert.True(t, created.CreatedAt.Equal(got.CreatedAt))
+
+	_, err = repo.GetUser(ctx, uuid.New())
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestUserRepo_SoftDeleteFreesEmail(t *testing.T) {
+	repo := NewUserRepo(newTestDB(t))
+	ctx := context.Background()
+
+	first, err := repo.CreateUser(ctx, randomUser())
+	require.NoError(t, err)
+
+	dup := randomUser()
+	dup.Email = first.Email
+	_, err = repo.CreateUser(ctx, dup)
+	assert.ErrorIs(t, err, domain.ErrEmailTaken)
+
+	require.NoError(t, repo.DeleteUser(ctx, first.Id))
+	assert.ErrorIs(t, repo.DeleteUser(ctx, first.Id), domain.ErrNotFound)
+	_, err = repo.GetUser(ctx, first.Id)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	_, err = repo.CreateUser(ctx, dup)
+	require.NoError(t, err)
+
+	// the email is taken again, restoring would break uniqueness
+	_, err = repo.RestoreUser(ctx, first.Id)
+	assert.ErrorIs(t, err, domain.ErrEmailTaken)
+
+	require.NoError(t, repo.PurgeUser(ctx, first.Id))
+	assert.ErrorIs(t, repo.PurgeUser(ctx, first.Id), domain.ErrNotFound)
+}
+
+func TestUserRepo_UpdateUserVersion(t *testing.T) {
+	repo := NewUserRepo(newTestDB(t))
+	ctx := context.Background()
+
+	user, err := repo.CreateUser(ctx, randomUser())
+	require.NoError(t, err)
+
+	lastName := user.LastName
+	user.FirstName = "Updated"
+	user.LastName = "Not written"
+	updated, err := repo.UpdateUser(ctx, user, []string{"first_name"})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), updated.Version)
+	assert.Equal(t, "Updated", updated.FirstName)
+	assert.Equal(t, lastName, updated.LastName)
+
+	// user still carries version 1
+	_, err = repo.UpdateUser(ctx, user, []string{"first_name"})
+	assert.True(t, errors.Is(err, domain.ErrVersionConflict))
+}
+
+func TestUserRepo_Role(t *testing.T) {
+	repo := NewUserRepo(newTestDB(t))
+	ctx := context.Background()
+
+	user, err := repo.CreateUser(ctx, randomUser())
+	require.NoError(t, err)
+	assert.Equal(t, domain.RoleUser, user.Role)
+
+	user.Role = domain.RoleSupport
+	_, err = repo.UpdateUser(ctx, user, []string{"role"})
+	require.NoError(t, err)
+	got, err := repo.GetUser(ctx, user.Id)
+	require.NoError(t, err)
+	assert.Equal(t, domain.RoleSupport, got.Role)
+
+	// the schema rejects roles that do not exist
+	got.Role = "owner"
+	_, err = repo.UpdateUser(ctx, got, []string{"role"})
+	assert.Error(t, err)
+}
+
+func TestUserRepo_ListUsers(t *testing.T) {
+	repo := NewUserRepo(newTestDB(t))
+	ctx := context.Background()
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.FixedZone("UTC+3", 3*60*60))
+	for i := 0; i < 5; i++ {
+		user := randomUser()
+		user.FirstName = "Anna"
+		user.CreatedAt = base.Add(time.Duration(i) * time.Second)
+		_, err := repo.CreateUser(ctx, user)
+		require.NoError(t, err)
+	}
+	_, err := repo.CreateUser(ctx, randomUser())
+	require.NoError(t, err)
+
+	query, err := domain.ListUsersQuery{Filter: domain.UserFilter{Name: "ann"}, Limit: 2}.Normalize()
+	require.NoError(t, err)
+
+	var seen []domain.User
+	for {
+		page, err := repo.ListUsers(ctx, query)
+		require.NoError(t, err)
+		seen = append(seen, page.Users...)
+		if page.NextCursor == "" {
+			break
+		}
+		query.Cursor = page.NextCursor
+	}
+
+	require.Len(t, seen, 5)
+	for i := 1; i < len(seen); i++ {
+		assert.True(t, seen[i-1].CreatedAt.After(seen[i].CreatedAt))
+	}
+}
+
+func TestUserRepo_SearchUsers(t *testing.T) {
+	repo := NewUserRepo(newTestDB(t))
+	ctx := context.Background()
+
+	user := randomUser()
+	user.FirstName = "Jonathan"
+	created, err := repo.CreateUser(ctx, user)
+	require.NoError(t, err)
+	_, err = repo.CreateUser(ctx, randomUser())
+	require.NoError(t, err)
+
+	query, err := domain.SearchUsersQuery{Query: "jonatan"}.Normalize()
+	require.NoError(t, err)
+	matches, err := repo.SearchUsers(ctx, query)
+	require.NoError(t, err)
+	require.NotEmpty(t, matches)
+	assert.Equal(t, created.Id, matches[0].User.Id)
+}
+
+func TestUserRepo_EmailCaseInsensitive(t *testing.T) {
+	repo := NewUserRepo(newTestDB(t))
+	ctx := context.Background()
+
+	user := randomUser()
+	user.Email = "Bob@example.com"
+	created, err := repo.CreateUser(ctx, user)
+	require.NoError(t, err)
+
+	dup := randomUser()
+	dup.Email = "bob@EXAMPLE.com"
+	_, err = repo.CreateUser(ctx, dup)
+	assert.ErrorIs(t, err, domain.ErrEmailTaken)
+
+	got, err := repo.GetUserByEmail(ctx, "BOB@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, created.Id, got.Id)
+	assert.Equal(t, "Bob@example.com", got.Email)
+
+	require.NoError(t, repo.DeleteUser(ctx, created.Id))
+	_, err = repo.GetUserByEmail(ctx, "bob@example.com")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestUserRepo_CreateUsers(t *testing.T) {
+	repo := NewUserRepo(newTestDB(t))
+	ctx := context.Background()
+
+	taken, err := repo.CreateUser(ctx, randomUser())
+	require.NoError(t, err)
+
+	first, second := randomUser(), randomUser()
+	first.Id, second.Id = uuid.New(), uuid.New()
+	clash := randomUser()
+	clash.Id = uuid.New()
+	clash.Email = taken.Email
+
+	// the taken email is skipped, the others are inserted
+	created, err := repo.CreateUsers(ctx, []domain.User{first, clash, second})
+	require.NoError(t, err)
+	require.Len(t, created, 2)
+	for _, user := range created {
+		assert.Contains(t, []uuid.UUID{first.Id, second.Id}, user.Id)
+		assert.Equal(t, int64(1), user.Version)
+		assert.False(t, user.CreatedAt.IsZero())
+	}
+
+	got, err := repo.GetUsers(ctx, []uuid.UUID{first.Id, clash.Id, taken.Id})
+	require.NoError(t, err)
+	assert.Len(t, got, 2)
+}
+
+func TestUserRepo_UpdateUsers(t *testing.T) {
+	repo := NewUserRepo(newTestDB(t))
+	ctx := context.Background()
+
+	first, err := repo.CreateUser(ctx, randomUser())
+	require.NoError(t, err)
+	second, err := repo.CreateUser(ctx, randomUser())
+	require.NoError(t, err)
+
+	// the stale version is skipped, the other user is written
+	first.Age, second.Age = 50, 60
+	stale := second
+	stale.Version++
+	updated, err := repo.UpdateUsers(ctx, []domain.User{first, stale})
+	require.NoError(t, err)
+	require.Len(t, updated, 1)
+	assert.Equal(t, first.Id, updated[0].Id)
+	assert.Equal(t, uint8(50), updated[0].Age)
+	assert.Equal(t, int64(2), updated[0].Version)
+
+	// a taken email fails the statement and nothing is written
+	second.Email = first.Email
+	first = updated[0]
+	first.Age = 51
+	_, err = repo.UpdateUsers(ctx, []domain.User{first, second})
+	assert.ErrorIs(t, err, domain.ErrEmailTaken)
+	got, err := repo.GetUser(ctx, first.Id)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(50), got.Age)
+}
@@ -0,0 +1,31 @@
+package sqliterepo
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"gorm.io/gorm"
+)
+
+// SearchUsers scores every active user in Go with the same approximation
+// memrepo uses, SQLite has no trigram index. Fine for the small single-node
+// databases this storage is meant for.
+func (r UserRepo) SearchUsers(ctx context.Context, query domain.SearchUsersQuery) ([]domain.UserMatch, error) {
+	var (
+		users   []domain.User
+		matches []domain.UserMatch
+	)
+	result := r.db.Conn(ctx).FindInBatches(&users, 500, func(tx *gorm.DB, batch int) error {
+		for _, user := range users {
+			if score, ok := domain.ScoreUser(query.Query, user); ok {
+				matches = append(matches, domain.UserMatch{User: user, Score: score})
+			}
+		}
+		return nil
+	})
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to search users: %w", translateError(result.Error))
+	}
+	return domain.RankUserMatches(matches, query.Limit), nil
+}
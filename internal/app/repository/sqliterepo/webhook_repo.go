@@ -0,0 +1,174 @@
+package sqliterepo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"github.com/vlad19930514/webApp/internal/pkg/sqlite"
+	"gorm.io/gorm/clause"
+)
+
+type WebhookRepo struct {
+	db *sqlite.DB
+}
+
+func NewWebhookRepo(db *sqlite.DB) *WebhookRepo {
+	return &WebhookRepo{
+		db: db,
+	}
+}
+
+func (r WebhookRepo) CreateWebhook(ctx context.Context, webhook domain.WebhookSubscription) (domain.WebhookSubscription, error) {
+	webhook.CreatedAt, webhook.UpdatedAt = webhook.CreatedAt.UTC(), webhook.UpdatedAt.UTC()
+	result := r.db.Conn(ctx).Create(&webhook)
+	if result.Error != nil {
+		return domain.WebhookSubscription{}, fmt.Errorf("failed to create webhook: %w", translateError(result.Error))
+	}
+	return webhook, nil
+}
+
+func (r WebhookRepo) GetWebhook(ctx context.Context, id uuid.UUID) (domain.WebhookSubscription, error) {
+	var webhook domain.WebhookSubscription
+	result := r.db.Conn(ctx).Where("id = ?", id).First(&webhook)
+	if result.Error != nil {
+		return domain.WebhookSubscription{}, fmt.Errorf("failed to get webhook: %w", translateError(result.Error))
+	}
+	return webhook, nil
+}
+
+func (r WebhookRepo) ListWebhooks(ctx context.Context) ([]domain.WebhookSubscription, error) {
+	var webhooks []domain.WebhookSubscription
+	result := r.db.Conn(ctx).Order("created_at, id").Find(&webhooks)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to list webhooks: %w", translateError(result.Error))
+	}
+	return webhooks, nil
+}
+
+func (r WebhookRepo) UpdateWebhook(ctx context.Context, webhook domain.WebhookSubscription) (domain.WebhookSubscription, error) {
+	webhook.CreatedAt, webhook.UpdatedAt = webhook.CreatedAt.UTC(), webhook.UpdatedAt.UTC()
+	result := r.db.Conn(ctx).
+		Model(&domain.WebhookSubscription{}).
+		Where("id = ?", webhook.Id).
+		Select("url", "secret", "event_types", "fields", "active", "updated_at").
+		Updates(&webhook)
+	if result.Error != nil {
+		return domain.WebhookSubscription{}, fmt.Errorf("failed to update webhook: %w", translateError(result.Error))
+	}
+	if result.RowsAffected == 0 {
+		return domain.WebhookSubscription{}, fmt.Errorf("failed to update webhook: %w", domain.ErrNotFound)
+	}
+	return webhook, nil
+}
+
+// DeleteWebhook removes the subscription, its deliveries go with it
+func (r WebhookRepo) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
+	result := r.db.Conn(ctx).Delete(&domain.WebhookSubscription{Id: id})
+	if result.Error != nil {
+		return fmt.Errorf("failed to delete webhook: %w", translateError(result.Error))
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("failed to delete webhook: %w", domain.ErrNotFound)
+	}
+	return nil
+}
+
+// AddWebhookDeliveries skips deliveries of an event the subscription already has
+func (r WebhookRepo) AddWebhookDeliveries(ctx context.Context, deliveries []domain.WebhookDelivery) error {
+	if len(deliveries) == 0 {
+		return nil
+	}
+	for i := range deliveries {
+		deliveries[i].NextAttemptAt = deliveries[i].NextAttemptAt.UTC()
+		deliveries[i].CreatedAt, deliveries[i].UpdatedAt = deliveries[i].CreatedAt.UTC(), deliveries[i].UpdatedAt.UTC()
+	}
+	result := r.db.Conn(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&deliveries)
+	if result.Error != nil {
+		return fmt.Errorf("failed to add webhook deliveries: %w", translateError(result.Error))
+	}
+	return nil
+}
+
+// ClaimDueDeliveries returns the pending deliveries of active subscriptions
+// that are due at now and moves their next attempt to leaseUntil, so they are
+// tried again should the worker die before it records the outcome
+func (r WebhookRepo) ClaimDueDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]domain.WebhookDelivery, error) {
+	var deliveries []domain.WebhookDelivery
+	result := r.db.Conn(ctx).Raw(`
+		UPDATE webhook_deliveries SET next_attempt_at = ?
+		WHERE id IN (
+			SELECT d.id FROM webhook_deliveries d
+			JOIN webhook_subscriptions s ON s.id = d.subscription_id
+			WHERE d.status = ? AND d.next_attempt_at <= ? AND s.active
+			ORDER BY d.next_attempt_at
+			LIMIT ?
+		)
+		RETURNING *`, leaseUntil.UTC(), domain.DeliveryPending, now.UTC(), limit).
+		Scan(&deliveries)
+	if result.Error != nil {
+		return nil, fmt.Errorf("failed to claim webhook deliveries: %w", translateError(result.Error))
+	}
+	return deliveries, nil
+}
+
+func (r WebhookRepo) GetWebhookDelivery(ctx context.Context, webhookID uuid.UUID, id int64) (domain.WebhookDelivery, error) {
+	var delivery domain.WebhookDelivery
+	result := r.db.Conn(ctx).Where("id = ? AND subscription_id = ?", id, webhookID).First(&delivery)
+	if result.Error != nil {
+		return domain.WebhookDelivery{}, fmt.Errorf("failed to get webhook delivery: %w", translateError(result.Error))
+	}
+	return delivery, nil
+}
+
+// UpdateWebhookDelivery stores the outcome of an attempt or a redelivery
+func (r WebhookRepo) UpdateWebhookDelivery(ctx context.Context, delivery domain.WebhookDelivery) error {
+	result := r.db.Conn(ctx).
+		Model(&domain.WebhookDelivery{}).
+		Where("id = ?", delivery.Id).
+		Updates(map[string]any{
+			"status":           delivery.Status,
+			"attempts":         delivery.Attempts,
+			"next_attempt_at":  delivery.NextAttemptAt.UTC(),
+			"last_status_code": delivery.LastStatusCode,
+			"last_error":       delivery.LastError,
+			"updated_at":       delivery.UpdatedAt.UTC(),
+		})
+	if result.Error != nil {
+		return fmt.Errorf("failed to update webhook delivery: %w", translateError(result.Error))
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("failed to update webhook delivery: %w", domain.ErrNotFound)
+	}
+	return nil
+}
+
+func (r WebhookRepo) ListWebhookDeliveries(ctx context.Context, query domain.DeliveryQuery) (domain.DeliveryPage, error) {
+	before, err := query.Before()
+	if err != nil {
+		return domain.DeliveryPage{}, fmt.Errorf("failed to list webhook deliveries: %w", err)
+	}
+
+	tx := r.db.Conn(ctx).Where("subscription_id = ?", query.SubscriptionId)
+	if query.Status != "" {
+		tx = tx.Where("status = ?", query.Status)
+	}
+	if before > 0 {
+		tx = tx.Where("id < ?", before)
+	}
+
+	var deliveries []domain.WebhookDelivery
+	result := tx.Order("id DESC").Limit(query.Limit + 1).Find(&deliveries)
+	if result.Error != nil {
+		return domain.DeliveryPage{}, fmt.Errorf("failed to list webhook deliveries: %w", translateError(result.Error))
+	}
+
+	page := domain.DeliveryPage{Deliveries: deliveries}
+	if len(deliveries) > query.Limit {
+		page.Deliveries = deliveries[:query.Limit]
+		page.NextCursor = domain.NextDeliveryCursor(page.Deliveries[query.Limit-1])
+	}
+	return page, nil
+}
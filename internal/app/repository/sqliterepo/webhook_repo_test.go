@@ -0,0 +1,78 @@
+package sqliterepo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+func TestWebhookRepo_Deliveries(t *testing.T) {
+	repo := NewWebhookRepo(newTestDB(t))
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Second)
+
+	webhook, err := repo.CreateWebhook(ctx, domain.WebhookSubscription{
+		Id:         uuid.New(),
+		URL:        "https://example.com/hook",
+		Secret:     "secret",
+		EventTypes: []domain.UserEventType{domain.UserCreated},
+		Active:     true,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	})
+	require.NoError(t, err)
+	got, err := repo.GetWebhook(ctx, webhook.Id)
+	require.NoError(t, err)
+	assert.Equal(t, []domain.UserEventType{domain.UserCreated}, got.EventTypes)
+
+	delivery := func(eventID int64, due time.Time) domain.WebhookDelivery {
+		return domain.WebhookDelivery{SubscriptionId: webhook.Id, EventId: eventID, EventType: domain.UserCreated, Payload: []byte(`{}`),
+			Status: domain.DeliveryPending, NextAttemptAt: due, CreatedAt: now, UpdatedAt: now}
+	}
+	require.NoError(t, repo.AddWebhookDeliveries(ctx, []domain.WebhookDelivery{delivery(1, now), delivery(2, now.Add(time.Hour))}))
+	// a republished event is not delivered twice
+	require.NoError(t, repo.AddWebhookDeliveries(ctx, []domain.WebhookDelivery{delivery(1, now)}))
+
+	claimed, err := repo.ClaimDueDeliveries(ctx, now, now.Add(time.Minute), 10)
+	require.NoError(t, err)
+	require.Len(t, claimed, 1)
+	assert.Equal(t, int64(1), claimed[0].EventId)
+	assert.Equal(t, []byte(`{}`), claimed[0].Payload)
+
+	// claimed deliveries are leased, inactive subscriptions are paused
+	claimed, err = repo.ClaimDueDeliveries(ctx, now, now.Add(time.Minute), 10)
+	require.NoError(t, err)
+	assert.Empty(t, claimed)
+	webhook.Active = false
+	_, err = repo.UpdateWebhook(ctx, webhook)
+	require.NoError(t, err)
+	claimed, err = repo.ClaimDueDeliveries(ctx, now.Add(2*time.Hour), now.Add(3*time.Hour), 10)
+	require.NoError(t, err)
+	assert.Empty(t, claimed)
+
+	page, err := repo.ListWebhookDeliveries(ctx, domain.DeliveryQuery{SubscriptionId: webhook.Id, Limit: 1})
+	require.NoError(t, err)
+	require.Len(t, page.Deliveries, 1)
+	assert.Equal(t, int64(2), page.Deliveries[0].EventId)
+	page, err = repo.ListWebhookDeliveries(ctx, domain.DeliveryQuery{SubscriptionId: webhook.Id, Limit: 1, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, page.Deliveries, 1)
+	assert.Empty(t, page.NextCursor)
+
+	first := page.Deliveries[0]
+	first.Record(domain.DeliveryAttempt{StatusCode: 500}, now, 1, time.Minute)
+	require.NoError(t, repo.UpdateWebhookDelivery(ctx, first))
+	stored, err := repo.GetWebhookDelivery(ctx, webhook.Id, first.Id)
+	require.NoError(t, err)
+	assert.Equal(t, domain.DeliveryDead, stored.Status)
+	assert.Equal(t, 500, stored.LastStatusCode)
+
+	require.NoError(t, repo.DeleteWebhook(ctx, webhook.Id))
+	_, err = repo.GetWebhookDelivery(ctx, webhook.Id, first.Id)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
@@ -0,0 +1,69 @@
+// Package sqlutil holds SQL building helpers shared by the GORM based repositories
+package sqlutil
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+// KeysetCondition selects the rows that come after the cursor position.
+// When every column is sorted the same way a row comparison is used so
+// the database can walk a composite index, otherwise the condition is expanded to
+// (a > ?) OR (a = ? AND b < ?) OR ...
+func KeysetCondition(sort []domain.UserSort, after domain.User) (string, []any) {
+	columns := make([]string, 0, len(sort)+1)
+	values := make([]any, 0, len(sort)+1)
+	desc := make([]bool, 0, len(sort)+1)
+	for _, s := range sort {
+		columns = append(columns, string(s.Field))
+		values = append(values, domain.SortValue(after, s.Field))
+		desc = append(desc, s.Desc)
+	}
+	columns = append(columns, "id")
+	values = append(values, after.Id)
+	desc = append(desc, IDDesc(sort))
+
+	uniform := true
+	for _, d := range desc {
+		uniform = uniform && d == desc[0]
+	}
+	if uniform {
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(values)), ", ")
+		return fmt.Sprintf("(%s) %s (%s)", strings.Join(columns, ", "), comparison(desc[0]), placeholders), values
+	}
+
+	var (
+		ors  []string
+		args []any
+	)
+	for i := range columns {
+		var ands []string
+		for j := 0; j < i; j++ {
+			ands = append(ands, columns[j]+" = ?")
+			args = append(args, values[j])
+		}
+		ands = append(ands, fmt.Sprintf("%s %s ?", columns[i], comparison(desc[i])))
+		args = append(args, values[i])
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
+
+func comparison(desc bool) string {
+	if desc {
+		return "<"
+	}
+	return ">"
+}
+
+// IDDesc is the direction of the id tie-breaker, it follows the last sort field
+func IDDesc(sort []domain.UserSort) bool {
+	return len(sort) > 0 && sort[len(sort)-1].Desc
+}
+
+// EscapeLike escapes LIKE wildcards with a backslash
+func EscapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
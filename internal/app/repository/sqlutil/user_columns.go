@@ -0,0 +1,43 @@
+package sqlutil
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+// UserColumns maps the changed user fields to column values for an UPDATE
+func UserColumns(user domain.User, fields []string) (map[string]any, error) {
+	columns := make(map[string]any, len(fields))
+	for _, field := range fields {
+		switch field {
+		case "first_name":
+			columns[field] = user.FirstName
+		case "last_name":
+			columns[field] = user.LastName
+		case "email":
+			columns[field] = user.Email
+		case "age":
+			columns[field] = user.Age
+		case "role":
+			columns[field] = string(user.Role)
+		default:
+			return nil, fmt.Errorf("user field %q cannot be updated", field)
+		}
+	}
+	return columns, nil
+}
+
+// UserValues renders one VALUES row per user for a batch UPDATE, holding the
+// id, the expected version and the client editable fields in that order. row
+// is the placeholder list of one row, e.g. "(?, ?, ?, ?, ?, ?)".
+func UserValues(users []domain.User, row string) (string, []any) {
+	rows := make([]string, len(users))
+	args := make([]any, 0, len(users)*6)
+	for i, user := range users {
+		rows[i] = row
+		args = append(args, user.Id, user.Version, user.FirstName, user.LastName, user.Email, user.Age)
+	}
+	return strings.Join(rows, ", "), args
+}
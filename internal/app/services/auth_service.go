@@ -0,0 +1,347 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+// UserCreator creates users the way UserService does, with audit and outbox
+type UserCreator interface {
+	CreateUser(ctx context.Context, user domain.User) (domain.User, error)
+}
+
+// DefaultRefreshTokenTTL is how long a session lasts without being refreshed
+const DefaultRefreshTokenTTL = 30 * 24 * time.Hour
+
+// refreshTokenSize is the number of random bytes in a refresh token
+const refreshTokenSize = 32
+
+// errRefreshTokenUsed ends the refresh transaction, the session is revoked after the rollback
+var errRefreshTokenUsed = errors.New("refresh token already used")
+
+// AuthService checks passwords, issues access and refresh tokens and manages
+// the sessions they belong to
+type AuthService struct {
+	users       UserRepository
+	creator     UserCreator
+	credentials CredentialRepository
+	sessions    SessionRepository
+	hasher      PasswordHasher
+	tokens      TokenIssuer
+	tx          TxManager
+	refreshTTL  time.Duration
+	// dummyHash is checked for unknown emails, so they take as long as wrong passwords
+	dummyHash func() (string, error)
+}
+
+// NewAuthService creates a new auth service
+func NewAuthService(users UserRepository, creator UserCreator, credentials CredentialRepository, sessions SessionRepository,
+	hasher PasswordHasher, tokens TokenIssuer, tx TxManager, refreshTTL time.Duration) AuthService {
+	if refreshTTL <= 0 {
+		refreshTTL = DefaultRefreshTokenTTL
+	}
+	return AuthService{
+		users:       users,
+		creator:     creator,
+		credentials: credentials,
+		sessions:    sessions,
+		hasher:      hasher,
+		tokens:      tokens,
+		tx:          tx,
+		refreshTTL:  refreshTTL,
+		dummyHash: sync.OnceValues(func() (string, error) {
+			return hasher.Hash(uuid.NewString())
+		}),
+	}
+}
+
+// Login checks the password of the user with the email and starts a session
+// on the calling device. Unknown emails, users without a password and wrong
+// passwords all fail with ErrInvalidCredentials.
+func (s AuthService) Login(ctx context.Context, email, password string) (domain.TokenPair, error) {
+	user, err := s.users.GetUserByEmail(ctx, domain.NormalizeEmail(email))
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		return domain.TokenPair{}, err
+	}
+	var credential domain.Credential
+	if err == nil {
+		credential, err = s.credentials.GetCredential(ctx, user.Id)
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			return domain.TokenPair{}, err
+		}
+	}
+	if err != nil {
+		s.burnVerify(password)
+		return domain.TokenPair{}, domain.ErrInvalidCredentials
+	}
+
+	ok, rehash, err := s.hasher.Verify(credential.PasswordHash, password)
+	if err != nil {
+		return domain.TokenPair{}, fmt.Errorf("failed to verify password: %w", err)
+	}
+	if !ok {
+		return domain.TokenPair{}, domain.ErrInvalidCredentials
+	}
+	if rehash {
+		// the login succeeds either way, the old hash still verifies
+		if err := s.savePassword(ctx, user.Id, password); err != nil {
+			log.Warn().Err(err).Str("user_id", user.Id.String()).Msg("failed to rehash password")
+		}
+	}
+	return s.startSession(ctx, user.Id, time.Now())
+}
+
+// Authenticate returns the principal of a valid access token. Tokens of a
+// revoked session or a deleted user stop working right away, not only once
+// they expire, and the role is the one the user has now.
+func (s AuthService) Authenticate(ctx context.Context, token string) (domain.Principal, error) {
+	now := time.Now()
+	principal, err := s.tokens.Verify(token, now)
+	if err != nil {
+		return domain.Principal{}, err
+	}
+	if principal.SessionId != uuid.Nil {
+		session, err := s.sessions.GetSession(ctx, principal.SessionId)
+		if errors.Is(err, domain.ErrNotFound) || (err == nil && session.RevokedAt != nil) {
+			return domain.Principal{}, fmt.Errorf("%w: session ended", domain.ErrUnauthenticated)
+		}
+		if err != nil {
+			return domain.Principal{}, err
+		}
+	}
+	user, err := s.users.GetUser(ctx, principal.UserId)
+	if errors.Is(err, domain.ErrNotFound) {
+		return domain.Principal{}, fmt.Errorf("%w: user deleted", domain.ErrUnauthenticated)
+	}
+	if err != nil {
+		return domain.Principal{}, err
+	}
+	principal.Role = user.Role
+	return principal, nil
+}
+
+// Refresh exchanges a refresh token for a new pair. Each refresh token works
+// once, presenting a used one again revokes its whole session.
+func (s AuthService) Refresh(ctx context.Context, refreshToken string) (domain.TokenPair, error) {
+	now := time.Now()
+	stored, err := s.sessions.GetRefreshToken(ctx, hashRefreshToken(refreshToken))
+	if errors.Is(err, domain.ErrNotFound) {
+		return domain.TokenPair{}, fmt.Errorf("%w: unknown refresh token", domain.ErrUnauthenticated)
+	}
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+	session, err := s.sessions.GetSession(ctx, stored.SessionId)
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+	if !session.Active(now) {
+		return domain.TokenPair{}, fmt.Errorf("%w: session ended", domain.ErrUnauthenticated)
+	}
+	if _, err := s.users.GetUser(ctx, session.UserId); err != nil {
+		if errors.Is(err, domain.ErrNotFound) {
+			return domain.TokenPair{}, fmt.Errorf("%w: user deleted", domain.ErrUnauthenticated)
+		}
+		return domain.TokenPair{}, err
+	}
+
+	var pair domain.TokenPair
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		fresh, err := s.sessions.UseRefreshToken(ctx, stored.Id, now)
+		if err != nil {
+			return err
+		}
+		if !fresh {
+			return errRefreshTokenUsed
+		}
+		session.LastUsedAt = now
+		session.ExpiresAt = now.Add(s.refreshTTL)
+		if err := s.sessions.ExtendSession(ctx, session.Id, session.LastUsedAt, session.ExpiresAt); err != nil {
+			return err
+		}
+		pair, err = s.issue(ctx, session, now)
+		return err
+	})
+	if errors.Is(err, errRefreshTokenUsed) {
+		log.Warn().Str("session_id", session.Id.String()).Msg("refresh token reused, revoking the session")
+		if err := s.sessions.RevokeSession(ctx, session.UserId, session.Id, now); err != nil && !errors.Is(err, domain.ErrNotFound) {
+			return domain.TokenPair{}, err
+		}
+		return domain.TokenPair{}, domain.ErrRefreshTokenReused
+	}
+	return pair, err
+}
+
+// ListSessions returns the active sessions of the calling user
+func (s AuthService) ListSessions(ctx context.Context) ([]domain.Session, error) {
+	principal, err := s.sessionOwner(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return s.sessions.ListSessions(ctx, principal.UserId, time.Now())
+}
+
+// RevokeSession signs one device of the calling user out, sessions of other
+// users are not found
+func (s AuthService) RevokeSession(ctx context.Context, id uuid.UUID) error {
+	principal, err := s.sessionOwner(ctx)
+	if err != nil {
+		return err
+	}
+	return s.sessions.RevokeSession(ctx, principal.UserId, id, time.Now())
+}
+
+// RevokeUserSessions signs the user out everywhere, it is meant for admins
+// handling a compromised account
+func (s AuthService) RevokeUserSessions(ctx context.Context, userID uuid.UUID) (int64, error) {
+	if err := domain.Authorize(ctx, domain.ActionRevokeSessions, userID); err != nil {
+		return 0, err
+	}
+	if _, err := s.users.GetUser(ctx, userID); err != nil {
+		return 0, err
+	}
+	return s.sessions.RevokeUserSessions(ctx, userID, time.Now())
+}
+
+// Register creates a user together with their password
+func (s AuthService) Register(ctx context.Context, user domain.User, password string) (domain.User, error) {
+	if err := domain.ValidatePassword(password); err != nil {
+		return domain.User{}, err
+	}
+	// hashing is slow on purpose, it stays out of the transaction
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		return domain.User{}, err
+	}
+	var created domain.User
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		created, err = s.creator.CreateUser(ctx, user)
+		if err != nil {
+			return err
+		}
+		return s.credentials.SaveCredential(ctx, domain.Credential{UserId: created.Id, PasswordHash: hash, UpdatedAt: time.Now()})
+	})
+	return created, err
+}
+
+// SetPassword replaces the password of a user. Users changing their own
+// password confirm it with the current one, admins set other users' without.
+func (s AuthService) SetPassword(ctx context.Context, userID uuid.UUID, current, password string) error {
+	if err := domain.Authorize(ctx, domain.ActionSetPassword, userID); err != nil {
+		return err
+	}
+	if principal, _ := domain.PrincipalFromContext(ctx); principal.UserId == userID {
+		credential, err := s.credentials.GetCredential(ctx, userID)
+		if err != nil && !errors.Is(err, domain.ErrNotFound) {
+			return err
+		}
+		if err == nil {
+			ok, _, err := s.hasher.Verify(credential.PasswordHash, current)
+			if err != nil {
+				return fmt.Errorf("failed to verify password: %w", err)
+			}
+			if !ok {
+				return domain.ErrInvalidCredentials
+			}
+		}
+	}
+	if err := domain.ValidatePassword(password); err != nil {
+		return err
+	}
+	if _, err := s.users.GetUser(ctx, userID); err != nil {
+		return err
+	}
+	return s.savePassword(ctx, userID, password)
+}
+
+// sessionOwner returns the principal of a user, the admin token has no sessions
+func (s AuthService) sessionOwner(ctx context.Context) (domain.Principal, error) {
+	principal, ok := domain.PrincipalFromContext(ctx)
+	if !ok {
+		return domain.Principal{}, domain.ErrUnauthenticated
+	}
+	if principal.UserId == uuid.Nil {
+		return domain.Principal{}, domain.ErrForbidden
+	}
+	return principal, nil
+}
+
+// startSession opens a session on the device of the request
+func (s AuthService) startSession(ctx context.Context, userID uuid.UUID, now time.Time) (domain.TokenPair, error) {
+	meta := domain.RequestMetaFromContext(ctx)
+	session := domain.Session{
+		Id:         uuid.New(),
+		UserId:     userID,
+		UserAgent:  meta.UserAgent,
+		ClientIp:   meta.ClientIP,
+		CreatedAt:  now,
+		LastUsedAt: now,
+		ExpiresAt:  now.Add(s.refreshTTL),
+	}
+	var pair domain.TokenPair
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		if err := s.sessions.CreateSession(ctx, session); err != nil {
+			return err
+		}
+		var err error
+		pair, err = s.issue(ctx, session, now)
+		return err
+	})
+	return pair, err
+}
+
+// issue stores a new refresh token of the session and signs an access token for it
+func (s AuthService) issue(ctx context.Context, session domain.Session, now time.Time) (domain.TokenPair, error) {
+	raw := make([]byte, refreshTokenSize)
+	if _, err := rand.Read(raw); err != nil {
+		return domain.TokenPair{}, fmt.Errorf("failed to generate refresh token: %w", err)
+	}
+	refreshToken := base64.RawURLEncoding.EncodeToString(raw)
+	err := s.sessions.AddRefreshToken(ctx, domain.RefreshToken{
+		Id:        uuid.New(),
+		SessionId: session.Id,
+		TokenHash: hashRefreshToken(refreshToken),
+		CreatedAt: now,
+	})
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+	access, err := s.tokens.Issue(domain.Principal{UserId: session.UserId, SessionId: session.Id}, now)
+	if err != nil {
+		return domain.TokenPair{}, err
+	}
+	return domain.TokenPair{Access: access, RefreshToken: refreshToken, Session: session}, nil
+}
+
+// hashRefreshToken is how refresh tokens are stored and looked up, they are
+// random enough that a fast hash does not help guessing them
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s AuthService) savePassword(ctx context.Context, userID uuid.UUID, password string) error {
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		return err
+	}
+	return s.credentials.SaveCredential(ctx, domain.Credential{UserId: userID, PasswordHash: hash, UpdatedAt: time.Now()})
+}
+
+func (s AuthService) burnVerify(password string) {
+	if hash, err := s.dummyHash(); err == nil {
+		_, _, _ = s.hasher.Verify(hash, password)
+	}
+}
@@ -0,0 +1,187 @@
+package services
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vlad19930514/webApp/internal/app/auth"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"github.com/vlad19930514/webApp/internal/app/repository/memrepo"
+)
+
+var testArgon2Params = auth.Argon2Params{Memory: 1024, Time: 1, Threads: 1, SaltLen: 16, KeyLen: 32}
+
+func newAuthService(t *testing.T, params auth.Argon2Params) (AuthService, *memrepo.CredentialRepo, *memrepo.AuditRepo) {
+	t.Helper()
+	keys, err := auth.ParseKeys([]string{"k1:" + base64.StdEncoding.EncodeToString([]byte(strings.Repeat("k", auth.MinKeySize)))})
+	require.NoError(t, err)
+	tokens, err := auth.NewTokens(keys, time.Minute)
+	require.NoError(t, err)
+
+	users, audit, credentials, tx := memrepo.NewUserRepo(), memrepo.NewAuditRepo(), memrepo.NewCredentialRepo(), memrepo.NewTxManager()
+	userService := NewUserService(users, audit, memrepo.NewOutboxRepo(), tx)
+	return NewAuthService(users, userService, credentials, memrepo.NewSessionRepo(), auth.NewHasher(params, 0), tokens, tx, time.Hour), credentials, audit
+}
+
+func TestAuthService_RegisterAndLogin(t *testing.T) {
+	service, credentials, _ := newAuthService(t, testArgon2Params)
+	ctx := context.Background()
+
+	user, err := service.Register(ctx, domain.User{FirstName: "Alice", LastName: "Johnson", Email: "alice@example.com", Age: 28}, "correct horse")
+	require.NoError(t, err)
+	credential, err := credentials.GetCredential(ctx, user.Id)
+	require.NoError(t, err)
+	assert.NotContains(t, credential.PasswordHash, "correct horse")
+
+	pair, err := service.Login(ctx, "ALICE@example.com", "correct horse")
+	require.NoError(t, err)
+	principal, err := service.Authenticate(ctx, pair.Access.Token)
+	require.NoError(t, err)
+	assert.Equal(t, user.Id, principal.UserId)
+
+	_, err = service.Login(ctx, "alice@example.com", "wrong horse")
+	assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+	_, err = service.Login(ctx, "nobody@example.com", "correct horse")
+	assert.ErrorIs(t, err, domain.ErrInvalidCredentials)
+
+	_, err = service.Register(ctx, domain.User{FirstName: "Bob", LastName: "Brown", Email: "bob@example.com", Age: 33}, "short")
+	assert.ErrorIs(t, err, domain.ErrValidation)
+}
+
+func TestAuthService_LoginRehashes(t *testing.T) {
+	weak, credentials, _ := newAuthService(t, testArgon2Params)
+	ctx := context.Background()
+	user, err := weak.Register(ctx, domain.User{FirstName: "Alice", LastName: "Johnson", Email: "alice@example.com", Age: 28}, "correct horse")
+	require.NoError(t, err)
+	before, err := credentials.GetCredential(ctx, user.Id)
+	require.NoError(t, err)
+
+	stronger := testArgon2Params
+	stronger.Time = 2
+	weak.hasher = auth.NewHasher(stronger, 0)
+	_, err = weak.Login(ctx, "alice@example.com", "correct horse")
+	require.NoError(t, err)
+
+	after, err := credentials.GetCredential(ctx, user.Id)
+	require.NoError(t, err)
+	assert.NotEqual(t, before.PasswordHash, after.PasswordHash)
+	assert.Contains(t, after.PasswordHash, "t=2")
+}
+
+func TestAuthService_SetPassword(t *testing.T) {
+	service, _, audit := newAuthService(t, testArgon2Params)
+	ctx := context.Background()
+	alice, err := service.Register(ctx, domain.User{FirstName: "Alice", LastName: "Johnson", Email: "alice@example.com", Age: 28}, "correct horse")
+	require.NoError(t, err)
+	bob, err := service.Register(ctx, domain.User{FirstName: "Bob", LastName: "Brown", Email: "bob@example.com", Age: 33}, "battery staple")
+	require.NoError(t, err)
+
+	assert.ErrorIs(t, service.SetPassword(ctx, alice.Id, "", "new password"), domain.ErrUnauthenticated)
+
+	asAlice := domain.ContextWithPrincipal(ctx, domain.Principal{UserId: alice.Id, Role: domain.RoleUser})
+	assert.ErrorIs(t, service.SetPassword(asAlice, bob.Id, "", "new password"), domain.ErrForbidden)
+	// support edits users but does not set their passwords
+	asAgent := domain.ContextWithPrincipal(ctx, domain.Principal{UserId: uuid.New(), Role: domain.RoleSupport})
+	assert.ErrorIs(t, service.SetPassword(asAgent, bob.Id, "", "new password"), domain.ErrForbidden)
+	assert.ErrorIs(t, service.SetPassword(asAlice, alice.Id, "wrong horse", "new password"), domain.ErrInvalidCredentials)
+	require.NoError(t, service.SetPassword(asAlice, alice.Id, "correct horse", "new password"))
+	_, err = service.Login(ctx, "alice@example.com", "new password")
+	require.NoError(t, err)
+
+	// admins do not know the current password
+	asAdmin := domain.ContextWithPrincipal(ctx, domain.AdminTokenPrincipal)
+	require.NoError(t, service.SetPassword(asAdmin, bob.Id, "", "reset password"))
+	_, err = service.Login(ctx, "bob@example.com", "reset password")
+	require.NoError(t, err)
+
+	// the audit log names the principal
+	asSupport := domain.ContextWithPrincipal(ctx, domain.Principal{UserId: alice.Id, Role: domain.RoleSupport})
+	carol, err := NewUserService(memrepo.NewUserRepo(), audit, memrepo.NewOutboxRepo(), memrepo.NewTxManager()).
+		CreateUser(asSupport, domain.User{FirstName: "Carol", LastName: "White", Email: "carol@example.com", Age: 40})
+	require.NoError(t, err)
+	page, err := audit.ListAuditRecords(ctx, domain.AuditQuery{UserId: carol.Id, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page.Records, 1)
+	assert.Equal(t, "user:"+alice.Id.String(), page.Records[0].Actor)
+}
+
+func TestAuthService_RefreshRotation(t *testing.T) {
+	service, _, _ := newAuthService(t, testArgon2Params)
+	ctx := domain.ContextWithRequestMeta(context.Background(), domain.RequestMeta{UserAgent: "phone", ClientIP: "10.0.0.1"})
+	user, err := service.Register(ctx, domain.User{FirstName: "Alice", LastName: "Johnson", Email: "alice@example.com", Age: 28}, "correct horse")
+	require.NoError(t, err)
+
+	login, err := service.Login(ctx, "alice@example.com", "correct horse")
+	require.NoError(t, err)
+	assert.Equal(t, "phone", login.Session.UserAgent)
+
+	refreshed, err := service.Refresh(ctx, login.RefreshToken)
+	require.NoError(t, err)
+	assert.NotEqual(t, login.RefreshToken, refreshed.RefreshToken)
+	assert.Equal(t, login.Session.Id, refreshed.Session.Id)
+	principal, err := service.Authenticate(ctx, refreshed.Access.Token)
+	require.NoError(t, err)
+	assert.Equal(t, domain.Principal{UserId: user.Id, SessionId: login.Session.Id, Role: domain.RoleUser}, principal)
+
+	_, err = service.Refresh(ctx, "unknown")
+	assert.ErrorIs(t, err, domain.ErrUnauthenticated)
+
+	// replaying the rotated token ends the session for everyone holding its tokens
+	_, err = service.Refresh(ctx, login.RefreshToken)
+	assert.ErrorIs(t, err, domain.ErrRefreshTokenReused)
+	_, err = service.Refresh(ctx, refreshed.RefreshToken)
+	assert.ErrorIs(t, err, domain.ErrUnauthenticated)
+	_, err = service.Authenticate(ctx, refreshed.Access.Token)
+	assert.ErrorIs(t, err, domain.ErrUnauthenticated)
+}
+
+func TestAuthService_Sessions(t *testing.T) {
+	service, _, _ := newAuthService(t, testArgon2Params)
+	ctx := context.Background()
+	alice, err := service.Register(ctx, domain.User{FirstName: "Alice", LastName: "Johnson", Email: "alice@example.com", Age: 28}, "correct horse")
+	require.NoError(t, err)
+	bob, err := service.Register(ctx, domain.User{FirstName: "Bob", LastName: "Brown", Email: "bob@example.com", Age: 33}, "battery staple")
+	require.NoError(t, err)
+
+	laptop, err := service.Login(ctx, "alice@example.com", "correct horse")
+	require.NoError(t, err)
+	phone, err := service.Login(ctx, "alice@example.com", "correct horse")
+	require.NoError(t, err)
+	bobs, err := service.Login(ctx, "bob@example.com", "battery staple")
+	require.NoError(t, err)
+
+	_, err = service.ListSessions(ctx)
+	assert.ErrorIs(t, err, domain.ErrUnauthenticated)
+
+	asAlice := domain.ContextWithPrincipal(ctx, domain.Principal{UserId: alice.Id, SessionId: laptop.Session.Id, Role: domain.RoleUser})
+	sessions, err := service.ListSessions(asAlice)
+	require.NoError(t, err)
+	assert.Len(t, sessions, 2)
+
+	// other users' sessions are out of reach
+	assert.ErrorIs(t, service.RevokeSession(asAlice, bobs.Session.Id), domain.ErrNotFound)
+	require.NoError(t, service.RevokeSession(asAlice, phone.Session.Id))
+	_, err = service.Refresh(ctx, phone.RefreshToken)
+	assert.ErrorIs(t, err, domain.ErrUnauthenticated)
+	sessions, err = service.ListSessions(asAlice)
+	require.NoError(t, err)
+	require.Len(t, sessions, 1)
+	assert.Equal(t, laptop.Session.Id, sessions[0].Id)
+
+	_, err = service.RevokeUserSessions(asAlice, bob.Id)
+	assert.ErrorIs(t, err, domain.ErrForbidden)
+	asAdmin := domain.ContextWithPrincipal(ctx, domain.AdminTokenPrincipal)
+	revoked, err := service.RevokeUserSessions(asAdmin, alice.Id)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), revoked)
+	_, err = service.Authenticate(ctx, laptop.Access.Token)
+	assert.ErrorIs(t, err, domain.ErrUnauthenticated)
+	_, err = service.Authenticate(ctx, bobs.Access.Token)
+	assert.NoError(t, err)
+}
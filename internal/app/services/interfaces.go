@@ -2,12 +2,79 @@ package services
 
 import (
 	"context"
+	"time"
+
 	"github.com/google/uuid"
 	"github.com/vlad19930514/webApp/internal/app/domain"
 )
 
 type UserRepository interface {
 	CreateUser(ctx context.Context, user domain.User) (domain.User, error)
+	CreateUsers(ctx context.Context, users []domain.User) ([]domain.User, error)
 	GetUser(ctx context.Context, id uuid.UUID) (domain.User, error)
-	UpdateUser(ctx context.Context, user domain.User) (domain.User, error)
+	GetUsers(ctx context.Context, ids []uuid.UUID) ([]domain.User, error)
+	GetUserByEmail(ctx context.Context, email string) (domain.User, error)
+	UpdateUser(ctx context.Context, user domain.User, fields []string) (domain.User, error)
+	UpdateUsers(ctx context.Context, users []domain.User) ([]domain.User, error)
+	DeleteUser(ctx context.Context, id uuid.UUID) error
+	RestoreUser(ctx context.Context, id uuid.UUID) (domain.User, error)
+	PurgeUser(ctx context.Context, id uuid.UUID) error
+	ListUsers(ctx context.Context, query domain.ListUsersQuery) (domain.UserPage, error)
+	SearchUsers(ctx context.Context, query domain.SearchUsersQuery) ([]domain.UserMatch, error)
+}
+
+type AuditRepository interface {
+	CreateAuditRecord(ctx context.Context, record domain.AuditRecord) error
+	CreateAuditRecords(ctx context.Context, records []domain.AuditRecord) error
+	ListAuditRecords(ctx context.Context, query domain.AuditQuery) (domain.AuditPage, error)
+}
+
+type OutboxRepository interface {
+	AddOutboxEvent(ctx context.Context, event domain.OutboxEvent) error
+	AddOutboxEvents(ctx context.Context, events []domain.OutboxEvent) error
+}
+
+// TxManager runs fn atomically, repositories called with the ctx passed to fn take part in the transaction
+type TxManager interface {
+	WithinTx(ctx context.Context, fn func(ctx context.Context) error) error
+}
+
+type WebhookRepository interface {
+	CreateWebhook(ctx context.Context, webhook domain.WebhookSubscription) (domain.WebhookSubscription, error)
+	GetWebhook(ctx context.Context, id uuid.UUID) (domain.WebhookSubscription, error)
+	ListWebhooks(ctx context.Context) ([]domain.WebhookSubscription, error)
+	UpdateWebhook(ctx context.Context, webhook domain.WebhookSubscription) (domain.WebhookSubscription, error)
+	DeleteWebhook(ctx context.Context, id uuid.UUID) error
+	GetWebhookDelivery(ctx context.Context, webhookID uuid.UUID, id int64) (domain.WebhookDelivery, error)
+	UpdateWebhookDelivery(ctx context.Context, delivery domain.WebhookDelivery) error
+	ListWebhookDeliveries(ctx context.Context, query domain.DeliveryQuery) (domain.DeliveryPage, error)
+}
+
+type CredentialRepository interface {
+	GetCredential(ctx context.Context, userID uuid.UUID) (domain.Credential, error)
+	SaveCredential(ctx context.Context, credential domain.Credential) error
+}
+
+// PasswordHasher hashes passwords, rehash reports a hash made with outdated parameters
+type PasswordHasher interface {
+	Hash(password string) (string, error)
+	Verify(hash, password string) (ok, rehash bool, err error)
+}
+
+// TokenIssuer signs and checks access tokens
+type TokenIssuer interface {
+	Issue(principal domain.Principal, now time.Time) (domain.AccessToken, error)
+	Verify(token string, now time.Time) (domain.Principal, error)
+}
+
+type SessionRepository interface {
+	CreateSession(ctx context.Context, session domain.Session) error
+	GetSession(ctx context.Context, id uuid.UUID) (domain.Session, error)
+	ListSessions(ctx context.Context, userID uuid.UUID, now time.Time) ([]domain.Session, error)
+	ExtendSession(ctx context.Context, id uuid.UUID, lastUsedAt, expiresAt time.Time) error
+	RevokeSession(ctx context.Context, userID, id uuid.UUID, now time.Time) error
+	RevokeUserSessions(ctx context.Context, userID uuid.UUID, now time.Time) (int64, error)
+	AddRefreshToken(ctx context.Context, token domain.RefreshToken) error
+	GetRefreshToken(ctx context.Context, tokenHash string) (domain.RefreshToken, error)
+	UseRefreshToken(ctx context.Context, id uuid.UUID, now time.Time) (bool, error)
 }
@@ -0,0 +1,254 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+// CreateUsers creates the users with one multi-row insert. Results follow the
+// order of users. In BatchAtomic mode the first failed item fails the batch with
+// a *domain.BatchItemError and nothing is created. Batch creation is not a
+// sign-up, anonymous callers may not use it.
+func (s UserService) CreateUsers(ctx context.Context, users []domain.User, mode domain.BatchMode) ([]domain.UserResult, error) {
+	if err := domain.Authorize(ctx, domain.ActionCreateUser, uuid.Nil); err != nil {
+		return nil, err
+	}
+	results := make([]domain.UserResult, len(users))
+	batch := make([]domain.User, 0, len(users))
+	indexes := make(map[uuid.UUID]int, len(users))
+	emails := make(map[string]bool, len(users))
+	for i, user := range users {
+		user.Email = domain.NormalizeEmail(user.Email)
+		user.Role = domain.RoleUser
+		if err := user.Validate(); err != nil {
+			results[i].Err = err
+			continue
+		}
+		// the insert would skip the later duplicate anyway, failing it here is cheaper
+		key := domain.EmailKey(user.Email)
+		if emails[key] {
+			results[i].Err = fmt.Errorf("failed to create domain user: %w", domain.ErrEmailTaken)
+			continue
+		}
+		emails[key] = true
+		user.Id = uuid.New()
+		indexes[user.Id] = i
+		batch = append(batch, user)
+	}
+	if err := checkAtomic(mode, results); err != nil {
+		return nil, err
+	}
+
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		created, err := s.repo.CreateUsers(ctx, batch)
+		if err != nil {
+			return err
+		}
+		for _, user := range created {
+			results[indexes[user.Id]].User = user
+		}
+		for _, user := range batch {
+			if i := indexes[user.Id]; results[i].User.Id == uuid.Nil {
+				results[i].Err = fmt.Errorf("failed to create domain user: %w", domain.ErrEmailTaken)
+			}
+		}
+		if err := checkAtomic(mode, results); err != nil {
+			return err
+		}
+
+		records := make([]domain.AuditRecord, 0, len(created))
+		events := make([]domain.OutboxEvent, 0, len(created))
+		meta := domain.RequestMetaFromContext(ctx)
+		for _, result := range results {
+			if result.Err != nil {
+				continue
+			}
+			user := result.User
+			records = append(records, domain.NewAuditRecord(meta, domain.AuditCreate, user.Id, nil, &user))
+			events = append(events, domain.NewUserEvent(domain.UserCreated, user))
+		}
+		if err := s.audit.CreateAuditRecords(ctx, records); err != nil {
+			return err
+		}
+		return s.outbox.AddOutboxEvents(ctx, events)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// GetUsers reads the users in one query, unknown ids get domain.ErrNotFound
+func (s UserService) GetUsers(ctx context.Context, ids []uuid.UUID) ([]domain.UserResult, error) {
+	if err := domain.Authorize(ctx, domain.ActionListUsers, uuid.Nil); err != nil {
+		return nil, err
+	}
+	users, err := s.repo.GetUsers(ctx, ids)
+	if err != nil {
+		return nil, err
+	}
+	byID := make(map[uuid.UUID]domain.User, len(users))
+	for _, user := range users {
+		byID[user.Id] = user
+	}
+
+	results := make([]domain.UserResult, len(ids))
+	for i, id := range ids {
+		if user, ok := byID[id]; ok {
+			results[i].User = user
+		} else {
+			results[i].Err = fmt.Errorf("failed to get a user: %w", domain.ErrNotFound)
+		}
+	}
+	return results, nil
+}
+
+// UpdateUsers applies every patch like PatchUser does, permissions included.
+// The users are read with one query and written with one UPDATE, their audit
+// records and events with one insert each. In BatchAtomic mode the first failed
+// item fails the batch with a *domain.BatchItemError and nothing is written, in
+// BatchBestEffort mode the other items are.
+func (s UserService) UpdateUsers(ctx context.Context, patches []domain.UserPatch, mode domain.BatchMode) ([]domain.UserResult, error) {
+	results := make([]domain.UserResult, len(patches))
+	ids := make([]uuid.UUID, 0, len(patches))
+	for i, patch := range patches {
+		if err := domain.Authorize(ctx, domain.ActionUpdateUser, patch.Id); err != nil {
+			results[i].Err = err
+			continue
+		}
+		ids = append(ids, patch.Id)
+	}
+	if err := checkAtomic(mode, results); err != nil {
+		return nil, err
+	}
+
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		stored, err := s.repo.GetUsers(ctx, ids)
+		if err != nil {
+			return err
+		}
+		byID := make(map[uuid.UUID]domain.User, len(stored))
+		for _, user := range stored {
+			byID[user.Id] = user
+		}
+
+		batch := make([]domain.User, 0, len(patches))
+		fields := make(map[uuid.UUID][]string, len(patches))
+		indexes := make(map[uuid.UUID]int, len(patches))
+		for i, patch := range patches {
+			if results[i].Err != nil {
+				continue
+			}
+			before, ok := byID[patch.Id]
+			if !ok {
+				results[i].Err = fmt.Errorf("failed to update a user: %w", domain.ErrNotFound)
+				continue
+			}
+			// one after the other, the second patch of a user would find its version bumped
+			if _, ok := indexes[patch.Id]; ok {
+				results[i].Err = fmt.Errorf("failed to update a user: %w", domain.ErrVersionConflict)
+				continue
+			}
+			after, changed, err := applyPatch(ctx, before, patch.Version, patch.Apply)
+			if err != nil {
+				results[i].Err = err
+				continue
+			}
+			if len(changed) == 0 {
+				results[i].User = before
+				continue
+			}
+			batch = append(batch, after)
+			fields[after.Id] = changed
+			indexes[after.Id] = i
+		}
+		if err := checkAtomic(mode, results); err != nil {
+			return err
+		}
+
+		written, err := s.writeUsers(ctx, batch, fields)
+		if err != nil {
+			return err
+		}
+		for _, user := range batch {
+			results[indexes[user.Id]] = written[user.Id]
+		}
+		if err := checkAtomic(mode, results); err != nil {
+			return err
+		}
+
+		records := make([]domain.AuditRecord, 0, len(batch))
+		events := make([]domain.OutboxEvent, 0, len(batch))
+		meta := domain.RequestMetaFromContext(ctx)
+		for _, user := range batch {
+			result := written[user.Id]
+			if result.Err != nil {
+				continue
+			}
+			before, updated := byID[user.Id], result.User
+			records = append(records, domain.NewAuditRecord(meta, domain.AuditUpdate, user.Id, &before, &updated))
+			event := domain.NewUserEvent(domain.UserUpdated, updated)
+			event.Payload.ChangedFields = fields[user.Id]
+			events = append(events, event)
+		}
+		if err := s.audit.CreateAuditRecords(ctx, records); err != nil {
+			return err
+		}
+		return s.outbox.AddOutboxEvents(ctx, events)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
+
+// writeUsers writes the batch with one UPDATE, a user that changed since it was
+// read gets domain.ErrVersionConflict. A taken email fails that statement as a
+// whole, the users are then written one by one to tell which of them failed.
+func (s UserService) writeUsers(ctx context.Context, batch []domain.User, fields map[uuid.UUID][]string) (map[uuid.UUID]domain.UserResult, error) {
+	written := make(map[uuid.UUID]domain.UserResult, len(batch))
+	// the savepoint keeps the failed statement from aborting the transaction
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		updated, err := s.repo.UpdateUsers(ctx, batch)
+		if err != nil {
+			return err
+		}
+		for _, user := range updated {
+			written[user.Id] = domain.UserResult{User: user}
+		}
+		for _, user := range batch {
+			if _, ok := written[user.Id]; !ok {
+				written[user.Id] = domain.UserResult{Err: fmt.Errorf("failed to update a user: %w", domain.ErrVersionConflict)}
+			}
+		}
+		return nil
+	})
+	if !errors.Is(err, domain.ErrEmailTaken) {
+		return written, err
+	}
+
+	for _, user := range batch {
+		var result domain.UserResult
+		result.Err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+			var err error
+			result.User, err = s.repo.UpdateUser(ctx, user, fields[user.Id])
+			return err
+		})
+		written[user.Id] = result
+	}
+	return written, nil
+}
+
+func checkAtomic(mode domain.BatchMode, results []domain.UserResult) error {
+	if mode != domain.BatchAtomic {
+		return nil
+	}
+	if i := domain.FirstFailed(results); i >= 0 {
+		return &domain.BatchItemError{Index: i, Err: results[i].Err}
+	}
+	return nil
+}
@@ -0,0 +1,218 @@
+package services
+
+import (
+	"context"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"github.com/vlad19930514/webApp/internal/app/migrations/sqlite"
+	"github.com/vlad19930514/webApp/internal/app/repository/memrepo"
+	"github.com/vlad19930514/webApp/internal/app/repository/sqliterepo"
+	sqlitedb "github.com/vlad19930514/webApp/internal/pkg/sqlite"
+)
+
+// newSQLiteService rolls transactions back in the database
+func newSQLiteService(t *testing.T) UserService {
+	t.Helper()
+	db, err := sqlitedb.Open(":memory:")
+	require.NoError(t, err)
+	require.NoError(t, sqlitedb.Migrate(context.Background(), db, sqlite.FS))
+	t.Cleanup(func() {
+		sqlDB, _ := db.DB.DB()
+		sqlDB.Close()
+	})
+	return NewUserService(sqliterepo.NewUserRepo(db), sqliterepo.NewAuditRepo(db), sqliterepo.NewOutboxRepo(db), sqlitedb.NewTxManager(db))
+}
+
+// newMemService rolls transactions back by restoring memrepo snapshots
+func newMemService(*testing.T) UserService {
+	users, audit, outbox := memrepo.NewUserRepo(), memrepo.NewAuditRepo(), memrepo.NewOutboxRepo()
+	return NewUserService(users, audit, outbox, memrepo.NewTxManager(users, audit, outbox))
+}
+
+// rollbackBackends are the storages whose transactions roll back
+var rollbackBackends = map[string]func(t *testing.T) UserService{
+	"sqlite": newSQLiteService,
+	"memory": newMemService,
+}
+
+func TestUserService_CreateUsersBestEffort(t *testing.T) {
+	outbox := memrepo.NewOutboxRepo()
+	service := NewUserService(memrepo.NewUserRepo(), memrepo.NewAuditRepo(), outbox, memrepo.NewTxManager())
+	ctx := adminContext()
+
+	taken, err := service.CreateUser(ctx, domain.User{FirstName: "Bob", LastName: "Brown", Email: "bob@example.com", Age: 33})
+	require.NoError(t, err)
+
+	results, err := service.CreateUsers(ctx, []domain.User{
+		{FirstName: "Alice", LastName: "Johnson", Email: "Alice@Example.com", Age: 28},
+		{FirstName: "Bob", LastName: "Green", Email: "BOB@example.com", Age: 40},
+		{FirstName: "", LastName: "Nobody", Email: "nobody@example.com", Age: 20},
+		{FirstName: "Alice", LastName: "Smith", Email: "alice@example.com", Age: 30},
+	}, domain.BatchBestEffort)
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+
+	require.NoError(t, results[0].Err)
+	assert.Equal(t, "Alice@example.com", results[0].User.Email)
+	assert.Equal(t, int64(1), results[0].User.Version)
+	assert.ErrorIs(t, results[1].Err, domain.ErrEmailTaken)
+	assert.ErrorIs(t, results[2].Err, domain.ErrValidation)
+	// a duplicate inside the batch loses to the earlier item
+	assert.ErrorIs(t, results[3].Err, domain.ErrEmailTaken)
+
+	got, err := service.GetUsers(ctx, []uuid.UUID{results[0].User.Id, uuid.New(), taken.Id})
+	require.NoError(t, err)
+	assert.Equal(t, results[0].User, got[0].User)
+	assert.ErrorIs(t, got[1].Err, domain.ErrNotFound)
+	assert.Equal(t, taken, got[2].User)
+
+	page, err := service.ListAuditRecords(ctx, domain.AuditQuery{UserId: results[0].User.Id})
+	require.NoError(t, err)
+	assert.Len(t, page.Records, 1)
+	events, err := outbox.FetchUnpublished(ctx, 10)
+	require.NoError(t, err)
+	assert.Len(t, events, 2)
+}
+
+func TestUserService_CreateUsersAtomic(t *testing.T) {
+	for name, newService := range rollbackBackends {
+		t.Run(name, func(t *testing.T) {
+			service := newService(t)
+			ctx := adminContext()
+
+			_, err := service.CreateUser(ctx, domain.User{FirstName: "Bob", LastName: "Brown", Email: "bob@example.com", Age: 33})
+			require.NoError(t, err)
+
+			_, err = service.CreateUsers(ctx, []domain.User{
+				{FirstName: "Alice", LastName: "Johnson", Email: "alice@example.com", Age: 28},
+				{FirstName: "Bob", LastName: "Green", Email: "bob@example.com", Age: 40},
+			}, domain.BatchAtomic)
+			var itemErr *domain.BatchItemError
+			require.ErrorAs(t, err, &itemErr)
+			assert.Equal(t, 1, itemErr.Index)
+			assert.ErrorIs(t, err, domain.ErrEmailTaken)
+
+			// the first item was inserted and rolled back
+			_, err = service.GetUserByEmail(ctx, "alice@example.com")
+			assert.ErrorIs(t, err, domain.ErrNotFound)
+		})
+	}
+}
+
+func TestUserService_UpdateUsers(t *testing.T) {
+	service := newSQLiteService(t)
+	ctx := adminContext()
+
+	results, err := service.CreateUsers(ctx, []domain.User{
+		{FirstName: "Alice", LastName: "Johnson", Email: "alice@example.com", Age: 28},
+		{FirstName: "Bob", LastName: "Brown", Email: "bob@example.com", Age: 33},
+	}, domain.BatchAtomic)
+	require.NoError(t, err)
+	alice, bob := results[0].User, results[1].User
+
+	setAge := func(age uint8) func(domain.User) (domain.User, error) {
+		return func(u domain.User) (domain.User, error) {
+			u.Age = age
+			return u, nil
+		}
+	}
+
+	// a stale version fails the batch and the first update is rolled back
+	_, err = service.UpdateUsers(ctx, []domain.UserPatch{
+		{Id: alice.Id, Version: alice.Version, Apply: setAge(29)},
+		{Id: bob.Id, Version: bob.Version + 1, Apply: setAge(34)},
+	}, domain.BatchAtomic)
+	assert.ErrorIs(t, err, domain.ErrVersionConflict)
+	got, err := service.GetUser(ctx, alice.Id)
+	require.NoError(t, err)
+	assert.Equal(t, alice.Age, got.Age)
+
+	results, err = service.UpdateUsers(ctx, []domain.UserPatch{
+		{Id: alice.Id, Version: alice.Version, Apply: setAge(29)},
+		{Id: bob.Id, Version: bob.Version + 1, Apply: setAge(34)},
+	}, domain.BatchBestEffort)
+	require.NoError(t, err)
+	require.NoError(t, results[0].Err)
+	assert.Equal(t, uint8(29), results[0].User.Age)
+	assert.ErrorIs(t, results[1].Err, domain.ErrVersionConflict)
+}
+
+func TestUserService_UpdateUsersEmailTaken(t *testing.T) {
+	service := newSQLiteService(t)
+	ctx := adminContext()
+
+	results, err := service.CreateUsers(ctx, []domain.User{
+		{FirstName: "Alice", LastName: "Johnson", Email: "alice@example.com", Age: 28},
+		{FirstName: "Bob", LastName: "Brown", Email: "bob@example.com", Age: 33},
+	}, domain.BatchAtomic)
+	require.NoError(t, err)
+	alice, bob := results[0].User, results[1].User
+
+	// the clash is reported on its own item, the other items are written once
+	results, err = service.UpdateUsers(ctx, []domain.UserPatch{
+		{Id: alice.Id, Version: alice.Version, Apply: func(u domain.User) (domain.User, error) {
+			u.Age = 29
+			return u, nil
+		}},
+		{Id: bob.Id, Version: bob.Version, Apply: func(u domain.User) (domain.User, error) {
+			u.Email = "ALICE@example.com"
+			return u, nil
+		}},
+		{Id: bob.Id, Version: bob.Version, Apply: func(u domain.User) (domain.User, error) {
+			u.Age = 34
+			return u, nil
+		}},
+	}, domain.BatchBestEffort)
+	require.NoError(t, err)
+	require.NoError(t, results[0].Err)
+	assert.Equal(t, alice.Version+1, results[0].User.Version)
+	assert.ErrorIs(t, results[1].Err, domain.ErrEmailTaken)
+	assert.ErrorIs(t, results[2].Err, domain.ErrVersionConflict)
+
+	page, err := service.ListAuditRecords(ctx, domain.AuditQuery{UserId: alice.Id, Limit: 10})
+	require.NoError(t, err)
+	assert.Len(t, page.Records, 2)
+}
+
+func TestUserService_UpdateUsersAtomicEmailTaken(t *testing.T) {
+	for name, newService := range rollbackBackends {
+		t.Run(name, func(t *testing.T) {
+			service := newService(t)
+			ctx := adminContext()
+
+			results, err := service.CreateUsers(ctx, []domain.User{
+				{FirstName: "Alice", LastName: "Johnson", Email: "alice@example.com", Age: 28},
+				{FirstName: "Bob", LastName: "Brown", Email: "bob@example.com", Age: 33},
+			}, domain.BatchAtomic)
+			require.NoError(t, err)
+			alice, bob := results[0].User, results[1].User
+
+			_, err = service.UpdateUsers(ctx, []domain.UserPatch{
+				{Id: alice.Id, Version: alice.Version, Apply: func(u domain.User) (domain.User, error) {
+					u.Age = 29
+					return u, nil
+				}},
+				{Id: bob.Id, Version: bob.Version, Apply: func(u domain.User) (domain.User, error) {
+					u.Email = "ALICE@example.com"
+					return u, nil
+				}},
+			}, domain.BatchAtomic)
+			var itemErr *domain.BatchItemError
+			require.ErrorAs(t, err, &itemErr)
+			assert.Equal(t, 1, itemErr.Index)
+			assert.ErrorIs(t, err, domain.ErrEmailTaken)
+
+			// alice was written one by one after the batch UPDATE failed, then rolled back
+			got, err := service.GetUser(ctx, alice.Id)
+			require.NoError(t, err)
+			assert.Equal(t, alice, got)
+			page, err := service.ListAuditRecords(ctx, domain.AuditQuery{UserId: alice.Id, Limit: 10})
+			require.NoError(t, err)
+			assert.Len(t, page.Records, 1)
+		})
+	}
+}
@@ -2,28 +2,293 @@ package services
 
 import (
 	"context"
+	"slices"
+
 	"github.com/google/uuid"
 	"github.com/vlad19930514/webApp/internal/app/domain"
 )
 
 // UserService is a user service
 type UserService struct {
-	repo UserRepository
+	repo   UserRepository
+	audit  AuditRepository
+	outbox OutboxRepository
+	tx     TxManager
 }
 
 // NewUserService creates a new user service
-func NewUserService(repo UserRepository) UserService {
+func NewUserService(repo UserRepository, audit AuditRepository, outbox OutboxRepository, tx TxManager) UserService {
 	return UserService{
-		repo: repo,
+		repo:   repo,
+		audit:  audit,
+		outbox: outbox,
+		tx:     tx,
 	}
 }
 
+// CreateUser creates a user. Like every mutation it validates the user first
+// and writes the audit record and the outbox event in the same transaction.
+// Anonymous callers sign themselves up, new users always get RoleUser.
 func (s UserService) CreateUser(ctx context.Context, user domain.User) (domain.User, error) {
-	return s.repo.CreateUser(ctx, user)
+	if err := authorizeCreate(ctx); err != nil {
+		return domain.User{}, err
+	}
+	user.Email = domain.NormalizeEmail(user.Email)
+	user.Role = domain.RoleUser
+	if err := user.Validate(); err != nil {
+		return domain.User{}, err
+	}
+	var created domain.User
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		created, err = s.repo.CreateUser(ctx, user)
+		if err != nil {
+			return err
+		}
+		if err := s.record(ctx, domain.AuditCreate, created.Id, nil, &created); err != nil {
+			return err
+		}
+		return s.outbox.AddOutboxEvent(ctx, domain.NewUserEvent(domain.UserCreated, created))
+	})
+	return created, err
 }
 func (s UserService) GetUser(ctx context.Context, id uuid.UUID) (domain.User, error) {
+	if err := domain.Authorize(ctx, domain.ActionReadUser, id); err != nil {
+		return domain.User{}, err
+	}
 	return s.repo.GetUser(ctx, id)
 }
+
+// GetUserByEmail looks a user up by email, the address is normalized the same
+// way it was on create and its case does not matter
+func (s UserService) GetUserByEmail(ctx context.Context, email string) (domain.User, error) {
+	if err := domain.Authorize(ctx, domain.ActionListUsers, uuid.Nil); err != nil {
+		return domain.User{}, err
+	}
+	return s.repo.GetUserByEmail(ctx, domain.NormalizeEmail(email))
+}
+
+// UpdateUser replaces the client editable fields of the user holding user.Version
 func (s UserService) UpdateUser(ctx context.Context, user domain.User) (domain.User, error) {
-	return s.repo.UpdateUser(ctx, user)
+	return s.PatchUser(ctx, user.Id, user.Version, func(current domain.User) (domain.User, error) {
+		current.FirstName = user.FirstName
+		current.LastName = user.LastName
+		current.Email = user.Email
+		current.Age = user.Age
+		return current, nil
+	})
+}
+
+// PatchUser applies patch to the stored user if it still has the given version.
+// The patched user is validated as a whole and only the fields that changed are
+// written, a patch that changes nothing leaves the user and its version as is.
+// The role is not the patch's to change either, see AssignRole.
+func (s UserService) PatchUser(ctx context.Context, id uuid.UUID, version int64, patch func(domain.User) (domain.User, error)) (domain.User, error) {
+	if err := domain.Authorize(ctx, domain.ActionUpdateUser, id); err != nil {
+		return domain.User{}, err
+	}
+	var updated domain.User
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		before, err := s.repo.GetUser(ctx, id)
+		if err != nil {
+			return err
+		}
+		after, fields, err := applyPatch(ctx, before, version, patch)
+		if err != nil {
+			return err
+		}
+		if len(fields) == 0 {
+			updated = before
+			return nil
+		}
+		updated, err = s.repo.UpdateUser(ctx, after, fields)
+		if err != nil {
+			return err
+		}
+		if err := s.record(ctx, domain.AuditUpdate, updated.Id, &before, &updated); err != nil {
+			return err
+		}
+		event := domain.NewUserEvent(domain.UserUpdated, updated)
+		event.Payload.ChangedFields = fields
+		return s.outbox.AddOutboxEvent(ctx, event)
+	})
+	return updated, err
+}
+
+// applyPatch patches the stored user and returns its new state along with the
+// fields that changed, checking the version and the email permission
+func applyPatch(ctx context.Context, before domain.User, version int64, patch func(domain.User) (domain.User, error)) (domain.User, []string, error) {
+	if before.Version != version {
+		return domain.User{}, nil, domain.ErrVersionConflict
+	}
+
+	after, err := patch(before)
+	if err != nil {
+		return domain.User{}, nil, err
+	}
+	// identity and bookkeeping are not the patch's to change
+	after.Id, after.CreatedAt, after.DeletedAt, after.Version = before.Id, before.CreatedAt, before.DeletedAt, before.Version
+	after.Role = before.Role
+	after.Email = domain.NormalizeEmail(after.Email)
+	if err := after.Validate(); err != nil {
+		return domain.User{}, nil, err
+	}
+
+	fields := domain.ChangedUserFields(before, after)
+	if slices.Contains(fields, "email") {
+		if err := domain.Authorize(ctx, domain.ActionChangeEmail, before.Id); err != nil {
+			return domain.User{}, nil, err
+		}
+	}
+	return after, fields, nil
+}
+
+// DeleteUser soft-deletes a user, the record can be brought back with RestoreUser
+func (s UserService) DeleteUser(ctx context.Context, id uuid.UUID) error {
+	if err := domain.Authorize(ctx, domain.ActionDeleteUser, id); err != nil {
+		return err
+	}
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		before, err := s.repo.GetUser(ctx, id)
+		if err != nil {
+			return err
+		}
+		if err := s.repo.DeleteUser(ctx, id); err != nil {
+			return err
+		}
+		after := before
+		after.DeletedAt.Valid = true
+		if err := s.record(ctx, domain.AuditDelete, id, &before, &after); err != nil {
+			return err
+		}
+		return s.outbox.AddOutboxEvent(ctx, domain.NewUserEvent(domain.UserDeleted, before))
+	})
+}
+func (s UserService) RestoreUser(ctx context.Context, id uuid.UUID) (domain.User, error) {
+	if err := domain.Authorize(ctx, domain.ActionDeleteUser, id); err != nil {
+		return domain.User{}, err
+	}
+	var restored domain.User
+	err := s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		var err error
+		restored, err = s.repo.RestoreUser(ctx, id)
+		if err != nil {
+			return err
+		}
+		before := restored
+		before.DeletedAt.Valid = true
+		if err := s.record(ctx, domain.AuditRestore, id, &before, &restored); err != nil {
+			return err
+		}
+		return s.outbox.AddOutboxEvent(ctx, domain.NewUserEvent(domain.UserUpdated, restored))
+	})
+	return restored, err
+}
+
+// PurgeUser removes a user permanently, including soft-deleted ones
+func (s UserService) PurgeUser(ctx context.Context, id uuid.UUID) error {
+	if err := domain.Authorize(ctx, domain.ActionPurgeUser, id); err != nil {
+		return err
+	}
+	return s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		// soft-deleted users cannot be read, their purge is recorded without a diff
+		var before *domain.User
+		purged := domain.User{Id: id}
+		if user, err := s.repo.GetUser(ctx, id); err == nil {
+			before, purged = &user, user
+		}
+		if err := s.repo.PurgeUser(ctx, id); err != nil {
+			return err
+		}
+		if err := s.record(ctx, domain.AuditPurge, id, before, nil); err != nil {
+			return err
+		}
+		event := domain.NewUserEvent(domain.UserDeleted, purged)
+		event.Payload.Purged = true
+		return s.outbox.AddOutboxEvent(ctx, event)
+	})
+}
+
+func (s UserService) ListUsers(ctx context.Context, query domain.ListUsersQuery) (domain.UserPage, error) {
+	if err := domain.Authorize(ctx, domain.ActionListUsers, uuid.Nil); err != nil {
+		return domain.UserPage{}, err
+	}
+	query, err := query.Normalize()
+	if err != nil {
+		return domain.UserPage{}, err
+	}
+	return s.repo.ListUsers(ctx, query)
+}
+
+func (s UserService) SearchUsers(ctx context.Context, query domain.SearchUsersQuery) ([]domain.UserMatch, error) {
+	if err := domain.Authorize(ctx, domain.ActionListUsers, uuid.Nil); err != nil {
+		return nil, err
+	}
+	query, err := query.Normalize()
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.SearchUsers(ctx, query)
+}
+
+// ListAuditRecords returns the change history of a user, newest first
+func (s UserService) ListAuditRecords(ctx context.Context, query domain.AuditQuery) (domain.AuditPage, error) {
+	if err := domain.Authorize(ctx, domain.ActionReadAudit, query.UserId); err != nil {
+		return domain.AuditPage{}, err
+	}
+	query, err := query.Normalize()
+	if err != nil {
+		return domain.AuditPage{}, err
+	}
+	return s.audit.ListAuditRecords(ctx, query)
+}
+
+// AssignRole gives the user a role, only admins may. The change is audited
+// and published like any other update.
+func (s UserService) AssignRole(ctx context.Context, id uuid.UUID, role domain.Role) (domain.User, error) {
+	if err := domain.Authorize(ctx, domain.ActionAssignRole, id); err != nil {
+		return domain.User{}, err
+	}
+	role, err := domain.ParseRole(string(role))
+	if err != nil {
+		return domain.User{}, err
+	}
+	var updated domain.User
+	err = s.tx.WithinTx(ctx, func(ctx context.Context) error {
+		before, err := s.repo.GetUser(ctx, id)
+		if err != nil {
+			return err
+		}
+		if before.Role == role {
+			updated = before
+			return nil
+		}
+		after := before
+		after.Role = role
+		updated, err = s.repo.UpdateUser(ctx, after, []string{"role"})
+		if err != nil {
+			return err
+		}
+		if err := s.record(ctx, domain.AuditRole, id, &before, &updated); err != nil {
+			return err
+		}
+		event := domain.NewUserEvent(domain.UserUpdated, updated)
+		event.Payload.ChangedFields = []string{"role"}
+		return s.outbox.AddOutboxEvent(ctx, event)
+	})
+	return updated, err
+}
+
+// authorizeCreate lets anonymous callers sign up, authenticated ones need
+// domain.ActionCreateUser to create users for others
+func authorizeCreate(ctx context.Context) error {
+	if _, ok := domain.PrincipalFromContext(ctx); !ok {
+		return nil
+	}
+	return domain.Authorize(ctx, domain.ActionCreateUser, uuid.Nil)
+}
+
+func (s UserService) record(ctx context.Context, op domain.AuditOperation, id uuid.UUID, before, after *domain.User) error {
+	meta := domain.RequestMetaFromContext(ctx)
+	return s.audit.CreateAuditRecord(ctx, domain.NewAuditRecord(meta, op, id, before, after))
 }
@@ -0,0 +1,359 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"github.com/vlad19930514/webApp/internal/app/repository/memrepo"
+)
+
+func TestUserService_WithMemRepo(t *testing.T) {
+	service := NewUserService(memrepo.NewUserRepo(), memrepo.NewAuditRepo(), memrepo.NewOutboxRepo(), memrepo.NewTxManager())
+	ctx := adminContext()
+
+	created, err := service.CreateUser(ctx, domain.User{
+		Id:        uuid.New(),
+		FirstName: "Alice",
+		LastName:  "Johnson",
+		Email:     "alice.johnson@example.com",
+		Age:       28,
+	})
+	require.NoError(t, err)
+
+	got, err := service.GetUser(ctx, created.Id)
+	require.NoError(t, err)
+	assert.Equal(t, created, got)
+
+	got.Age = 29
+	updated, err := service.UpdateUser(ctx, got)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(29), updated.Age)
+	assert.Equal(t, created.CreatedAt, updated.CreatedAt)
+	assert.Equal(t, created.Version+1, updated.Version)
+
+	// a second writer holding the old version loses
+	got.Age = 30
+	_, err = service.UpdateUser(ctx, got)
+	assert.ErrorIs(t, err, domain.ErrVersionConflict)
+
+	_, err = service.CreateUser(ctx, domain.User{
+		Id:        uuid.New(),
+		FirstName: "Alice",
+		LastName:  "Smith",
+		Email:     "alice.johnson@example.com",
+		Age:       30,
+	})
+	assert.ErrorIs(t, err, domain.ErrEmailTaken)
+
+	// updating an unknown user does not create it
+	ghost := got
+	ghost.Id = uuid.New()
+	_, err = service.UpdateUser(ctx, ghost)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	_, err = service.GetUser(ctx, ghost.Id)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+type txKey struct{}
+
+// recordingTxManager marks the context so tests can tell whether a repository
+// call ran inside WithinTx
+type recordingTxManager struct {
+	calls int
+}
+
+func (m *recordingTxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	m.calls++
+	return fn(context.WithValue(ctx, txKey{}, true))
+}
+
+type txCheckingRepo struct {
+	*memrepo.UserRepo
+	t *testing.T
+}
+
+func (r txCheckingRepo) UpdateUser(ctx context.Context, user domain.User, fields []string) (domain.User, error) {
+	assert.Equal(r.t, true, ctx.Value(txKey{}), "UpdateUser must run inside a transaction")
+	return r.UserRepo.UpdateUser(ctx, user, fields)
+}
+
+// the domain rules hold for every caller, not only for gin bindings
+func TestUserService_Validation(t *testing.T) {
+	repo := memrepo.NewUserRepo()
+	service := NewUserService(repo, memrepo.NewAuditRepo(), memrepo.NewOutboxRepo(), memrepo.NewTxManager())
+	ctx := adminContext()
+
+	_, err := service.CreateUser(ctx, domain.User{Id: uuid.New(), FirstName: "R2-D2", LastName: "Droid", Email: "r2@example.com", Age: 40})
+	assert.ErrorIs(t, err, domain.ErrValidation)
+	page, err := service.ListUsers(ctx, domain.ListUsersQuery{})
+	require.NoError(t, err)
+	assert.Empty(t, page.Users)
+
+	created, err := service.CreateUser(ctx, domain.User{Id: uuid.New(), FirstName: "Анна", LastName: "O'Brien", Email: "anna@example.com", Age: 40})
+	require.NoError(t, err)
+
+	created.Age = 200
+	_, err = service.UpdateUser(ctx, created)
+	assert.ErrorIs(t, err, domain.ErrValidation)
+	got, err := service.GetUser(ctx, created.Id)
+	require.NoError(t, err)
+	assert.Equal(t, uint8(40), got.Age)
+}
+
+func TestUserService_EmailNormalization(t *testing.T) {
+	service := NewUserService(memrepo.NewUserRepo(), memrepo.NewAuditRepo(), memrepo.NewOutboxRepo(), memrepo.NewTxManager())
+	ctx := adminContext()
+
+	created, err := service.CreateUser(ctx, domain.User{Id: uuid.New(), FirstName: "Bob", LastName: "Smith", Email: " Bob@Bücher.DE ", Age: 40})
+	require.NoError(t, err)
+	assert.Equal(t, "Bob@xn--bcher-kva.de", created.Email)
+
+	got, err := service.GetUserByEmail(ctx, "bob@BÜCHER.de")
+	require.NoError(t, err)
+	assert.Equal(t, created.Id, got.Id)
+
+	_, err = service.CreateUser(ctx, domain.User{Id: uuid.New(), FirstName: "Robert", LastName: "Smith", Email: "BOB@bücher.de", Age: 40})
+	assert.ErrorIs(t, err, domain.ErrEmailTaken)
+}
+
+func TestUserService_UpdateUserWithinTx(t *testing.T) {
+	txManager := &recordingTxManager{}
+	service := NewUserService(txCheckingRepo{UserRepo: memrepo.NewUserRepo(), t: t}, memrepo.NewAuditRepo(), memrepo.NewOutboxRepo(), txManager)
+	ctx := adminContext()
+
+	user, err := service.CreateUser(ctx, domain.User{FirstName: "Bob", LastName: "Brown", Email: "bob@example.com", Age: 33})
+	require.NoError(t, err)
+
+	calls := txManager.calls
+	user.Age = 34
+	_, err = service.UpdateUser(ctx, user)
+	require.NoError(t, err)
+	assert.Equal(t, calls+1, txManager.calls)
+}
+
+func TestUserService_PatchUser(t *testing.T) {
+	service := NewUserService(memrepo.NewUserRepo(), memrepo.NewAuditRepo(), memrepo.NewOutboxRepo(), memrepo.NewTxManager())
+	ctx := adminContext()
+
+	user, err := service.CreateUser(ctx, domain.User{FirstName: "Bob", LastName: "Brown", Email: "bob@example.com", Age: 33})
+	require.NoError(t, err)
+
+	patched, err := service.PatchUser(ctx, user.Id, user.Version, func(u domain.User) (domain.User, error) {
+		u.Age = 34
+		u.Id = uuid.New()
+		return u, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, user.Id, patched.Id)
+	assert.Equal(t, uint8(34), patched.Age)
+	assert.Equal(t, "Brown", patched.LastName)
+	assert.Equal(t, user.Version+1, patched.Version)
+
+	// nothing changed, nothing written
+	same, err := service.PatchUser(ctx, user.Id, patched.Version, func(u domain.User) (domain.User, error) {
+		return u, nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, patched.Version, same.Version)
+	page, err := service.ListAuditRecords(ctx, domain.AuditQuery{UserId: user.Id})
+	require.NoError(t, err)
+	assert.Len(t, page.Records, 2)
+
+	_, err = service.PatchUser(ctx, user.Id, user.Version, func(u domain.User) (domain.User, error) {
+		return u, nil
+	})
+	assert.ErrorIs(t, err, domain.ErrVersionConflict)
+
+	_, err = service.PatchUser(ctx, user.Id, patched.Version, func(u domain.User) (domain.User, error) {
+		u.Email = "not an email"
+		return u, nil
+	})
+	assert.ErrorIs(t, err, domain.ErrValidation)
+
+	errPatch := errors.New("bad patch")
+	_, err = service.PatchUser(ctx, user.Id, patched.Version, func(u domain.User) (domain.User, error) {
+		return u, errPatch
+	})
+	assert.ErrorIs(t, err, errPatch)
+}
+
+func TestUserService_AuditLog(t *testing.T) {
+	service := NewUserService(memrepo.NewUserRepo(), memrepo.NewAuditRepo(), memrepo.NewOutboxRepo(), memrepo.NewTxManager())
+	ctx := domain.ContextWithRequestMeta(adminContext(), domain.RequestMeta{
+		RequestID: "req-1",
+		ClientIP:  "10.0.0.1",
+	})
+
+	user, err := service.CreateUser(ctx, domain.User{FirstName: "Bob", LastName: "Brown", Email: "bob@example.com", Age: 33})
+	require.NoError(t, err)
+
+	user.Email = "robert@example.com"
+	_, err = service.UpdateUser(ctx, user)
+	require.NoError(t, err)
+	require.NoError(t, service.DeleteUser(ctx, user.Id))
+
+	page, err := service.ListAuditRecords(ctx, domain.AuditQuery{UserId: user.Id, Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, page.Records, 2)
+	require.NotEmpty(t, page.NextCursor)
+
+	deleted, updated := page.Records[0], page.Records[1]
+	assert.Equal(t, domain.AuditDelete, deleted.Operation)
+	assert.Equal(t, []domain.FieldChange{{Field: "deleted", Before: false, After: true}}, deleted.Changes)
+
+	assert.Equal(t, domain.AuditUpdate, updated.Operation)
+	assert.Equal(t, "admin", updated.Actor)
+	assert.Equal(t, "req-1", updated.RequestId)
+	assert.Equal(t, "10.0.0.1", updated.ClientIp)
+	assert.Equal(t, []domain.FieldChange{{Field: "email", Before: "bob@example.com", After: "robert@example.com"}}, updated.Changes)
+
+	page, err = service.ListAuditRecords(ctx, domain.AuditQuery{UserId: user.Id, Cursor: page.NextCursor})
+	require.NoError(t, err)
+	require.Len(t, page.Records, 1)
+	assert.Equal(t, domain.AuditCreate, page.Records[0].Operation)
+	assert.Len(t, page.Records[0].Changes, 5)
+	assert.Empty(t, page.NextCursor)
+}
+
+func TestUserService_OutboxEvents(t *testing.T) {
+	outboxRepo := memrepo.NewOutboxRepo()
+	service := NewUserService(memrepo.NewUserRepo(), memrepo.NewAuditRepo(), outboxRepo, memrepo.NewTxManager())
+	ctx := adminContext()
+
+	user, err := service.CreateUser(ctx, domain.User{FirstName: "Bob", LastName: "Brown", Email: "bob@example.com", Age: 33})
+	require.NoError(t, err)
+	user.Age = 34
+	user, err = service.UpdateUser(ctx, user)
+	require.NoError(t, err)
+	require.NoError(t, service.DeleteUser(ctx, user.Id))
+	require.NoError(t, service.PurgeUser(ctx, user.Id))
+
+	events, err := outboxRepo.FetchUnpublished(ctx, 10)
+	require.NoError(t, err)
+	require.Len(t, events, 4)
+
+	types := make([]domain.UserEventType, len(events))
+	for i, event := range events {
+		types[i] = event.Type
+		assert.Equal(t, user.Id, event.AggregateId)
+	}
+	assert.Equal(t, []domain.UserEventType{domain.UserCreated, domain.UserUpdated, domain.UserDeleted, domain.UserDeleted}, types)
+	assert.Equal(t, uint8(34), events[1].Payload.Age)
+	assert.Equal(t, int64(2), events[1].Payload.Version)
+	assert.Equal(t, []string{"age"}, events[1].Payload.ChangedFields)
+	assert.Empty(t, events[0].Payload.ChangedFields)
+	assert.True(t, events[3].Payload.Purged)
+}
+
+// adminContext acts as the admin token, for tests that are not about permissions
+func adminContext() context.Context {
+	return domain.ContextWithPrincipal(context.Background(), domain.AdminTokenPrincipal)
+}
+
+func TestUserService_Permissions(t *testing.T) {
+	audit := memrepo.NewAuditRepo()
+	service := NewUserService(memrepo.NewUserRepo(), audit, memrepo.NewOutboxRepo(), memrepo.NewTxManager())
+	anonymous := context.Background()
+
+	// anyone may sign up, new users are self-service whoever creates them
+	alice, err := service.CreateUser(anonymous, domain.User{FirstName: "Alice", LastName: "Johnson", Email: "alice@example.com", Age: 28})
+	require.NoError(t, err)
+	assert.Equal(t, domain.RoleUser, alice.Role)
+	bob, err := service.CreateUser(adminContext(), domain.User{FirstName: "Bob", LastName: "Brown", Email: "bob@example.com", Age: 33})
+	require.NoError(t, err)
+	assert.Equal(t, domain.RoleUser, bob.Role)
+
+	_, err = service.GetUser(anonymous, alice.Id)
+	assert.ErrorIs(t, err, domain.ErrUnauthenticated)
+
+	t.Run("self-service", func(t *testing.T) {
+		ctx := domain.ContextWithPrincipal(anonymous, domain.Principal{UserId: alice.Id, Role: domain.RoleUser})
+
+		_, err := service.GetUser(ctx, alice.Id)
+		require.NoError(t, err)
+		_, err = service.GetUser(ctx, bob.Id)
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+		_, err = service.GetUserByEmail(ctx, "alice@example.com")
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+		_, err = service.ListUsers(ctx, domain.ListUsersQuery{})
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+		_, err = service.CreateUser(ctx, domain.User{FirstName: "Carol", LastName: "White", Email: "carol@example.com", Age: 40})
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+
+		current, err := service.GetUser(ctx, alice.Id)
+		require.NoError(t, err)
+		current.Email = "alice.j@example.com"
+		alice, err = service.UpdateUser(ctx, current)
+		require.NoError(t, err)
+		assert.Equal(t, "alice.j@example.com", alice.Email)
+
+		bob.Age = 34
+		_, err = service.UpdateUser(ctx, bob)
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+		assert.ErrorIs(t, service.DeleteUser(ctx, alice.Id), domain.ErrForbidden)
+		_, err = service.AssignRole(ctx, alice.Id, domain.RoleAdmin)
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+	})
+
+	t.Run("support", func(t *testing.T) {
+		ctx := domain.ContextWithPrincipal(anonymous, domain.Principal{UserId: uuid.New(), Role: domain.RoleSupport})
+
+		current, err := service.GetUser(ctx, bob.Id)
+		require.NoError(t, err)
+		_, err = service.ListAuditRecords(ctx, domain.AuditQuery{UserId: bob.Id})
+		require.NoError(t, err)
+
+		current.Age = 35
+		current, err = service.UpdateUser(ctx, current)
+		require.NoError(t, err)
+		assert.Equal(t, uint8(35), current.Age)
+
+		current.Email = "robert@example.com"
+		_, err = service.UpdateUser(ctx, current)
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+		assert.ErrorIs(t, service.DeleteUser(ctx, bob.Id), domain.ErrForbidden)
+		assert.ErrorIs(t, service.PurgeUser(ctx, bob.Id), domain.ErrForbidden)
+		_, err = service.AssignRole(ctx, bob.Id, domain.RoleSupport)
+		assert.ErrorIs(t, err, domain.ErrForbidden)
+	})
+
+	t.Run("role assignment", func(t *testing.T) {
+		ctx := adminContext()
+
+		_, err := service.AssignRole(ctx, bob.Id, "owner")
+		assert.ErrorIs(t, err, domain.ErrValidation)
+		_, err = service.AssignRole(ctx, uuid.New(), domain.RoleSupport)
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+
+		before, err := service.GetUser(ctx, bob.Id)
+		require.NoError(t, err)
+		updated, err := service.AssignRole(ctx, bob.Id, domain.RoleSupport)
+		require.NoError(t, err)
+		assert.Equal(t, domain.RoleSupport, updated.Role)
+		assert.Equal(t, before.Version+1, updated.Version)
+
+		// assigning the role a user already has changes nothing
+		again, err := service.AssignRole(ctx, bob.Id, domain.RoleSupport)
+		require.NoError(t, err)
+		assert.Equal(t, updated.Version, again.Version)
+
+		page, err := audit.ListAuditRecords(ctx, domain.AuditQuery{UserId: bob.Id, Limit: 1})
+		require.NoError(t, err)
+		require.Len(t, page.Records, 1)
+		assert.Equal(t, domain.AuditRole, page.Records[0].Operation)
+		assert.Equal(t, "admin", page.Records[0].Actor)
+		assert.Equal(t, []domain.FieldChange{{Field: "role", Before: "user", After: "support"}}, page.Records[0].Changes)
+
+		// a patch cannot change the role back
+		updated.FirstName = "Robert"
+		updated.Role = domain.RoleUser
+		updated, err = service.UpdateUser(ctx, updated)
+		require.NoError(t, err)
+		assert.Equal(t, domain.RoleSupport, updated.Role)
+	})
+}
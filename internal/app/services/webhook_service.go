@@ -0,0 +1,109 @@
+package services
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+// secretSize is the byte length of generated webhook secrets
+const secretSize = 32
+
+// WebhookService manages webhook subscriptions and their delivery log
+type WebhookService struct {
+	repo WebhookRepository
+}
+
+// NewWebhookService creates a new webhook service
+func NewWebhookService(repo WebhookRepository) WebhookService {
+	return WebhookService{repo: repo}
+}
+
+// CreateWebhook stores a new subscription, a random secret is generated when
+// none is given
+func (s WebhookService) CreateWebhook(ctx context.Context, webhook domain.WebhookSubscription) (domain.WebhookSubscription, error) {
+	if webhook.Secret == "" {
+		secret, err := newSecret()
+		if err != nil {
+			return domain.WebhookSubscription{}, err
+		}
+		webhook.Secret = secret
+	}
+	if err := webhook.Validate(); err != nil {
+		return domain.WebhookSubscription{}, err
+	}
+	now := time.Now()
+	webhook.Id = uuid.New()
+	webhook.CreatedAt = now
+	webhook.UpdatedAt = now
+	return s.repo.CreateWebhook(ctx, webhook)
+}
+
+func (s WebhookService) GetWebhook(ctx context.Context, id uuid.UUID) (domain.WebhookSubscription, error) {
+	return s.repo.GetWebhook(ctx, id)
+}
+
+func (s WebhookService) ListWebhooks(ctx context.Context) ([]domain.WebhookSubscription, error) {
+	return s.repo.ListWebhooks(ctx)
+}
+
+// UpdateWebhook replaces the subscription settings, an empty secret keeps the current one
+func (s WebhookService) UpdateWebhook(ctx context.Context, webhook domain.WebhookSubscription) (domain.WebhookSubscription, error) {
+	current, err := s.repo.GetWebhook(ctx, webhook.Id)
+	if err != nil {
+		return domain.WebhookSubscription{}, err
+	}
+	if webhook.Secret == "" {
+		webhook.Secret = current.Secret
+	}
+	if err := webhook.Validate(); err != nil {
+		return domain.WebhookSubscription{}, err
+	}
+	webhook.CreatedAt = current.CreatedAt
+	webhook.UpdatedAt = time.Now()
+	return s.repo.UpdateWebhook(ctx, webhook)
+}
+
+// DeleteWebhook removes the subscription together with its delivery log
+func (s WebhookService) DeleteWebhook(ctx context.Context, id uuid.UUID) error {
+	return s.repo.DeleteWebhook(ctx, id)
+}
+
+// ListWebhookDeliveries returns a page of the delivery log of a subscription, newest first
+func (s WebhookService) ListWebhookDeliveries(ctx context.Context, query domain.DeliveryQuery) (domain.DeliveryPage, error) {
+	if _, err := s.repo.GetWebhook(ctx, query.SubscriptionId); err != nil {
+		return domain.DeliveryPage{}, err
+	}
+	query, err := query.Normalize()
+	if err != nil {
+		return domain.DeliveryPage{}, err
+	}
+	return s.repo.ListWebhookDeliveries(ctx, query)
+}
+
+// RedeliverWebhook queues a delivery for an immediate attempt, dead deliveries
+// get a fresh set of retries
+func (s WebhookService) RedeliverWebhook(ctx context.Context, webhookID uuid.UUID, deliveryID int64) (domain.WebhookDelivery, error) {
+	delivery, err := s.repo.GetWebhookDelivery(ctx, webhookID, deliveryID)
+	if err != nil {
+		return domain.WebhookDelivery{}, err
+	}
+	delivery.Redeliver(time.Now())
+	if err := s.repo.UpdateWebhookDelivery(ctx, delivery); err != nil {
+		return domain.WebhookDelivery{}, err
+	}
+	return delivery, nil
+}
+
+func newSecret() (string, error) {
+	b := make([]byte, secretSize)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate webhook secret: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
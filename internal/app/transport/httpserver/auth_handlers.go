@@ -0,0 +1,129 @@
+package httpserver
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+type loginRequest struct {
+	Email    string `json:"email" binding:"required,email"`
+	Password string `json:"password" binding:"required,max=128"`
+}
+
+func (server *HttpServer) login(ctx *gin.Context) {
+	var req loginRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeBindError(ctx, err)
+		return
+	}
+
+	pair, err := server.auth.Login(ctx, req.Email, req.Password)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, newTokenResponse(pair, time.Now()))
+}
+
+type refreshRequest struct {
+	RefreshToken string `json:"refresh_token" binding:"required,max=256"`
+}
+
+// refreshTokens rotates the refresh token, the one sent is no longer valid afterwards
+func (server *HttpServer) refreshTokens(ctx *gin.Context) {
+	var req refreshRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeBindError(ctx, err)
+		return
+	}
+
+	pair, err := server.auth.Refresh(ctx, req.RefreshToken)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.Header("Cache-Control", "no-store")
+	ctx.JSON(http.StatusOK, newTokenResponse(pair, time.Now()))
+}
+
+type listSessionsResponse struct {
+	Sessions []sessionResponse `json:"sessions"`
+}
+
+func (server *HttpServer) listSessions(ctx *gin.Context) {
+	sessions, err := server.auth.ListSessions(ctx)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	principal, _ := domain.PrincipalFromContext(ctx)
+	ctx.JSON(http.StatusOK, listSessionsResponse{Sessions: newSessionResponses(sessions, principal.SessionId)})
+}
+
+func (server *HttpServer) revokeSession(ctx *gin.Context) {
+	id, ok := bindUserID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := server.auth.RevokeSession(ctx, id); err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+type revokeSessionsResponse struct {
+	Revoked int64 `json:"revoked"`
+}
+
+// revokeUserSessions signs a user out everywhere, e.g. after an account takeover
+func (server *HttpServer) revokeUserSessions(ctx *gin.Context) {
+	id, ok := bindUserID(ctx)
+	if !ok {
+		return
+	}
+
+	revoked, err := server.auth.RevokeUserSessions(ctx, id)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, revokeSessionsResponse{Revoked: revoked})
+}
+
+type setPasswordRequest struct {
+	// CurrentPassword confirms a change of one's own password, admins leave it out
+	CurrentPassword string `json:"current_password" binding:"max=128"`
+	Password        string `json:"password" binding:"required,min=8,max=128"`
+}
+
+func (server *HttpServer) setPassword(ctx *gin.Context) {
+	id, ok := bindUserID(ctx)
+	if !ok {
+		return
+	}
+	var req setPasswordRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeBindError(ctx, err)
+		return
+	}
+
+	if err := server.auth.SetPassword(ctx, id, req.CurrentPassword, req.Password); err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+// registerUser creates a user who can log in with the password right away
+func (server *HttpServer) registerUser(ctx *gin.Context, user domain.User, password string) (domain.User, error) {
+	if server.auth == nil || password == "" {
+		return server.userService.CreateUser(ctx, user)
+	}
+	return server.auth.Register(ctx, user, password)
+}
@@ -0,0 +1,211 @@
+package httpserver
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"github.com/vlad19930514/webApp/internal/app/transport/httpserver/mocks"
+)
+
+func TestLogin(t *testing.T) {
+	authService := mocks.NewMockIAuthService(gomock.NewController(t))
+	server, _ := newMockServer(t, Options{Auth: authService})
+
+	authService.EXPECT().
+		Login(gomock.Any(), "alice@example.com", "correct horse").
+		Return(domain.TokenPair{
+			Access:       domain.AccessToken{Token: "signed", ExpiresAt: time.Now().Add(15 * time.Minute)},
+			RefreshToken: "refresh",
+		}, nil)
+	authService.EXPECT().
+		Login(gomock.Any(), "alice@example.com", "wrong horse").
+		Return(domain.TokenPair{}, domain.ErrInvalidCredentials)
+
+	req := httptest.NewRequest("POST", "/v1/auth/login", bytes.NewBufferString(`{"email":"alice@example.com","password":"correct horse"}`))
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "no-store", w.Header().Get("Cache-Control"))
+	var body tokenResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "signed", body.AccessToken)
+	assert.Equal(t, "Bearer", body.TokenType)
+	assert.Equal(t, int64(900), body.ExpiresIn)
+	assert.Equal(t, "refresh", body.RefreshToken)
+
+	req = httptest.NewRequest("POST", "/v1/auth/login", bytes.NewBufferString(`{"email":"alice@example.com","password":"wrong horse"}`))
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+	assert.Equal(t, "Bearer", w.Header().Get("WWW-Authenticate"))
+	var problemBody problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problemBody))
+	assert.Equal(t, codeInvalidCredentials, problemBody.Code)
+}
+
+func TestAuthenticate(t *testing.T) {
+	authService := mocks.NewMockIAuthService(gomock.NewController(t))
+	server, users := newMockServer(t, Options{Auth: authService})
+
+	principal := domain.Principal{UserId: uuid.New()}
+	authService.EXPECT().Authenticate(gomock.Any(), "good").Return(principal, nil)
+	authService.EXPECT().Authenticate(gomock.Any(), "expired").Return(domain.Principal{}, domain.ErrUnauthenticated)
+	users.EXPECT().
+		GetUser(gomock.Any(), principal.UserId).
+		DoAndReturn(func(ctx context.Context, id uuid.UUID) (domain.User, error) {
+			got, ok := domain.PrincipalFromContext(ctx)
+			assert.True(t, ok)
+			assert.Equal(t, principal, got)
+			assert.Equal(t, principal.Actor(), domain.RequestMetaFromContext(ctx).Actor)
+			return domain.User{Id: id, Version: 1}, nil
+		})
+
+	get := func(authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", "/v1/user/"+principal.UserId.String(), nil)
+		req.Header.Set("Authorization", authorization)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+	assert.Less(t, get("Bearer good").Code, 300)
+	assert.Equal(t, http.StatusUnauthorized, get("Bearer expired").Code)
+	assert.Equal(t, http.StatusUnauthorized, get("Basic YWxpY2U6cGFzcw==").Code)
+}
+
+func TestCreateUser_WithPassword(t *testing.T) {
+	authService := mocks.NewMockIAuthService(gomock.NewController(t))
+	server, _ := newMockServer(t, Options{Auth: authService})
+
+	authService.EXPECT().
+		Register(gomock.Any(), gomock.Any(), "correct horse").
+		DoAndReturn(func(_ context.Context, user domain.User, _ string) (domain.User, error) {
+			user.Version = 1
+			return user, nil
+		})
+
+	req := httptest.NewRequest("POST", "/v1/user", bytes.NewBufferString(
+		`{"first_name":"Alice","last_name":"Johnson","email":"alice@example.com","age":28,"password":"correct horse"}`))
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "correct horse")
+}
+
+func TestRefreshTokens(t *testing.T) {
+	authService := mocks.NewMockIAuthService(gomock.NewController(t))
+	server, _ := newMockServer(t, Options{Auth: authService})
+
+	authService.EXPECT().
+		Refresh(gomock.Any(), "old").
+		Return(domain.TokenPair{Access: domain.AccessToken{Token: "signed", ExpiresAt: time.Now().Add(time.Minute)}, RefreshToken: "new"}, nil)
+	authService.EXPECT().
+		Refresh(gomock.Any(), "old").
+		Return(domain.TokenPair{}, domain.ErrRefreshTokenReused)
+
+	refresh := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/v1/auth/refresh", bytes.NewBufferString(`{"refresh_token":"old"}`))
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+	w := refresh()
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Contains(t, w.Body.String(), `"refresh_token":"new"`)
+	assert.Equal(t, http.StatusUnauthorized, refresh().Code)
+}
+
+func TestSessions(t *testing.T) {
+	authService := mocks.NewMockIAuthService(gomock.NewController(t))
+	server, _ := newMockServer(t, Options{Auth: authService})
+
+	principal := domain.Principal{UserId: uuid.New(), SessionId: uuid.New()}
+	other := uuid.New()
+	authService.EXPECT().Authenticate(gomock.Any(), "good").Return(principal, nil).Times(2)
+	authService.EXPECT().
+		ListSessions(gomock.Any()).
+		Return([]domain.Session{{Id: principal.SessionId, UserAgent: "laptop"}, {Id: other, UserAgent: "phone"}}, nil)
+	authService.EXPECT().RevokeSession(gomock.Any(), other).Return(nil)
+
+	send := func(method, path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, nil)
+		req.Header.Set("Authorization", "Bearer good")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+	w := send("GET", "/v1/me/sessions")
+	require.Equal(t, http.StatusOK, w.Code)
+	var body listSessionsResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Sessions, 2)
+	assert.True(t, body.Sessions[0].Current)
+	assert.False(t, body.Sessions[1].Current)
+
+	assert.Equal(t, http.StatusNoContent, send("DELETE", "/v1/me/sessions/"+other.String()).Code)
+}
+
+func TestRevokeUserSessions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	authService := mocks.NewMockIAuthService(ctrl)
+	server := newTestServer(t, mocks.NewMockIUserService(ctrl), Options{AdminToken: testAdminToken, Auth: authService})
+
+	id := uuid.New()
+	authService.EXPECT().
+		RevokeUserSessions(gomock.Any(), id).
+		DoAndReturn(func(ctx context.Context, _ uuid.UUID) (int64, error) {
+			principal, _ := domain.PrincipalFromContext(ctx)
+			assert.Equal(t, domain.RoleAdmin, principal.Role)
+			return 3, nil
+		})
+
+	w := adminRequest(server, "DELETE", "/v1/admin/user/"+id.String()+"/sessions", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"revoked":3}`, w.Body.String())
+}
+
+func TestAssignRole(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	userService := mocks.NewMockIUserService(ctrl)
+	authService := mocks.NewMockIAuthService(ctrl)
+	server := newTestServer(t, userService, Options{AdminToken: testAdminToken, Auth: authService})
+
+	id := uuid.New()
+	userService.EXPECT().
+		AssignRole(gomock.Any(), id, domain.RoleSupport).
+		Return(domain.User{Id: id, Role: domain.RoleSupport, Version: 2}, nil).
+		Times(2)
+
+	w := adminRequest(server, "PUT", "/v1/admin/user/"+id.String()+"/role", `{"role":"support"}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, `"2"`, w.Header().Get("ETag"))
+	var body userResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "support", body.Role)
+
+	w = adminRequest(server, "PUT", "/v1/admin/user/"+id.String()+"/role", `{"role":"owner"}`)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+
+	// users with the admin role get through as well, everyone else is turned away
+	authService.EXPECT().Authenticate(gomock.Any(), "admin").Return(domain.Principal{UserId: uuid.New(), Role: domain.RoleAdmin}, nil)
+	authService.EXPECT().Authenticate(gomock.Any(), "user").Return(domain.Principal{UserId: id, Role: domain.RoleUser}, nil)
+	send := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", "/v1/admin/user/"+id.String()+"/role", bytes.NewBufferString(`{"role":"support"}`))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set(authorizationHeader, "Bearer "+token)
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w
+	}
+	assert.Equal(t, http.StatusOK, send("admin").Code)
+	assert.Equal(t, http.StatusForbidden, send("user").Code)
+}
@@ -0,0 +1,189 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+const (
+	DefaultMaxBatchSize = 100
+	// maxBatchItemSize bounds the body of a batch request to this many bytes
+	// per allowed item
+	maxBatchItemSize = 4 << 10
+)
+
+var errBatchTooLarge = errors.New("too many items in the batch")
+
+// usersAction serves the custom methods POST /users:<method>. gin cannot
+// register a literal colon, so the route is a "users:action" wildcard and the
+// parameter holds the colon and the method name.
+func (server *HttpServer) usersAction(ctx *gin.Context) {
+	switch ctx.Param("action") {
+	case ":batchCreate":
+		server.batchCreateUsers(ctx)
+	case ":batchGet":
+		server.batchGetUsers(ctx)
+	case ":batchUpdate":
+		server.batchUpdateUsers(ctx)
+	default:
+		server.routeNotFound(ctx)
+	}
+}
+
+type batchCreateUsersRequest struct {
+	Mode domain.BatchMode `json:"mode" binding:"omitempty,oneof=atomic best_effort"`
+	// Users are decoded by the codec of the version, see userCodec.newCreateRequest
+	Users []json.RawMessage `json:"users" binding:"required,min=1"`
+}
+
+// batchCreateUsers validates every item with the domain rules, in best effort
+// mode an invalid item gets its own error instead of failing the request
+func (server *HttpServer) batchCreateUsers(ctx *gin.Context) {
+	var req batchCreateUsersRequest
+	if !server.bindBatch(ctx, &req) {
+		return
+	}
+	if err := server.checkBatchSize(len(req.Users)); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	codec := codecFrom(ctx)
+	users := make([]domain.User, len(req.Users))
+	for i, item := range req.Users {
+		fields := codec.newCreateRequest()
+		if err := json.Unmarshal(item, fields); err != nil {
+			writeBindError(ctx, fmt.Errorf("users[%d]: %w", i, err))
+			return
+		}
+		users[i] = fields.user()
+	}
+	results, err := server.userService.CreateUsers(ctx, users, batchMode(req.Mode))
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, batchResponse{Results: newUserResultResponses(ctx, results)})
+}
+
+type batchGetUsersRequest struct {
+	IDs []uuid.UUID `json:"ids" binding:"required,min=1"`
+}
+
+func (server *HttpServer) batchGetUsers(ctx *gin.Context) {
+	var req batchGetUsersRequest
+	if !server.bindBatch(ctx, &req) {
+		return
+	}
+	if err := server.checkBatchSize(len(req.IDs)); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	results, err := server.userService.GetUsers(ctx, req.IDs)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, batchResponse{Results: newUserResultResponses(ctx, results)})
+}
+
+// batchUpdateItem carries a JSON Merge Patch of one user and the version it applies to
+type batchUpdateItem struct {
+	ID      uuid.UUID       `json:"id" binding:"required"`
+	Version int64           `json:"version" binding:"required,min=1"`
+	Patch   json.RawMessage `json:"patch" binding:"required"`
+}
+
+type batchUpdateUsersRequest struct {
+	Mode    domain.BatchMode  `json:"mode" binding:"omitempty,oneof=atomic best_effort"`
+	Updates []batchUpdateItem `json:"updates" binding:"required,min=1,dive"`
+}
+
+func (server *HttpServer) batchUpdateUsers(ctx *gin.Context) {
+	var req batchUpdateUsersRequest
+	if !server.bindBatch(ctx, &req) {
+		return
+	}
+	if err := server.checkBatchSize(len(req.Updates)); err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	patches := make([]domain.UserPatch, len(req.Updates))
+	for i, item := range req.Updates {
+		apply, err := newUserPatcher(mergePatchContentType, item.Patch)
+		patches[i] = domain.UserPatch{
+			Id:      item.ID,
+			Version: item.Version,
+			Apply: func(current domain.User) (domain.User, error) {
+				// a malformed patch fails its own item only
+				if err != nil {
+					return domain.User{}, err
+				}
+				return applyUserPatch(codecFrom(ctx), current, apply)
+			},
+		}
+	}
+	results, err := server.userService.UpdateUsers(ctx, patches, batchMode(req.Mode))
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, batchResponse{Results: newUserResultResponses(ctx, results)})
+}
+
+// bindBatch decodes the request into req and writes the error response when
+// it fails. The body is capped first, so a batch far over the limit is turned
+// away without being parsed.
+func (server *HttpServer) bindBatch(ctx *gin.Context, req any) bool {
+	limit := int64(server.maxBatchSize) * maxBatchItemSize
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, limit)
+	err := ctx.ShouldBindJSON(req)
+	if tooLarge := new(http.MaxBytesError); errors.As(err, &tooLarge) {
+		writeError(ctx, withDetail(errRequestTooLarge, "at most %d bytes are allowed in a batch", limit))
+		return false
+	}
+	if err != nil {
+		writeBindError(ctx, err)
+		return false
+	}
+	return true
+}
+
+func (server *HttpServer) checkBatchSize(n int) error {
+	if n > server.maxBatchSize {
+		return withDetail(errBatchTooLarge, "%d items, at most %d are allowed", n, server.maxBatchSize)
+	}
+	return nil
+}
+
+// batchMode defaults to all or nothing, partial success has to be asked for
+func batchMode(mode domain.BatchMode) domain.BatchMode {
+	if mode == "" {
+		return domain.BatchAtomic
+	}
+	return mode
+}
+
+func newUserResultResponses(ctx *gin.Context, results []domain.UserResult) []userResultResponse {
+	out := make([]userResultResponse, len(results))
+	for i, result := range results {
+		out[i].Index = i
+		if result.Err != nil {
+			p := errorProblem(ctx, result.Err)
+			out[i].Status = p.Status
+			out[i].Error = &p
+			continue
+		}
+		out[i].Status = http.StatusOK
+		out[i].User = codecFrom(ctx).encodeUser(result.User)
+	}
+	return out
+}
@@ -0,0 +1,195 @@
+package httpserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+// batchResponseV1 decodes a batchResponse holding v1 users
+type batchResponseV1 struct {
+	Results []struct {
+		Index  int           `json:"index"`
+		Status int           `json:"status"`
+		User   *userResponse `json:"user"`
+		Error  *problem      `json:"error"`
+	} `json:"results"`
+}
+
+func postBatch(server HttpServer, path string, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("POST", path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestBatchCreateUsers(t *testing.T) {
+	server, mockUserService := newMockServer(t, Options{})
+
+	created := domain.User{Id: uuid.New(), FirstName: "Alice", LastName: "Johnson", Email: "alice@example.com", Age: 28, Version: 1}
+	mockUserService.EXPECT().
+		CreateUsers(gomock.Any(), gomock.Len(2), domain.BatchBestEffort).
+		DoAndReturn(func(_ any, users []domain.User, _ domain.BatchMode) ([]domain.UserResult, error) {
+			assert.Equal(t, "alice@example.com", users[0].Email)
+			return []domain.UserResult{{User: created}, {Err: domain.ErrEmailTaken}}, nil
+		})
+
+	w := postBatch(server, "/v1/users:batchCreate", `{"mode":"best_effort","users":[
+		{"first_name":"Alice","last_name":"Johnson","email":"alice@example.com","age":28},
+		{"first_name":"Bob","last_name":"Brown","email":"bob@example.com","age":33}]}`)
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var body batchResponseV1
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Results, 2)
+	assert.Equal(t, http.StatusOK, body.Results[0].Status)
+	require.NotNil(t, body.Results[0].User)
+	assert.Equal(t, created.Id, body.Results[0].User.ID)
+	assert.Nil(t, body.Results[0].Error)
+	assert.Equal(t, 1, body.Results[1].Index)
+	assert.Equal(t, http.StatusConflict, body.Results[1].Status)
+	require.NotNil(t, body.Results[1].Error)
+	assert.Equal(t, codeEmailTaken, body.Results[1].Error.Code)
+}
+
+func TestBatchCreateUsers_Atomic(t *testing.T) {
+	server, mockUserService := newMockServer(t, Options{})
+
+	// the mode defaults to atomic and a failed item fails the request
+	mockUserService.EXPECT().
+		CreateUsers(gomock.Any(), gomock.Any(), domain.BatchAtomic).
+		Return(nil, &domain.BatchItemError{Index: 1, Err: domain.ErrEmailTaken})
+
+	w := postBatch(server, "/v1/users:batchCreate", `{"users":[
+		{"first_name":"Alice","last_name":"Johnson","email":"alice@example.com","age":28},
+		{"first_name":"Bob","last_name":"Brown","email":"alice@example.com","age":33}]}`)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+}
+
+func TestBatchGetUsers(t *testing.T) {
+	server, mockUserService := newMockServer(t, Options{})
+
+	found := domain.User{Id: uuid.New(), FirstName: "Alice", Version: 1}
+	missing := uuid.New()
+	mockUserService.EXPECT().
+		GetUsers(gomock.Any(), []uuid.UUID{found.Id, missing}).
+		Return([]domain.UserResult{{User: found}, {Err: domain.ErrNotFound}}, nil)
+
+	w := postBatch(server, "/v1/users:batchGet", fmt.Sprintf(`{"ids":[%q,%q]}`, found.Id, missing))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var body batchResponseV1
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Results, 2)
+	assert.Equal(t, found.Id, body.Results[0].User.ID)
+	assert.Equal(t, http.StatusNotFound, body.Results[1].Status)
+}
+
+func TestBatchUpdateUsers(t *testing.T) {
+	server, mockUserService := newMockServer(t, Options{})
+
+	stored := domain.User{Id: uuid.New(), FirstName: "Alice", LastName: "Johnson", Email: "alice@example.com", Age: 28, Version: 3}
+	other := uuid.New()
+	mockUserService.EXPECT().
+		UpdateUsers(gomock.Any(), gomock.Len(2), domain.BatchBestEffort).
+		DoAndReturn(func(_ any, patches []domain.UserPatch, _ domain.BatchMode) ([]domain.UserResult, error) {
+			assert.Equal(t, stored.Id, patches[0].Id)
+			assert.Equal(t, int64(3), patches[0].Version)
+			patched, err := patches[0].Apply(stored)
+			require.NoError(t, err)
+			assert.Equal(t, uint8(29), patched.Age)
+			assert.Equal(t, "Johnson", patched.LastName)
+
+			// the malformed patch fails only its own item
+			_, err = patches[1].Apply(stored)
+			assert.ErrorIs(t, err, errInvalidPatch)
+			return []domain.UserResult{{User: patched}, {Err: err}}, nil
+		})
+
+	w := postBatch(server, "/v1/users:batchUpdate", fmt.Sprintf(`{"mode":"best_effort","updates":[
+		{"id":%q,"version":3,"patch":{"age":29}},
+		{"id":%q,"version":1,"patch":[1]}]}`, stored.Id, other))
+	require.Equal(t, http.StatusOK, w.Code)
+
+	var body batchResponseV1
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Results, 2)
+	assert.Equal(t, uint8(29), body.Results[0].User.Age)
+	assert.Equal(t, http.StatusBadRequest, body.Results[1].Status)
+}
+
+func TestBatch_RejectedRequests(t *testing.T) {
+	server, _ := newMockServer(t, Options{MaxBatchSize: 2})
+
+	tests := []struct {
+		name           string
+		path           string
+		body           string
+		expectedStatus int
+		expectedCode   string
+		expectedDetail string
+	}{
+		{
+			name:           "too many items",
+			path:           "/v1/users:batchGet",
+			body:           fmt.Sprintf(`{"ids":[%q,%q,%q]}`, uuid.New(), uuid.New(), uuid.New()),
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedCode:   codeBatchTooLarge,
+			expectedDetail: "too many items in the batch: 3 items, at most 2 are allowed",
+		},
+		{
+			name:           "body over the limit",
+			path:           "/v1/users:batchUpdate",
+			body:           `{"updates":[{"patch":{"first_name":"` + strings.Repeat("a", 2*maxBatchItemSize) + `"}}]}`,
+			expectedStatus: http.StatusRequestEntityTooLarge,
+			expectedCode:   codeRequestTooLarge,
+			expectedDetail: "request body is too large: at most 8192 bytes are allowed in a batch",
+		},
+		{
+			name:           "empty batch",
+			path:           "/v1/users:batchGet",
+			body:           `{"ids":[]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   codeInvalidRequest,
+		},
+		{
+			name:           "unknown mode",
+			path:           "/v1/users:batchCreate",
+			body:           `{"mode":"sometimes","users":[{"first_name":"Alice"}]}`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   codeInvalidRequest,
+		},
+		{
+			name:           "unknown method",
+			path:           "/v1/users:batchDelete",
+			body:           `{}`,
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   codeRouteNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := postBatch(server, tt.path, tt.body)
+			assert.Equal(t, tt.expectedStatus, w.Code)
+			var body problem
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, tt.expectedCode, body.Code)
+			if tt.expectedDetail != "" {
+				assert.Equal(t, tt.expectedDetail, body.Detail)
+			}
+		})
+	}
+}
@@ -0,0 +1,292 @@
+package httpserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+// The v1 wire format. Handlers never serialize domain types directly, so the
+// domain can change without breaking clients.
+
+// userCodecV1 is the v1 wire format of users
+type userCodecV1 struct{}
+
+func (userCodecV1) encodeUser(u domain.User) any {
+	return newUserResponse(u)
+}
+
+func (userCodecV1) decodeUser(doc []byte) (domain.User, error) {
+	var out userResponse
+	decoder := json.NewDecoder(bytes.NewReader(doc))
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&out); err != nil {
+		return domain.User{}, err
+	}
+	return domain.User{
+		Id:        out.ID,
+		FirstName: out.FirstName,
+		LastName:  out.LastName,
+		Email:     out.Email,
+		Age:       out.Age,
+		Role:      domain.Role(out.Role),
+		CreatedAt: out.CreatedAt,
+		Version:   out.Version,
+	}, nil
+}
+
+func (userCodecV1) newCreateRequest() createRequest {
+	return &createUserRequest{}
+}
+
+type createUserRequest struct {
+	FirstName string `json:"first_name" binding:"required,name,max=100"`
+	LastName  string `json:"last_name" binding:"required,name,max=100"`
+	Email     string `json:"email" binding:"required,email"`
+	Age       uint8  `json:"age" binding:"required,min=1,max=130"`
+	// Password is optional, users created without one cannot log in
+	Password string `json:"password" binding:"omitempty,min=8,max=128"`
+}
+
+// UnmarshalJSON still accepts firstname and lastname, the names v1 clients
+// were written against before the snake_case ones. The new names win when a
+// body has both.
+func (r *createUserRequest) UnmarshalJSON(data []byte) error {
+	type fields createUserRequest
+	var body struct {
+		fields
+		LegacyFirstName string `json:"firstname"`
+		LegacyLastName  string `json:"lastname"`
+	}
+	if err := json.Unmarshal(data, &body); err != nil {
+		return err
+	}
+	*r = createUserRequest(body.fields)
+	if r.FirstName == "" {
+		r.FirstName = body.LegacyFirstName
+	}
+	if r.LastName == "" {
+		r.LastName = body.LegacyLastName
+	}
+	return nil
+}
+
+func (r *createUserRequest) user() domain.User {
+	return domain.User{FirstName: r.FirstName, LastName: r.LastName, Email: r.Email, Age: r.Age}
+}
+
+func (r *createUserRequest) password() string {
+	return r.Password
+}
+
+type userResponse struct {
+	ID        uuid.UUID `json:"id"`
+	FirstName string    `json:"first_name"`
+	LastName  string    `json:"last_name"`
+	Email     string    `json:"email"`
+	Age       uint8     `json:"age"`
+	Role      string    `json:"role"`
+	CreatedAt time.Time `json:"created_at"`
+	Version   int64     `json:"version"`
+}
+
+func newUserResponse(u domain.User) userResponse {
+	return userResponse{
+		ID:        u.Id,
+		FirstName: u.FirstName,
+		LastName:  u.LastName,
+		Email:     u.Email,
+		Age:       u.Age,
+		Role:      string(u.Role),
+		CreatedAt: u.CreatedAt,
+		Version:   u.Version,
+	}
+}
+
+type userMatchResponse struct {
+	User  any     `json:"user"`
+	Score float64 `json:"score"`
+}
+
+func newUserMatchResponses(codec userCodec, matches []domain.UserMatch) []userMatchResponse {
+	out := make([]userMatchResponse, len(matches))
+	for i, m := range matches {
+		out[i] = userMatchResponse{User: codec.encodeUser(m.User), Score: m.Score}
+	}
+	return out
+}
+
+type fieldChangeResponse struct {
+	Field  string `json:"field"`
+	Before any    `json:"before"`
+	After  any    `json:"after"`
+}
+
+type auditRecordResponse struct {
+	ID        uuid.UUID             `json:"id"`
+	UserID    uuid.UUID             `json:"user_id"`
+	Operation string                `json:"operation"`
+	Actor     string                `json:"actor"`
+	RequestID string                `json:"request_id,omitempty"`
+	ClientIP  string                `json:"client_ip,omitempty"`
+	Changes   []fieldChangeResponse `json:"changes"`
+	CreatedAt time.Time             `json:"created_at"`
+}
+
+func newAuditRecordResponses(records []domain.AuditRecord) []auditRecordResponse {
+	out := make([]auditRecordResponse, len(records))
+	for i, r := range records {
+		changes := make([]fieldChangeResponse, len(r.Changes))
+		for j, c := range r.Changes {
+			changes[j] = fieldChangeResponse{Field: c.Field, Before: c.Before, After: c.After}
+		}
+		out[i] = auditRecordResponse{
+			ID:        r.Id,
+			UserID:    r.UserId,
+			Operation: string(r.Operation),
+			Actor:     r.Actor,
+			RequestID: r.RequestId,
+			ClientIP:  r.ClientIp,
+			Changes:   changes,
+			CreatedAt: r.CreatedAt,
+		}
+	}
+	return out
+}
+
+// userResultResponse is one item of a batch response, either user or error is set
+type userResultResponse struct {
+	Index  int      `json:"index"`
+	Status int      `json:"status"`
+	User   any      `json:"user,omitempty"`
+	Error  *problem `json:"error,omitempty"`
+}
+
+type batchResponse struct {
+	Results []userResultResponse `json:"results"`
+}
+
+// webhookResponse leaves the secret out, only the create response carries it
+type webhookResponse struct {
+	ID         uuid.UUID `json:"id"`
+	URL        string    `json:"url"`
+	Secret     string    `json:"secret,omitempty"`
+	EventTypes []string  `json:"event_types"`
+	Fields     []string  `json:"fields"`
+	Active     bool      `json:"active"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+func newWebhookResponse(w domain.WebhookSubscription) webhookResponse {
+	eventTypes := make([]string, len(w.EventTypes))
+	for i, t := range w.EventTypes {
+		eventTypes[i] = string(t)
+	}
+	fields := make([]string, len(w.Fields))
+	copy(fields, w.Fields)
+	return webhookResponse{
+		ID:         w.Id,
+		URL:        w.URL,
+		EventTypes: eventTypes,
+		Fields:     fields,
+		Active:     w.Active,
+		CreatedAt:  w.CreatedAt,
+		UpdatedAt:  w.UpdatedAt,
+	}
+}
+
+func newWebhookResponses(webhooks []domain.WebhookSubscription) []webhookResponse {
+	out := make([]webhookResponse, len(webhooks))
+	for i, w := range webhooks {
+		out[i] = newWebhookResponse(w)
+	}
+	return out
+}
+
+type deliveryResponse struct {
+	ID             int64     `json:"id"`
+	WebhookID      uuid.UUID `json:"webhook_id"`
+	EventID        int64     `json:"event_id"`
+	EventType      string    `json:"event_type"`
+	Status         string    `json:"status"`
+	Attempts       int       `json:"attempts"`
+	NextAttemptAt  time.Time `json:"next_attempt_at"`
+	LastStatusCode int       `json:"last_status_code,omitempty"`
+	LastError      string    `json:"last_error,omitempty"`
+	CreatedAt      time.Time `json:"created_at"`
+	UpdatedAt      time.Time `json:"updated_at"`
+}
+
+func newDeliveryResponse(d domain.WebhookDelivery) deliveryResponse {
+	return deliveryResponse{
+		ID:             d.Id,
+		WebhookID:      d.SubscriptionId,
+		EventID:        d.EventId,
+		EventType:      string(d.EventType),
+		Status:         string(d.Status),
+		Attempts:       d.Attempts,
+		NextAttemptAt:  d.NextAttemptAt,
+		LastStatusCode: d.LastStatusCode,
+		LastError:      d.LastError,
+		CreatedAt:      d.CreatedAt,
+		UpdatedAt:      d.UpdatedAt,
+	}
+}
+
+func newDeliveryResponses(deliveries []domain.WebhookDelivery) []deliveryResponse {
+	out := make([]deliveryResponse, len(deliveries))
+	for i, d := range deliveries {
+		out[i] = newDeliveryResponse(d)
+	}
+	return out
+}
+
+// tokenResponse follows the OAuth 2.0 token response, expires_in is in seconds
+type tokenResponse struct {
+	AccessToken  string    `json:"access_token"`
+	TokenType    string    `json:"token_type"`
+	ExpiresIn    int64     `json:"expires_in"`
+	RefreshToken string    `json:"refresh_token"`
+	SessionID    uuid.UUID `json:"session_id"`
+}
+
+func newTokenResponse(p domain.TokenPair, now time.Time) tokenResponse {
+	return tokenResponse{
+		AccessToken:  p.Access.Token,
+		TokenType:    bearerScheme,
+		ExpiresIn:    int64(p.Access.ExpiresAt.Sub(now).Round(time.Second) / time.Second),
+		RefreshToken: p.RefreshToken,
+		SessionID:    p.Session.Id,
+	}
+}
+
+// sessionResponse is a signed-in device, current marks the one asking
+type sessionResponse struct {
+	ID         uuid.UUID `json:"id"`
+	UserAgent  string    `json:"user_agent,omitempty"`
+	ClientIP   string    `json:"client_ip,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+	LastUsedAt time.Time `json:"last_used_at"`
+	ExpiresAt  time.Time `json:"expires_at"`
+	Current    bool      `json:"current"`
+}
+
+func newSessionResponses(sessions []domain.Session, current uuid.UUID) []sessionResponse {
+	out := make([]sessionResponse, len(sessions))
+	for i, s := range sessions {
+		out[i] = sessionResponse{
+			ID:         s.Id,
+			UserAgent:  s.UserAgent,
+			ClientIP:   s.ClientIp,
+			CreatedAt:  s.CreatedAt,
+			LastUsedAt: s.LastUsedAt,
+			ExpiresAt:  s.ExpiresAt,
+			Current:    s.Id == current,
+		}
+	}
+	return out
+}
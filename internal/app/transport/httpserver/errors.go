@@ -0,0 +1,227 @@
+package httpserver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"github.com/vlad19930514/webApp/util"
+)
+
+// Stable error codes of the API, clients match on these instead of messages
+const (
+	codeInvalidRequest       = "invalid_request"
+	codeInvalidQuery         = "invalid_query"
+	codeInvalidIfMatch       = "invalid_if_match"
+	codeInvalidLastEventID   = "invalid_last_event_id"
+	codeInvalidPatch         = "invalid_patch"
+	codeBatchTooLarge        = "batch_too_large"
+	codeRequestTooLarge      = "request_too_large"
+	codeInvalidIdempotency   = "invalid_idempotency_key"
+	codeIdempotencyReused    = "idempotency_key_reused"
+	codeIdempotencyInFlight  = "idempotency_key_in_flight"
+	codePatchFailed          = "patch_failed"
+	codeUnsupportedMediaType = "unsupported_media_type"
+	codePreconditionRequired = "precondition_required"
+	codeForbidden            = "forbidden"
+	codeUnauthenticated      = "unauthenticated"
+	codeInvalidCredentials   = "invalid_credentials"
+	codeNotFound             = "not_found"
+	codeRouteNotFound        = "route_not_found"
+	codeMethodNotAllowed     = "method_not_allowed"
+	codeEmailTaken           = "email_taken"
+	codeVersionConflict      = "version_conflict"
+	codeConflict             = "conflict"
+	codeValidation           = "validation_failed"
+	codeUnavailable          = "unavailable"
+	codeInternal             = "internal"
+)
+
+const (
+	problemContentType = "application/problem+json"
+	// problemTypeBase prefixes the error code to form the problem type URI
+	problemTypeBase = "/problems/"
+)
+
+type errorMapping struct {
+	err    error
+	status int
+	code   string
+	title  string
+}
+
+// errorMappings is checked in order, more specific errors go first
+var errorMappings = []errorMapping{
+	{domain.ErrNotFound, http.StatusNotFound, codeNotFound, "Resource not found"},
+	{domain.ErrEmailTaken, http.StatusConflict, codeEmailTaken, "Email already taken"},
+	{domain.ErrVersionConflict, http.StatusPreconditionFailed, codeVersionConflict, "Resource was modified"},
+	{domain.ErrConflict, http.StatusConflict, codeConflict, "Conflicting change"},
+	{domain.ErrValidation, http.StatusUnprocessableEntity, codeValidation, "Validation failed"},
+	{domain.ErrInvalidQuery, http.StatusBadRequest, codeInvalidQuery, "Invalid query"},
+	{domain.ErrUnavailable, http.StatusServiceUnavailable, codeUnavailable, "Service unavailable"},
+	{context.DeadlineExceeded, http.StatusServiceUnavailable, codeUnavailable, "Service unavailable"},
+	{errIfMatchRequired, http.StatusPreconditionRequired, codePreconditionRequired, "Precondition required"},
+	{errInvalidIfMatch, http.StatusBadRequest, codeInvalidIfMatch, "Invalid If-Match header"},
+	{errInvalidLastEventID, http.StatusBadRequest, codeInvalidLastEventID, "Invalid Last-Event-ID header"},
+	{errUnsupportedPatch, http.StatusUnsupportedMediaType, codeUnsupportedMediaType, "Unsupported media type"},
+	{errInvalidPatch, http.StatusBadRequest, codeInvalidPatch, "Invalid patch document"},
+	{errPatchFailed, http.StatusUnprocessableEntity, codePatchFailed, "Patch cannot be applied"},
+	{errInvalidIdempotencyKey, http.StatusBadRequest, codeInvalidIdempotency, "Invalid Idempotency-Key header"},
+	{errIdempotencyKeyReused, http.StatusUnprocessableEntity, codeIdempotencyReused, "Idempotency-Key reused"},
+	{errIdempotencyKeyInFlight, http.StatusConflict, codeIdempotencyInFlight, "Request in progress"},
+	{errBatchTooLarge, http.StatusRequestEntityTooLarge, codeBatchTooLarge, "Batch too large"},
+	{errRequestTooLarge, http.StatusRequestEntityTooLarge, codeRequestTooLarge, "Request too large"},
+	{errAdminOnly, http.StatusForbidden, codeForbidden, "Forbidden"},
+	{domain.ErrInvalidCredentials, http.StatusUnauthorized, codeInvalidCredentials, "Invalid credentials"},
+	{domain.ErrUnauthenticated, http.StatusUnauthorized, codeUnauthenticated, "Authentication required"},
+	{domain.ErrForbidden, http.StatusForbidden, codeForbidden, "Forbidden"},
+}
+
+// detailError is an error whose message was written for clients, the problem
+// detail uses it instead of the mapped message
+type detailError struct {
+	err    error
+	detail string
+}
+
+// withDetail adds a client facing detail to err, one of the errorMappings
+func withDetail(err error, format string, args ...any) error {
+	return &detailError{err: err, detail: fmt.Sprintf("%v: %s", err, fmt.Sprintf(format, args...))}
+}
+
+func (e *detailError) Error() string { return e.detail }
+
+func (e *detailError) Unwrap() error { return e.err }
+
+var internalError = errorMapping{status: http.StatusInternalServerError, code: codeInternal, title: "Internal server error"}
+
+// problem is an RFC 7807 problem details object, code, request_id and
+// invalid_params are extension members
+type problem struct {
+	Type          string          `json:"type"`
+	Title         string          `json:"title"`
+	Status        int             `json:"status"`
+	Detail        string          `json:"detail,omitempty"`
+	Instance      string          `json:"instance,omitempty"`
+	Code          string          `json:"code"`
+	RequestID     string          `json:"request_id,omitempty"`
+	InvalidParams []util.ErrorMsg `json:"invalid_params,omitempty"`
+}
+
+func newProblem(ctx *gin.Context, m errorMapping, detail string) problem {
+	return problem{
+		Type:      problemTypeBase + m.code,
+		Title:     m.title,
+		Status:    m.status,
+		Detail:    detail,
+		Instance:  ctx.Request.URL.Path,
+		Code:      m.code,
+		RequestID: domain.RequestMetaFromContext(ctx.Request.Context()).RequestID,
+	}
+}
+
+func writeProblem(ctx *gin.Context, p problem) {
+	ctx.Header("Content-Type", problemContentType)
+	if p.Status == http.StatusUnauthorized {
+		ctx.Header("WWW-Authenticate", bearerScheme)
+	}
+	ctx.AbortWithStatusJSON(p.Status, p)
+}
+
+// writeError is the one place that turns an error into a response. Server
+// side errors are logged and their details are not sent to the client.
+func writeError(ctx *gin.Context, err error) {
+	writeProblem(ctx, errorProblem(ctx, err))
+}
+
+// errorProblem builds the problem for err, batch handlers use it for every failed item
+func errorProblem(ctx *gin.Context, err error) problem {
+	m := internalError
+	for _, candidate := range errorMappings {
+		if errors.Is(err, candidate.err) {
+			m = candidate
+			break
+		}
+	}
+
+	// wrapped errors may carry driver messages, clients only get the mapped one
+	var detail string
+	if m.err != nil {
+		detail = m.err.Error()
+	}
+	var detailErr *detailError
+	if errors.As(err, &detailErr) {
+		detail = detailErr.detail
+	}
+	var invalidParams []util.ErrorMsg
+	var validationErr *domain.ValidationError
+	if errors.As(err, &validationErr) {
+		detail = "the resource breaks domain rules"
+		invalidParams = violationMessages(ctx, validationErr)
+	}
+	var itemErr *domain.BatchItemError
+	if errors.As(err, &itemErr) && validationErr != nil {
+		detail = fmt.Sprintf("item %d: %s", itemErr.Index, detail)
+	}
+	// the full error only goes to the log, client errors at a lower level
+	event := log.Info()
+	if m.status >= http.StatusInternalServerError {
+		event = log.Error()
+		detail = ""
+	}
+	event.Err(err).
+		Str("request_id", domain.RequestMetaFromContext(ctx.Request.Context()).RequestID).
+		Str("path", ctx.Request.URL.Path).
+		Int("status", m.status).
+		Msg("request failed")
+	p := newProblem(ctx, m, detail)
+	p.InvalidParams = invalidParams
+	return p
+}
+
+// violationMessages translates domain rule violations the same way as binding errors
+func violationMessages(ctx *gin.Context, err *domain.ValidationError) []util.ErrorMsg {
+	acceptLanguage := ctx.GetHeader("Accept-Language")
+	out := make([]util.ErrorMsg, len(err.Violations))
+	for i, v := range err.Violations {
+		out[i] = util.ErrorMsg{
+			Field:   v.Field,
+			Tag:     v.Rule,
+			Param:   v.Param,
+			Message: util.ValidationMessage(acceptLanguage, v.Field, v.Rule, v.Param, v.Value),
+		}
+	}
+	return out
+}
+
+// writeBindError answers a request that failed binding, validation failures
+// are listed field by field in invalid_params in the client's language
+func writeBindError(ctx *gin.Context, err error) {
+	m := errorMapping{status: http.StatusBadRequest, code: codeInvalidRequest, title: "Invalid request"}
+	if invalidParams, ok := util.GetValidationErrors(&err, ctx.GetHeader("Accept-Language")); ok {
+		p := newProblem(ctx, m, "request parameters failed validation")
+		p.InvalidParams = invalidParams
+		writeProblem(ctx, p)
+		return
+	}
+	writeProblem(ctx, newProblem(ctx, m, err.Error()))
+}
+
+func (server *HttpServer) routeNotFound(ctx *gin.Context) {
+	m := errorMapping{status: http.StatusNotFound, code: codeRouteNotFound, title: "Route not found"}
+	writeProblem(ctx, newProblem(ctx, m, fmt.Sprintf("no route for %s %s", ctx.Request.Method, ctx.Request.URL.Path)))
+}
+
+func (server *HttpServer) methodNotAllowed(ctx *gin.Context) {
+	m := errorMapping{status: http.StatusMethodNotAllowed, code: codeMethodNotAllowed, title: "Method not allowed"}
+	writeProblem(ctx, newProblem(ctx, m, fmt.Sprintf("%s is not allowed on %s", ctx.Request.Method, ctx.Request.URL.Path)))
+}
+
+// recoverPanic answers a panicking handler with an internal error problem
+func (server *HttpServer) recoverPanic(ctx *gin.Context, recovered any) {
+	writeError(ctx, fmt.Errorf("panic: %v", recovered))
+}
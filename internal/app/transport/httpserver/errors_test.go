@@ -0,0 +1,140 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"github.com/vlad19930514/webApp/internal/app/transport/httpserver/mocks"
+	"github.com/vlad19930514/webApp/util"
+)
+
+func TestWriteError(t *testing.T) {
+	testCases := []struct {
+		name   string
+		err    error
+		status int
+		code   string
+		detail string
+	}{
+		{"not found", fmt.Errorf("failed to get a user: %w", domain.ErrNotFound), http.StatusNotFound, codeNotFound, "not found"},
+		{"email taken", fmt.Errorf("failed to create domain user: %w", domain.ErrEmailTaken), http.StatusConflict, codeEmailTaken, "email already taken"},
+		{"version conflict", domain.ErrVersionConflict, http.StatusPreconditionFailed, codeVersionConflict, "user version conflict"},
+		{"conflict", domain.ErrConflict, http.StatusConflict, codeConflict, "conflict"},
+		{"validation", domain.ErrValidation, http.StatusUnprocessableEntity, codeValidation, "validation failed"},
+		{"unavailable", fmt.Errorf("dial tcp: %w", domain.ErrUnavailable), http.StatusServiceUnavailable, codeUnavailable, ""},
+		{"internal", errors.New("pq: relation does not exist"), http.StatusInternalServerError, codeInternal, ""},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			ctx, _ := gin.CreateTestContext(w)
+			ctx.Request = httptest.NewRequest("GET", "/user/42", nil)
+			ctx.Request = ctx.Request.WithContext(domain.ContextWithRequestMeta(ctx.Request.Context(), domain.RequestMeta{RequestID: "req-1"}))
+
+			writeError(ctx, tc.err)
+
+			assert.Equal(t, tc.status, w.Code)
+			assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+			var body problem
+			require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(t, problemTypeBase+tc.code, body.Type)
+			assert.NotEmpty(t, body.Title)
+			assert.Equal(t, tc.status, body.Status)
+			assert.Equal(t, tc.detail, body.Detail)
+			assert.Equal(t, "/user/42", body.Instance)
+			assert.Equal(t, tc.code, body.Code)
+			assert.Equal(t, "req-1", body.RequestID)
+		})
+	}
+}
+
+func TestWriteError_HidesDriverErrors(t *testing.T) {
+	users := mocks.NewMockIUserService(gomock.NewController(t))
+	server := newTestServer(t, users, Options{})
+
+	// wrapped the way pgrepo translates a unique violation
+	pgErr := &pgconn.PgError{Code: "23505", ConstraintName: "idx_users_email", Message: `duplicate key value violates unique constraint "idx_users_email"`}
+	users.EXPECT().
+		CreateUser(gomock.Any(), gomock.Any()).
+		Return(domain.User{}, fmt.Errorf("failed to create domain user: %w", fmt.Errorf("%w: %w", domain.ErrEmailTaken, pgErr)))
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/v1/user", strings.NewReader(`{"first_name":"Bob","last_name":"Brown","email":"bob@example.com","age":33}`))
+	req.Header.Set("Content-Type", "application/json")
+	server.router.ServeHTTP(w, req)
+
+	assert.Equal(t, http.StatusConflict, w.Code)
+	var body problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, codeEmailTaken, body.Code)
+	assert.Equal(t, "email already taken", body.Detail)
+	assert.NotContains(t, w.Body.String(), "idx_users_email")
+	assert.NotContains(t, w.Body.String(), "SQLSTATE")
+}
+
+func TestWriteBindError(t *testing.T) {
+	type request struct {
+		Email string `json:"email" binding:"required,email"`
+	}
+
+	newTestServer(t, nil, Options{AdminToken: testAdminToken})
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest("POST", "/user", strings.NewReader(`{"email": "nope"}`))
+	ctx.Request.Header.Set("Accept-Language", "en")
+	var req request
+	writeBindError(ctx, ctx.ShouldBindJSON(&req))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	var body problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, codeInvalidRequest, body.Code)
+	require.Len(t, body.InvalidParams, 1)
+	assert.Equal(t, util.ErrorMsg{Field: "email", Tag: "email", Message: `email must be a valid email address, got "nope"`}, body.InvalidParams[0])
+}
+
+func TestUnknownRoute(t *testing.T) {
+	server := newTestServer(t, nil, Options{AdminToken: testAdminToken})
+
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest("GET", "/nowhere", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+	assert.Equal(t, problemContentType, w.Header().Get("Content-Type"))
+
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest("PUT", "/v1/users", nil))
+	assert.Equal(t, http.StatusMethodNotAllowed, w.Code)
+	var body problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, codeMethodNotAllowed, body.Code)
+	assert.NotEmpty(t, body.RequestID)
+}
+
+func TestWriteError_DomainViolations(t *testing.T) {
+	w := httptest.NewRecorder()
+	ctx, _ := gin.CreateTestContext(w)
+	ctx.Request = httptest.NewRequest("POST", "/user", nil)
+	ctx.Request.Header.Set("Accept-Language", "en")
+
+	writeError(ctx, &domain.ValidationError{Violations: []domain.FieldViolation{
+		{Field: "age", Rule: domain.RuleMax, Param: "130", Value: uint8(200)},
+	}})
+
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+	var body problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, codeValidation, body.Code)
+	assert.Equal(t, []util.ErrorMsg{{Field: "age", Tag: "max", Param: "130", Message: "age must be at most 130"}}, body.InvalidParams)
+}
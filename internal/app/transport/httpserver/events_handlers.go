@@ -0,0 +1,109 @@
+package httpserver
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+const (
+	lastEventIDHeader = "Last-Event-ID"
+	// eventKeepAlive keeps proxies from closing a stream that has nothing to send
+	eventKeepAlive = 15 * time.Second
+)
+
+var errInvalidLastEventID = errors.New("Last-Event-ID must be the id of an event")
+
+type streamUserEventsRequest struct {
+	UserIds []string `form:"user_id" binding:"dive,uuid"`
+	Fields  []string `form:"field" binding:"dive,oneof=first_name last_name email age"`
+}
+
+// streamUserEvents sends user events as Server-Sent Events until the client
+// goes away. The SSE id is the outbox event id, a client that reconnects with
+// it in Last-Event-ID gets the events it missed first. Staff get the events of
+// every user, self-service users only their own.
+func (server *HttpServer) streamUserEvents(ctx *gin.Context) {
+	var req streamUserEventsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeBindError(ctx, err)
+		return
+	}
+	filter := domain.UserEventFilter{Fields: req.Fields}
+	for _, id := range req.UserIds {
+		filter.UserIds = append(filter.UserIds, uuid.MustParse(id))
+	}
+	filter, err := authorizeEvents(ctx, filter)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	var lastEventID int64
+	if header := ctx.GetHeader(lastEventIDHeader); header != "" {
+		id, err := strconv.ParseInt(header, 10, 64)
+		if err != nil || id < 0 {
+			writeError(ctx, errInvalidLastEventID)
+			return
+		}
+		lastEventID = id
+	}
+
+	// the subscription outlives this handler's gin.Context, which gets reused
+	events, err := server.events.Subscribe(ctx.Request.Context(), lastEventID)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	// nginx buffers responses unless told otherwise
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Flush()
+
+	keepAlive := time.NewTicker(eventKeepAlive)
+	defer keepAlive.Stop()
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			if filter.Match(event) {
+				ctx.Render(-1, sse.Event{Id: strconv.FormatInt(event.Id, 10), Event: string(event.Type), Data: event})
+			}
+			return true
+		case <-keepAlive.C:
+			_, err := io.WriteString(w, ": keep-alive\n\n")
+			return err == nil
+		}
+	})
+}
+
+// authorizeEvents narrows filter to what the caller may read. Callers who may
+// not list users but may read their own record get only their own events.
+func authorizeEvents(ctx *gin.Context, filter domain.UserEventFilter) (domain.UserEventFilter, error) {
+	err := domain.Authorize(ctx, domain.ActionListUsers, uuid.Nil)
+	if !errors.Is(err, domain.ErrForbidden) {
+		return filter, err
+	}
+	principal, _ := domain.PrincipalFromContext(ctx)
+	if !principal.Can(domain.ActionReadUser, principal.UserId) {
+		return filter, err
+	}
+	for _, id := range filter.UserIds {
+		if id != principal.UserId {
+			return filter, err
+		}
+	}
+	filter.UserIds = []uuid.UUID{principal.UserId}
+	return filter, nil
+}
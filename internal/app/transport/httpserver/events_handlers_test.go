@@ -0,0 +1,200 @@
+package httpserver
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"github.com/vlad19930514/webApp/internal/app/outbox"
+	"github.com/vlad19930514/webApp/internal/app/repository/memrepo"
+	"github.com/vlad19930514/webApp/internal/app/transport/httpserver/mocks"
+)
+
+type sseEvent struct {
+	id    string
+	event string
+	data  string
+}
+
+// readEvent reads the next event off the stream, skipping comments
+func readEvent(t *testing.T, scanner *bufio.Scanner) sseEvent {
+	t.Helper()
+	var event sseEvent
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case line == "" && event.id != "":
+			return event
+		case strings.HasPrefix(line, "id:"):
+			event.id = strings.TrimPrefix(line, "id:")
+		case strings.HasPrefix(line, "event:"):
+			event.event = strings.TrimPrefix(line, "event:")
+		case strings.HasPrefix(line, "data:"):
+			event.data = strings.TrimPrefix(line, "data:")
+		}
+	}
+	t.Fatalf("stream ended: %v", scanner.Err())
+	return event
+}
+
+func newEventsServer(t *testing.T, auth IAuthService) (*httptest.Server, *memrepo.OutboxRepo, *outbox.Broadcaster) {
+	store := memrepo.NewOutboxRepo()
+	broadcaster := outbox.NewBroadcaster(store)
+	options := Options{Events: broadcaster, AdminToken: testAdminToken}
+	if auth != nil {
+		options.Auth = auth
+	}
+	server := newTestServer(t, mocks.NewMockIUserService(gomock.NewController(t)), options)
+	ts := httptest.NewServer(server.router)
+	t.Cleanup(ts.Close)
+	return ts, store, broadcaster
+}
+
+// openStream subscribes with the admin token
+func openStream(t *testing.T, ctx context.Context, url string, lastEventID string) *http.Response {
+	t.Helper()
+	header := http.Header{adminTokenHeader: {testAdminToken}}
+	if lastEventID != "" {
+		header.Set(lastEventIDHeader, lastEventID)
+	}
+	return openStreamWith(t, ctx, url, header)
+}
+
+func openStreamWith(t *testing.T, ctx context.Context, url string, header http.Header) *http.Response {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	require.NoError(t, err)
+	req.Header = header
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func TestStreamUserEvents(t *testing.T) {
+	ts, store, broadcaster := newEventsServer(t, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	alice, bob := domain.User{Id: uuid.New(), FirstName: "Alice"}, domain.User{Id: uuid.New(), FirstName: "Bob"}
+	for _, user := range []domain.User{alice, bob, alice} {
+		require.NoError(t, store.AddOutboxEvent(ctx, domain.NewUserEvent(domain.UserCreated, user)))
+	}
+	stored, err := store.ListOutboxEvents(ctx, 0, 10)
+	require.NoError(t, err)
+
+	// resumes after the first event and only sends alice's events
+	resp := openStream(t, ctx, ts.URL+"/v1/users/events?user_id="+alice.Id.String(), "1")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+	scanner := bufio.NewScanner(resp.Body)
+
+	replayed := readEvent(t, scanner)
+	assert.Equal(t, "3", replayed.id)
+	assert.Equal(t, string(domain.UserCreated), replayed.event)
+
+	update := domain.NewUserEvent(domain.UserUpdated, bob)
+	update.Id = stored[2].Id + 1
+	require.NoError(t, broadcaster.Publish(ctx, update))
+	update = domain.NewUserEvent(domain.UserUpdated, alice)
+	update.Id = stored[2].Id + 2
+	update.Payload.ChangedFields = []string{"age"}
+	require.NoError(t, broadcaster.Publish(ctx, update))
+
+	live := readEvent(t, scanner)
+	assert.Equal(t, "5", live.id)
+	var event domain.OutboxEvent
+	require.NoError(t, json.Unmarshal([]byte(live.data), &event))
+	assert.Equal(t, alice.Id, event.Payload.UserId)
+	assert.Equal(t, []string{"age"}, event.Payload.ChangedFields)
+}
+
+func TestStreamUserEvents_FieldFilter(t *testing.T) {
+	ts, _, broadcaster := newEventsServer(t, nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	resp := openStream(t, ctx, ts.URL+"/v1/users/events?field=email", "")
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	scanner := bufio.NewScanner(resp.Body)
+
+	user := domain.User{Id: uuid.New()}
+	for i, fields := range [][]string{{"age"}, {"first_name", "email"}} {
+		event := domain.NewUserEvent(domain.UserUpdated, user)
+		event.Id = int64(i + 1)
+		event.Payload.ChangedFields = fields
+		require.NoError(t, broadcaster.Publish(ctx, event))
+	}
+	assert.Equal(t, "2", readEvent(t, scanner).id)
+}
+
+func TestStreamUserEvents_BadRequests(t *testing.T) {
+	ts, _, _ := newEventsServer(t, nil)
+	ctx := context.Background()
+
+	tests := []struct {
+		name         string
+		query        string
+		lastEventID  string
+		expectedCode string
+	}{
+		{name: "bad user id", query: "?user_id=nope", expectedCode: codeInvalidRequest},
+		{name: "unknown field", query: "?field=password", expectedCode: codeInvalidRequest},
+		{name: "bad last event id", lastEventID: "abc", expectedCode: codeInvalidLastEventID},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resp := openStream(t, ctx, ts.URL+"/v1/users/events"+tt.query, tt.lastEventID)
+			assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+			var body problem
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+			assert.Equal(t, tt.expectedCode, body.Code)
+		})
+	}
+}
+
+func TestStreamUserEvents_NotMountedWithoutEvents(t *testing.T) {
+	server := newTestServer(t, mocks.NewMockIUserService(gomock.NewController(t)), Options{})
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/users/events", nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+func TestStreamUserEvents_Permissions(t *testing.T) {
+	auth := mocks.NewMockIAuthService(gomock.NewController(t))
+	ts, _, broadcaster := newEventsServer(t, auth)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	alice, bob := domain.User{Id: uuid.New(), FirstName: "Alice"}, domain.User{Id: uuid.New(), FirstName: "Bob"}
+	auth.EXPECT().Authenticate(gomock.Any(), "alice").Return(domain.Principal{UserId: alice.Id, Role: domain.RoleUser}, nil).AnyTimes()
+	asAlice := http.Header{authorizationHeader: {"Bearer alice"}}
+
+	resp := openStreamWith(t, ctx, ts.URL+"/v1/users/events", http.Header{})
+	assert.Equal(t, http.StatusUnauthorized, resp.StatusCode)
+	resp = openStreamWith(t, ctx, ts.URL+"/v1/users/events?user_id="+bob.Id.String(), asAlice)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+
+	// self-service users only get their own events, the admin gets everyone's
+	self := openStreamWith(t, ctx, ts.URL+"/v1/users/events", asAlice)
+	require.Equal(t, http.StatusOK, self.StatusCode)
+	admin := openStream(t, ctx, ts.URL+"/v1/users/events", "")
+	require.Equal(t, http.StatusOK, admin.StatusCode)
+
+	for i, user := range []domain.User{bob, alice} {
+		event := domain.NewUserEvent(domain.UserUpdated, user)
+		event.Id = int64(i + 1)
+		require.NoError(t, broadcaster.Publish(ctx, event))
+	}
+	assert.Equal(t, "2", readEvent(t, bufio.NewScanner(self.Body)).id)
+	assert.Equal(t, "1", readEvent(t, bufio.NewScanner(admin.Body)).id)
+}
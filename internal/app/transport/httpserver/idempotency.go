@@ -0,0 +1,171 @@
+package httpserver
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rs/zerolog/log"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+const (
+	idempotencyKeyHeader     = "Idempotency-Key"
+	idempotentReplayedHeader = "Idempotent-Replayed"
+	maxIdempotencyKeyLength  = 255
+	maxIdempotentBodySize    = 1 << 20 // bodies are buffered to fingerprint them
+	DefaultIdempotencyTTL    = 24 * time.Hour
+)
+
+var (
+	errInvalidIdempotencyKey  = errors.New("Idempotency-Key must be 1 to 255 characters long")
+	errIdempotencyKeyReused   = errors.New("Idempotency-Key was already used for a different request")
+	errIdempotencyKeyInFlight = errors.New("a request with this Idempotency-Key is still being processed")
+	errRequestTooLarge        = errors.New("request body is too large")
+)
+
+// replayedHeaders are saved with the response and sent again on a replay
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// idempotent makes mutations sent with an Idempotency-Key safe to retry. The
+// first request runs and its response is saved, later requests with the same
+// key and payload get that response back until the key expires. Server errors
+// are not saved, the client may retry them with the same key.
+func (server *HttpServer) idempotent(ctx *gin.Context) {
+	key := ctx.GetHeader(idempotencyKeyHeader)
+	if key == "" || !isMutation(ctx.Request.Method) || issuesTokens(ctx) {
+		ctx.Next()
+		return
+	}
+	if len(key) > maxIdempotencyKeyLength {
+		writeError(ctx, errInvalidIdempotencyKey)
+		return
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(ctx.Writer, ctx.Request.Body, maxIdempotentBodySize))
+	if tooLarge := new(http.MaxBytesError); errors.As(err, &tooLarge) {
+		writeError(ctx, withDetail(errRequestTooLarge, "at most %d bytes are allowed with an %s", tooLarge.Limit, idempotencyKeyHeader))
+		return
+	}
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	now := time.Now()
+	record := domain.IdempotencyRecord{
+		Key:         key,
+		Fingerprint: requestFingerprint(ctx.Request, body),
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(server.idempotencyTTL),
+	}
+	stored, reserved, err := server.idempotency.ReserveIdempotencyKey(ctx, record)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	if !reserved {
+		replay(ctx, record, stored)
+		return
+	}
+
+	// the outcome is saved even if the client went away meanwhile
+	storeCtx := context.WithoutCancel(ctx.Request.Context())
+	completed := false
+	defer func() {
+		// a panic or a server error leaves the key free for a retry
+		if !completed {
+			if err := server.idempotency.ReleaseIdempotencyKey(storeCtx, key); err != nil {
+				log.Error().Err(err).Str("idempotency_key", key).Msg("failed to release idempotency key")
+			}
+		}
+	}()
+
+	recorder := &responseRecorder{ResponseWriter: ctx.Writer}
+	ctx.Writer = recorder
+	ctx.Next()
+
+	if recorder.Status() >= http.StatusInternalServerError {
+		return
+	}
+	record.Status = recorder.Status()
+	record.Headers = make(map[string]string, len(replayedHeaders))
+	for _, name := range replayedHeaders {
+		if value := recorder.Header().Get(name); value != "" {
+			record.Headers[name] = value
+		}
+	}
+	record.Body = recorder.body.Bytes()
+	if err := server.idempotency.CompleteIdempotencyKey(storeCtx, record); err != nil {
+		log.Error().Err(err).Str("idempotency_key", key).Msg("failed to save idempotent response")
+		return
+	}
+	completed = true
+}
+
+// replay answers a repeated request with the saved response
+func replay(ctx *gin.Context, record, stored domain.IdempotencyRecord) {
+	switch {
+	case stored.Fingerprint != record.Fingerprint:
+		writeError(ctx, errIdempotencyKeyReused)
+	case !stored.Completed():
+		writeError(ctx, errIdempotencyKeyInFlight)
+	default:
+		for name, value := range stored.Headers {
+			ctx.Header(name, value)
+		}
+		ctx.Header(idempotentReplayedHeader, "true")
+		ctx.Status(stored.Status)
+		_, _ = ctx.Writer.Write(stored.Body)
+		ctx.Abort()
+	}
+}
+
+// requestFingerprint tells requests apart by method, URL, caller and body
+func requestFingerprint(req *http.Request, body []byte) string {
+	hash := sha256.New()
+	hash.Write([]byte(req.Method + " " + req.URL.RequestURI() + "\n"))
+	// a key reused by another caller never replays this caller's response
+	hash.Write([]byte(domain.RequestMetaFromContext(req.Context()).Actor + "\n"))
+	hash.Write(body)
+	return hex.EncodeToString(hash.Sum(nil))
+}
+
+// issuesTokens tells the routes whose responses carry credentials, they are
+// not stored
+func issuesTokens(ctx *gin.Context) bool {
+	path := ctx.FullPath()
+	return strings.HasSuffix(path, "/auth/login") || strings.HasSuffix(path, "/auth/refresh")
+}
+
+func isMutation(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// responseRecorder keeps a copy of the response body while it is written
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
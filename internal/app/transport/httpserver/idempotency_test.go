@@ -0,0 +1,149 @@
+package httpserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"github.com/vlad19930514/webApp/internal/app/repository/memrepo"
+)
+
+func postUser(server HttpServer, key string, input createUserRequest) *httptest.ResponseRecorder {
+	body, _ := json.Marshal(input)
+	req := httptest.NewRequest("POST", "/v1/user", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	if key != "" {
+		req.Header.Set(idempotencyKeyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestIdempotency_Replay(t *testing.T) {
+	server, mockUserService := newMockServer(t, Options{Idempotency: memrepo.NewIdempotencyRepo(), IdempotencyTTL: time.Hour})
+
+	input := createUserRequest{FirstName: "Alice", LastName: "Johnson", Email: "alice@example.com", Age: 28}
+	created := domain.User{Id: uuid.New(), FirstName: "Alice", LastName: "Johnson", Email: "alice@example.com", Age: 28, Version: 1}
+	mockUserService.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Return(created, nil).Times(1)
+
+	first := postUser(server, "key-1", input)
+	require.Equal(t, http.StatusOK, first.Code)
+	assert.Empty(t, first.Header().Get(idempotentReplayedHeader))
+
+	second := postUser(server, "key-1", input)
+	assert.Equal(t, first.Code, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, first.Header().Get("Content-Type"), second.Header().Get("Content-Type"))
+	assert.Equal(t, "true", second.Header().Get(idempotentReplayedHeader))
+
+	// the same key for another payload is a client bug
+	input.Age = 29
+	reused := postUser(server, "key-1", input)
+	assert.Equal(t, http.StatusUnprocessableEntity, reused.Code)
+	var body problem
+	require.NoError(t, json.Unmarshal(reused.Body.Bytes(), &body))
+	assert.Equal(t, codeIdempotencyReused, body.Code)
+}
+
+func TestIdempotency_ClientErrorsAreReplayed(t *testing.T) {
+	server, mockUserService := newMockServer(t, Options{Idempotency: memrepo.NewIdempotencyRepo(), IdempotencyTTL: time.Hour})
+
+	input := createUserRequest{FirstName: "Alice", LastName: "Johnson", Email: "alice@example.com", Age: 28}
+	mockUserService.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Return(domain.User{}, domain.ErrEmailTaken).Times(1)
+
+	first := postUser(server, "key-1", input)
+	require.Equal(t, http.StatusConflict, first.Code)
+	second := postUser(server, "key-1", input)
+	assert.Equal(t, http.StatusConflict, second.Code)
+	assert.Equal(t, first.Body.String(), second.Body.String())
+	assert.Equal(t, problemContentType, second.Header().Get("Content-Type"))
+}
+
+func TestIdempotency_ServerErrorsAreRetried(t *testing.T) {
+	server, mockUserService := newMockServer(t, Options{Idempotency: memrepo.NewIdempotencyRepo(), IdempotencyTTL: time.Hour})
+
+	input := createUserRequest{FirstName: "Alice", LastName: "Johnson", Email: "alice@example.com", Age: 28}
+	created := domain.User{Id: uuid.New(), FirstName: "Alice", Version: 1}
+	gomock.InOrder(
+		mockUserService.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Return(domain.User{}, domain.ErrUnavailable),
+		mockUserService.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Return(created, nil),
+	)
+
+	assert.Equal(t, http.StatusServiceUnavailable, postUser(server, "key-1", input).Code)
+	assert.Equal(t, http.StatusOK, postUser(server, "key-1", input).Code)
+}
+
+func TestIdempotency_KeyExpires(t *testing.T) {
+	server, mockUserService := newMockServer(t, Options{Idempotency: memrepo.NewIdempotencyRepo(), IdempotencyTTL: time.Nanosecond})
+
+	input := createUserRequest{FirstName: "Alice", LastName: "Johnson", Email: "alice@example.com", Age: 28}
+	mockUserService.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Return(domain.User{Id: uuid.New()}, nil).Times(2)
+
+	postUser(server, "key-1", input)
+	time.Sleep(time.Millisecond)
+	assert.Empty(t, postUser(server, "key-1", input).Header().Get(idempotentReplayedHeader))
+}
+
+func TestIdempotency_InFlight(t *testing.T) {
+	store := memrepo.NewIdempotencyRepo()
+	server, _ := newMockServer(t, Options{Idempotency: store})
+
+	input := createUserRequest{FirstName: "Alice", LastName: "Johnson", Email: "alice@example.com", Age: 28}
+	body, _ := json.Marshal(input)
+	req := httptest.NewRequest("POST", "/v1/user", bytes.NewReader(body))
+	now := time.Now()
+	_, reserved, err := store.ReserveIdempotencyKey(req.Context(), domain.IdempotencyRecord{
+		Key:         "key-1",
+		Fingerprint: requestFingerprint(req, body),
+		CreatedAt:   now,
+		ExpiresAt:   now.Add(time.Hour),
+	})
+	require.NoError(t, err)
+	require.True(t, reserved)
+
+	w := postUser(server, "key-1", input)
+	assert.Equal(t, http.StatusConflict, w.Code)
+	var problemBody problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &problemBody))
+	assert.Equal(t, codeIdempotencyInFlight, problemBody.Code)
+}
+
+func TestIdempotency_WithoutKey(t *testing.T) {
+	server, mockUserService := newMockServer(t, Options{Idempotency: memrepo.NewIdempotencyRepo(), IdempotencyTTL: time.Hour})
+
+	input := createUserRequest{FirstName: "Alice", LastName: "Johnson", Email: "alice@example.com", Age: 28}
+	mockUserService.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Return(domain.User{Id: uuid.New()}, nil).Times(2)
+
+	postUser(server, "", input)
+	postUser(server, "", input)
+
+	w := postUser(server, strings.Repeat("k", maxIdempotencyKeyLength+1), input)
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+}
+
+func TestIdempotency_BodyTooLarge(t *testing.T) {
+	server, _ := newMockServer(t, Options{Idempotency: memrepo.NewIdempotencyRepo(), IdempotencyTTL: time.Hour})
+
+	body := `{"first_name":"` + strings.Repeat("a", maxIdempotentBodySize) + `"}`
+	req := httptest.NewRequest("POST", "/v1/user", strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(idempotencyKeyHeader, "key-1")
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+
+	require.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	var p problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &p))
+	assert.Equal(t, codeRequestTooLarge, p.Code)
+	assert.Equal(t, "request body is too large: at most 1048576 bytes are allowed with an Idempotency-Key", p.Detail)
+}
@@ -13,5 +13,52 @@ import (
 type IUserService interface {
 	CreateUser(ctx context.Context, user domain.User) (domain.User, error)
 	GetUser(ctx context.Context, id uuid.UUID) (domain.User, error)
-	UpdateUser(ctx context.Context, user domain.User) (domain.User, error)
+	GetUserByEmail(ctx context.Context, email string) (domain.User, error)
+	PatchUser(ctx context.Context, id uuid.UUID, version int64, patch func(domain.User) (domain.User, error)) (domain.User, error)
+	DeleteUser(ctx context.Context, id uuid.UUID) error
+	RestoreUser(ctx context.Context, id uuid.UUID) (domain.User, error)
+	PurgeUser(ctx context.Context, id uuid.UUID) error
+	ListUsers(ctx context.Context, query domain.ListUsersQuery) (domain.UserPage, error)
+	SearchUsers(ctx context.Context, query domain.SearchUsersQuery) ([]domain.UserMatch, error)
+	ListAuditRecords(ctx context.Context, query domain.AuditQuery) (domain.AuditPage, error)
+	CreateUsers(ctx context.Context, users []domain.User, mode domain.BatchMode) ([]domain.UserResult, error)
+	GetUsers(ctx context.Context, ids []uuid.UUID) ([]domain.UserResult, error)
+	UpdateUsers(ctx context.Context, patches []domain.UserPatch, mode domain.BatchMode) ([]domain.UserResult, error)
+	AssignRole(ctx context.Context, id uuid.UUID, role domain.Role) (domain.User, error)
+}
+
+// IIdempotencyStore keeps the responses of requests sent with an Idempotency-Key
+type IIdempotencyStore interface {
+	ReserveIdempotencyKey(ctx context.Context, record domain.IdempotencyRecord) (domain.IdempotencyRecord, bool, error)
+	CompleteIdempotencyKey(ctx context.Context, record domain.IdempotencyRecord) error
+	ReleaseIdempotencyKey(ctx context.Context, key string) error
+}
+
+// IUserEventStream streams the published user events
+type IUserEventStream interface {
+	// Subscribe sends the events after afterID, then the live ones, until ctx is done
+	Subscribe(ctx context.Context, afterID int64) (<-chan domain.OutboxEvent, error)
+}
+
+// IWebhookService manages webhook subscriptions and their delivery log
+type IWebhookService interface {
+	CreateWebhook(ctx context.Context, webhook domain.WebhookSubscription) (domain.WebhookSubscription, error)
+	GetWebhook(ctx context.Context, id uuid.UUID) (domain.WebhookSubscription, error)
+	ListWebhooks(ctx context.Context) ([]domain.WebhookSubscription, error)
+	UpdateWebhook(ctx context.Context, webhook domain.WebhookSubscription) (domain.WebhookSubscription, error)
+	DeleteWebhook(ctx context.Context, id uuid.UUID) error
+	ListWebhookDeliveries(ctx context.Context, query domain.DeliveryQuery) (domain.DeliveryPage, error)
+	RedeliverWebhook(ctx context.Context, webhookID uuid.UUID, deliveryID int64) (domain.WebhookDelivery, error)
+}
+
+// IAuthService checks credentials, issues tokens and manages sessions
+type IAuthService interface {
+	Login(ctx context.Context, email, password string) (domain.TokenPair, error)
+	Refresh(ctx context.Context, refreshToken string) (domain.TokenPair, error)
+	Authenticate(ctx context.Context, token string) (domain.Principal, error)
+	Register(ctx context.Context, user domain.User, password string) (domain.User, error)
+	SetPassword(ctx context.Context, userID uuid.UUID, current, password string) error
+	ListSessions(ctx context.Context) ([]domain.Session, error)
+	RevokeSession(ctx context.Context, id uuid.UUID) error
+	RevokeUserSessions(ctx context.Context, userID uuid.UUID) (int64, error)
 }
@@ -0,0 +1,115 @@
+package httpserver
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+const (
+	adminTokenHeader = "X-Admin-Token"
+	requestIDHeader  = "X-Request-ID"
+	sessionIDHeader  = "X-Session-ID"
+	// authorizationHeader carries "Bearer <access token>"
+	authorizationHeader = "Authorization"
+	bearerScheme        = "Bearer"
+
+	adminActor         = "admin"
+	maxRequestIDLength = 128
+	maxUserAgentLength = 256
+)
+
+var (
+	errAdminOnly              = errors.New("admin role required")
+	errMalformedAuthorization = fmt.Errorf("%w: malformed Authorization header", domain.ErrUnauthenticated)
+)
+
+// requireAdmin lets the request through only with a valid admin token or the
+// access token of a user with the admin role. The services check permissions
+// again, this only turns everyone else away early.
+func (server *HttpServer) requireAdmin(ctx *gin.Context) {
+	principal, _ := domain.PrincipalFromContext(ctx.Request.Context())
+	if !server.isAdmin(ctx) && principal.Role != domain.RoleAdmin {
+		writeError(ctx, errAdminOnly)
+		return
+	}
+	ctx.Next()
+}
+
+func (server *HttpServer) isAdmin(ctx *gin.Context) bool {
+	token := ctx.GetHeader(adminTokenHeader)
+	return server.adminToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(server.adminToken)) == 1
+}
+
+// requestMeta puts the actor, request and session IDs, client IP and user
+// agent into the request context for the audit log and the session list. A
+// missing or oversized X-Request-ID is replaced, without X-Session-ID the
+// request is its own session.
+func (server *HttpServer) requestMeta(ctx *gin.Context) {
+	requestID := ctx.GetHeader(requestIDHeader)
+	if requestID == "" || len(requestID) > maxRequestIDLength {
+		requestID = uuid.NewString()
+	}
+	ctx.Header(requestIDHeader, requestID)
+
+	sessionID := ctx.GetHeader(sessionIDHeader)
+	if sessionID == "" || len(sessionID) > maxRequestIDLength {
+		sessionID = requestID
+	}
+
+	meta := domain.RequestMeta{
+		Actor:     domain.AnonymousActor,
+		RequestID: requestID,
+		SessionID: sessionID,
+		ClientIP:  ctx.ClientIP(),
+		UserAgent: truncate(ctx.Request.UserAgent(), maxUserAgentLength),
+	}
+	reqCtx := ctx.Request.Context()
+	if server.isAdmin(ctx) {
+		meta.Actor = adminActor
+		reqCtx = domain.ContextWithPrincipal(reqCtx, domain.AdminTokenPrincipal)
+	}
+
+	ctx.Request = ctx.Request.WithContext(domain.ContextWithRequestMeta(reqCtx, meta))
+	ctx.Next()
+}
+
+// authenticate puts the principal of a bearer token into the request context.
+// Requests without one stay anonymous, an invalid token fails the request.
+func (server *HttpServer) authenticate(ctx *gin.Context) {
+	header := ctx.GetHeader(authorizationHeader)
+	if header == "" {
+		ctx.Next()
+		return
+	}
+	scheme, token, _ := strings.Cut(header, " ")
+	if !strings.EqualFold(scheme, bearerScheme) || token == "" {
+		writeError(ctx, errMalformedAuthorization)
+		return
+	}
+
+	principal, err := server.auth.Authenticate(ctx, strings.TrimSpace(token))
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.Request = ctx.Request.WithContext(domain.ContextWithPrincipal(ctx.Request.Context(), principal))
+	ctx.Next()
+}
+
+// truncate cuts s to at most n bytes without splitting a character
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	for n > 0 && !utf8.RuneStart(s[n]) {
+		n--
+	}
+	return s[:n]
+}
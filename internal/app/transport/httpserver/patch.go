@@ -0,0 +1,75 @@
+package httpserver
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	jsonpatch "github.com/evanphx/json-patch/v5"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+const (
+	mergePatchContentType = "application/merge-patch+json" // RFC 7396
+	jsonPatchContentType  = "application/json-patch+json"  // RFC 6902
+)
+
+var (
+	errUnsupportedPatch = errors.New("patch must be sent as " + mergePatchContentType + " or " + jsonPatchContentType)
+	errInvalidPatch     = errors.New("malformed patch document")
+	errPatchFailed      = errors.New("patch cannot be applied to the user")
+)
+
+// userPatcher applies a patch document to the JSON of a user
+type userPatcher func(doc []byte) ([]byte, error)
+
+// newUserPatcher checks the patch document up front, so a malformed patch is
+// rejected before the user is read
+func newUserPatcher(contentType string, body []byte) (userPatcher, error) {
+	switch contentType {
+	case mergePatchContentType:
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(body, &fields); err != nil {
+			return nil, fmt.Errorf("%w: a merge patch must be a JSON object", errInvalidPatch)
+		}
+		return func(doc []byte) ([]byte, error) {
+			return jsonpatch.MergePatch(doc, body)
+		}, nil
+	case jsonPatchContentType:
+		patch, err := jsonpatch.DecodePatch(body)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", errInvalidPatch, err)
+		}
+		return patch.Apply, nil
+	default:
+		return nil, errUnsupportedPatch
+	}
+}
+
+// applyUserPatch patches the representation of the user in the version's
+// wire format, clients address fields by the names they read. id, role,
+// created_at and version are read-only, roles change through the admin API.
+func applyUserPatch(codec userCodec, current domain.User, apply userPatcher) (domain.User, error) {
+	doc, err := json.Marshal(codec.encodeUser(current))
+	if err != nil {
+		return domain.User{}, err
+	}
+	patched, err := apply(doc)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("%w: %v", errPatchFailed, err)
+	}
+
+	out, err := codec.decodeUser(patched)
+	if err != nil {
+		return domain.User{}, fmt.Errorf("%w: %v", errPatchFailed, err)
+	}
+	if out.Id != current.Id || out.Role != current.Role || !out.CreatedAt.Equal(current.CreatedAt) || out.Version != current.Version {
+		return domain.User{}, fmt.Errorf("%w: id, role, created_at and version are read-only", errPatchFailed)
+	}
+
+	current.FirstName = out.FirstName
+	current.LastName = out.LastName
+	current.Email = out.Email
+	current.Age = out.Age
+	return current, nil
+}
@@ -0,0 +1,44 @@
+package httpserver
+
+import "github.com/gin-gonic/gin"
+
+// registerV1 mounts the v1 API, its wire format is defined in dto_v1.go
+func (server *HttpServer) registerV1(api *gin.RouterGroup) {
+	if server.auth != nil {
+		api.POST("auth/login", server.login)
+		api.POST("auth/refresh", server.refreshTokens)
+		api.PUT("user/:id/password", server.setPassword)
+		api.GET("me/sessions", server.listSessions)
+		api.DELETE("me/sessions/:id", server.revokeSession)
+	}
+	api.POST("user", server.createUser)
+	api.GET("user/:id", server.getUser)
+	api.PATCH("user/:id", server.patchUser)
+	api.DELETE("user/:id", server.deleteUser)
+	api.POST("user/:id/restore", server.restoreUser)
+	api.GET("user/:id/audit", server.listAuditRecords)
+	api.GET("users", server.listUsers)
+	api.GET("users/search", server.searchUsers)
+	api.GET("users/by-email/:email", server.getUserByEmail)
+	// POST users:batchCreate, users:batchGet and users:batchUpdate
+	api.POST("users:action", server.usersAction)
+	if server.events != nil {
+		api.GET("users/events", server.streamUserEvents)
+	}
+
+	admin := api.Group("admin", server.requireAdmin)
+	admin.DELETE("user/:id", server.purgeUser)
+	admin.PUT("user/:id/role", server.assignRole)
+	if server.auth != nil {
+		admin.DELETE("user/:id/sessions", server.revokeUserSessions)
+	}
+	if server.webhooks != nil {
+		admin.POST("webhooks", server.createWebhook)
+		admin.GET("webhooks", server.listWebhooks)
+		admin.GET("webhooks/:id", server.getWebhook)
+		admin.PUT("webhooks/:id", server.updateWebhook)
+		admin.DELETE("webhooks/:id", server.deleteWebhook)
+		admin.GET("webhooks/:id/deliveries", server.listWebhookDeliveries)
+		admin.POST("webhooks/:id/deliveries/:delivery_id/redeliver", server.redeliverWebhook)
+	}
+}
@@ -1,29 +1,138 @@
 package httpserver
 
-import "github.com/gin-gonic/gin"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/binding"
+	"github.com/go-playground/validator/v10"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"github.com/vlad19930514/webApp/util"
+)
+
+// registerValidator runs once, every server shares gin's validator
+var registerValidator = sync.OnceValue(func() error {
+	v, ok := binding.Validator.Engine().(*validator.Validate)
+	if !ok {
+		return nil
+	}
+	util.UseRequestFieldNames(v)
+	return v.RegisterValidation(domain.RuleName, func(fl validator.FieldLevel) bool {
+		return domain.ValidName(fl.Field().String())
+	})
+})
 
 type HttpServer struct {
-	userService IUserService
-	router      *gin.Engine
+	userService    IUserService
+	adminToken     string
+	idempotency    IIdempotencyStore
+	idempotencyTTL time.Duration
+	maxBatchSize   int
+	events         IUserEventStream
+	webhooks       IWebhookService
+	auth           IAuthService
+	router         *gin.Engine
+}
+
+// Options are the optional parts of the server, the zero value serves the API
+// without admin routes and without Idempotency-Key support
+type Options struct {
+	AdminToken string
+	// Idempotency stores the responses of requests sent with an Idempotency-Key
+	Idempotency    IIdempotencyStore
+	IdempotencyTTL time.Duration
+	// MaxBatchSize caps the items of one batch request
+	MaxBatchSize int
+	// Events serves GET /users/events, the route is not mounted without it
+	Events IUserEventStream
+	// Webhooks serves the admin webhook routes, they are not mounted without it
+	Webhooks IWebhookService
+	// Auth serves POST /auth/login and authenticates bearer tokens, without
+	// it every request is anonymous
+	Auth IAuthService
+	// TrustedProxies are the IPs and CIDRs whose X-Forwarded-For is believed
+	// for the client IP, none by default
+	TrustedProxies []string
 }
 
-func NewHttpServer(userService IUserService) HttpServer {
+func NewHttpServer(userService IUserService, options Options) (HttpServer, error) {
+	// validation errors name fields the way clients send them, the name
+	// binding applies the same rule as domain.User.Validate
+	if err := registerValidator(); err != nil {
+		return HttpServer{}, fmt.Errorf("failed to register validations: %w", err)
+	}
+
+	if options.IdempotencyTTL <= 0 {
+		options.IdempotencyTTL = DefaultIdempotencyTTL
+	}
+	if options.MaxBatchSize <= 0 {
+		options.MaxBatchSize = DefaultMaxBatchSize
+	}
 	server := HttpServer{
-		userService: userService,
+		userService:    userService,
+		adminToken:     options.AdminToken,
+		idempotency:    options.Idempotency,
+		idempotencyTTL: options.IdempotencyTTL,
+		maxBatchSize:   options.MaxBatchSize,
+		events:         options.Events,
+		webhooks:       options.Webhooks,
+		auth:           options.Auth,
 	}
 
-	router := gin.Default()
-	router.POST("user", server.createUser)
-	router.GET("user/:id", server.getUser)
-	router.PATCH("user", server.updateUser)
+	router := gin.New()
+	// the client IP ends up in the audit log, a forwarded one is only taken
+	// from the proxies the deployment names
+	if err := router.SetTrustedProxies(options.TrustedProxies); err != nil {
+		return HttpServer{}, fmt.Errorf("invalid trusted proxies: %w", err)
+	}
+	router.Use(gin.Logger(), gin.CustomRecovery(server.recoverPanic))
+	// unknown routes and methods answer with a problem too
+	router.HandleMethodNotAllowed = true
+	router.NoRoute(server.routeNotFound)
+	router.NoMethod(server.methodNotAllowed)
+	// handlers pass *gin.Context on as context.Context, let it see the request context values
+	router.ContextWithFallback = true
+	router.Use(server.requestMeta)
+	if server.auth != nil {
+		router.Use(server.authenticate)
+	}
+	if server.idempotency != nil {
+		router.Use(server.idempotent)
+	}
+
+	mountVersions(router, server.versions())
 
 	server.router = router
-	return server
+	return server, nil
 }
 
-func (server *HttpServer) Start(address string) error {
-	return server.router.Run(address)
-}
-func errorResponse(err error) gin.H {
-	return gin.H{"error": err.Error()}
+// shutdownTimeout bounds the wait for requests in flight once Start is asked to stop
+const shutdownTimeout = 10 * time.Second
+
+// Start serves until ctx is done, then stops taking new connections and waits
+// for the requests in flight. Event streams that are still open after
+// shutdownTimeout are cut.
+func (server *HttpServer) Start(ctx context.Context, address string) error {
+	srv := &http.Server{Addr: address, Handler: server.router}
+	errs := make(chan error, 1)
+	go func() {
+		errs <- srv.ListenAndServe()
+	}()
+
+	select {
+	case err := <-errs:
+		return err
+	case <-ctx.Done():
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return errors.Join(err, srv.Close())
+	}
+	return nil
 }
@@ -0,0 +1,51 @@
+package httpserver
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewHttpServer_TrustedProxies(t *testing.T) {
+	clientIP := func(server HttpServer, remoteAddr string) string {
+		server.router.GET("/ip", func(ctx *gin.Context) { ctx.String(http.StatusOK, ctx.ClientIP()) })
+		req := httptest.NewRequest("GET", "/ip", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set("X-Forwarded-For", "203.0.113.7")
+		w := httptest.NewRecorder()
+		server.router.ServeHTTP(w, req)
+		return w.Body.String()
+	}
+
+	// nobody is trusted by default, a client can not pick its own IP
+	assert.Equal(t, "198.51.100.1", clientIP(newTestServer(t, nil, Options{}), "198.51.100.1:4000"))
+
+	proxied := newTestServer(t, nil, Options{TrustedProxies: []string{"10.0.0.0/8"}})
+	assert.Equal(t, "203.0.113.7", clientIP(proxied, "10.1.2.3:4000"))
+
+	_, err := NewHttpServer(nil, Options{TrustedProxies: []string{"not-an-ip"}})
+	require.Error(t, err)
+}
+
+func TestHttpServer_StartStopsWithContext(t *testing.T) {
+	server := newTestServer(t, nil, Options{})
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		done <- server.Start(ctx, "127.0.0.1:0")
+	}()
+
+	cancel()
+	select {
+	case err := <-done:
+		assert.NoError(t, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("server did not stop")
+	}
+}
@@ -1,50 +1,35 @@
 package httpserver
 
 import (
-	"fmt"
 	"github.com/google/uuid"
 	"github.com/vlad19930514/webApp/internal/app/domain"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
-	"github.com/vlad19930514/webApp/util"
 )
 
-type createUserRequest struct {
-	FirstName string `json:"firstname" binding:"required,alpha"`
-	LastName  string `json:"lastname" binding:"required,alpha"`
-	Email     string `json:"email" binding:"required,email"`
-	Age       uint8  `json:"age" binding:"required,min=1,max=130"`
-}
-
 func (server *HttpServer) createUser(ctx *gin.Context) {
-
-	var req createUserRequest
-
-	if err := ctx.ShouldBindJSON(&req); err != nil {
-
-		customErrors, errorsExist := util.GetValidationErrors(&err)
-
-		if errorsExist {
-			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"errors": customErrors})
-			return
-		}
-
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+	codec := codecFrom(ctx)
+	req := codec.newCreateRequest()
+	if err := ctx.ShouldBindJSON(req); err != nil {
+		writeBindError(ctx, err)
 		return
 	}
 
-	arg, err := toDomainUser(req)
+	fields := req.user()
+	arg, err := domain.NewUser(fields.FirstName, fields.LastName, fields.Email, fields.Age)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, err)
 		return
 	}
-	user, err := server.userService.CreateUser(ctx, arg)
+	user, err := server.registerUser(ctx, arg, req.password())
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, err)
 		return
 	}
-	ctx.JSON(http.StatusOK, user)
+	setETag(ctx, user.Version)
+	ctx.JSON(http.StatusOK, codec.encodeUser(user))
 
 }
 
@@ -55,13 +40,7 @@ type getUserRequest struct {
 func (server *HttpServer) getUser(ctx *gin.Context) {
 	var req getUserRequest
 	if err := ctx.ShouldBindUri(&req); err != nil {
-		validationErrors, errorsExist := util.GetValidationErrors(&err)
-		fmt.Println(errorsExist)
-		if errorsExist {
-			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"errors": validationErrors})
-			return
-		}
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+		writeBindError(ctx, err)
 		return
 	}
 
@@ -69,56 +48,252 @@ func (server *HttpServer) getUser(ctx *gin.Context) {
 
 	user, err := server.userService.GetUser(ctx, id)
 	if err != nil {
-		ctx.JSON(http.StatusNotFound, errorResponse(err))
+		writeError(ctx, err)
 		return
 	}
-	ctx.JSON(http.StatusAccepted, user)
+	setETag(ctx, user.Version)
+	ctx.JSON(http.StatusAccepted, codecFrom(ctx).encodeUser(user))
+}
+
+type getUserByEmailRequest struct {
+	Email string `uri:"email" binding:"required,email"`
 }
 
-type updateUserRequest struct {
-	ID        uuid.UUID `json:"id" binding:"required,uuid"`
-	FirstName string    `json:"firstname" binding:"required,alpha"`
-	LastName  string    `json:"lastname" binding:"required,alpha"`
-	Email     string    `json:"email" binding:"required,email"`
-	Age       uint8     `json:"age" binding:"required,min=1,max=130"`
+func (server *HttpServer) getUserByEmail(ctx *gin.Context) {
+	var req getUserByEmailRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		writeBindError(ctx, err)
+		return
+	}
+
+	user, err := server.userService.GetUserByEmail(ctx, req.Email)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	setETag(ctx, user.Version)
+	ctx.JSON(http.StatusOK, codecFrom(ctx).encodeUser(user))
 }
 
-func (server *HttpServer) updateUser(ctx *gin.Context) {
-	var req updateUserRequest
+// patchUser takes a JSON Merge Patch or a JSON Patch of the user, the
+// Content-Type tells which one
+func (server *HttpServer) patchUser(ctx *gin.Context) {
+	id, ok := bindUserID(ctx)
+	if !ok {
+		return
+	}
+
+	body, err := ctx.GetRawData()
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	apply, err := newUserPatcher(ctx.ContentType(), body)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	version, err := ifMatchVersion(ctx)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	user, err := server.userService.PatchUser(ctx, id, version, func(current domain.User) (domain.User, error) {
+		return applyUserPatch(codecFrom(ctx), current, apply)
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	setETag(ctx, user.Version)
+	ctx.JSON(http.StatusOK, codecFrom(ctx).encodeUser(user))
+}
+
+func (server *HttpServer) deleteUser(ctx *gin.Context) {
+	id, ok := bindUserID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := server.userService.DeleteUser(ctx, id); err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+func (server *HttpServer) restoreUser(ctx *gin.Context) {
+	id, ok := bindUserID(ctx)
+	if !ok {
+		return
+	}
+
+	user, err := server.userService.RestoreUser(ctx, id)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, codecFrom(ctx).encodeUser(user))
+}
+
+func (server *HttpServer) purgeUser(ctx *gin.Context) {
+	id, ok := bindUserID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := server.userService.PurgeUser(ctx, id); err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+type assignRoleRequest struct {
+	Role string `json:"role" binding:"required,oneof=admin support user"`
+}
+
+// assignRole changes the role of a user, the change shows up in its audit log
+func (server *HttpServer) assignRole(ctx *gin.Context) {
+	id, ok := bindUserID(ctx)
+	if !ok {
+		return
+	}
+	var req assignRoleRequest
 	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeBindError(ctx, err)
+		return
+	}
+
+	user, err := server.userService.AssignRole(ctx, id, domain.Role(req.Role))
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	setETag(ctx, user.Version)
+	ctx.JSON(http.StatusOK, codecFrom(ctx).encodeUser(user))
+}
+
+type listUsersRequest struct {
+	Name          string    `form:"name"`
+	EmailDomain   string    `form:"email_domain" binding:"omitempty,fqdn"`
+	MinAge        uint8     `form:"min_age" binding:"omitempty,min=1,max=130"`
+	MaxAge        uint8     `form:"max_age" binding:"omitempty,min=1,max=130"`
+	CreatedAfter  time.Time `form:"created_after" time_format:"2006-01-02T15:04:05Z07:00"`
+	CreatedBefore time.Time `form:"created_before" time_format:"2006-01-02T15:04:05Z07:00"`
+	Sort          string    `form:"sort"`
+	Limit         int       `form:"limit" binding:"omitempty,min=1,max=100"`
+	Cursor        string    `form:"cursor"`
+}
+
+type listUsersResponse struct {
+	Users      []any  `json:"users"`
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+func (server *HttpServer) listUsers(ctx *gin.Context) {
+	var req listUsersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeBindError(ctx, err)
+		return
+	}
+
+	sort, err := domain.ParseUserSort(req.Sort)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	page, err := server.userService.ListUsers(ctx, domain.ListUsersQuery{
+		Filter: domain.UserFilter{
+			Name:          req.Name,
+			EmailDomain:   req.EmailDomain,
+			MinAge:        req.MinAge,
+			MaxAge:        req.MaxAge,
+			CreatedAfter:  req.CreatedAfter,
+			CreatedBefore: req.CreatedBefore,
+		},
+		Sort:   sort,
+		Limit:  req.Limit,
+		Cursor: req.Cursor,
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, listUsersResponse{Users: encodeUsers(codecFrom(ctx), page.Users), NextCursor: page.NextCursor})
+}
 
-		customErrors, errorsExist := util.GetValidationErrors(&err)
+type searchUsersRequest struct {
+	Query string `form:"q" binding:"required,max=100"`
+	Limit int    `form:"limit" binding:"omitempty,min=1,max=100"`
+}
+
+type searchUsersResponse struct {
+	Results []userMatchResponse `json:"results"`
+}
 
-		if errorsExist {
-			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"errors": customErrors})
-			return
-		}
-		ctx.JSON(http.StatusBadRequest, errorResponse(err))
+func (server *HttpServer) searchUsers(ctx *gin.Context) {
+	var req searchUsersRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeBindError(ctx, err)
 		return
 	}
-	domainUser := domain.User{
-		Id:        req.ID,
-		FirstName: req.FirstName,
-		LastName:  req.LastName,
-		Email:     req.Email,
-		Age:       req.Age,
+
+	matches, err := server.userService.SearchUsers(ctx, domain.SearchUsersQuery{Query: req.Query, Limit: req.Limit})
+	if err != nil {
+		writeError(ctx, err)
+		return
 	}
 
-	user, err := server.userService.UpdateUser(ctx, domainUser)
+	ctx.JSON(http.StatusOK, searchUsersResponse{Results: newUserMatchResponses(codecFrom(ctx), matches)})
+}
+
+type listAuditRecordsRequest struct {
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+	Cursor string `form:"cursor"`
+}
+
+type listAuditRecordsResponse struct {
+	Records    []auditRecordResponse `json:"records"`
+	NextCursor string                `json:"next_cursor,omitempty"`
+}
+
+func (server *HttpServer) listAuditRecords(ctx *gin.Context) {
+	id, ok := bindUserID(ctx)
+	if !ok {
+		return
+	}
+	var req listAuditRecordsRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeBindError(ctx, err)
+		return
+	}
+
+	page, err := server.userService.ListAuditRecords(ctx, domain.AuditQuery{
+		UserId: id,
+		Limit:  req.Limit,
+		Cursor: req.Cursor,
+	})
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, errorResponse(err))
+		writeError(ctx, err)
 		return
 	}
-	ctx.JSON(http.StatusOK, user)
+
+	ctx.JSON(http.StatusOK, listAuditRecordsResponse{Records: newAuditRecordResponses(page.Records), NextCursor: page.NextCursor})
 }
-func toDomainUser(req createUserRequest) (domain.User, error) {
-	id := uuid.New()
-	user := domain.User{
-		Id:        id,
-		FirstName: req.FirstName,
-		LastName:  req.LastName,
-		Email:     req.Email,
-		Age:       req.Age,
+
+// bindUserID parses the :id path param and writes the error response itself
+func bindUserID(ctx *gin.Context) (uuid.UUID, bool) {
+	var req getUserRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		writeBindError(ctx, err)
+		return uuid.Nil, false
 	}
-	return user, nil
+
+	id, _ := uuid.Parse(req.ID)
+	return id, true
 }
@@ -2,12 +2,15 @@ package httpserver
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/golang/mock/gomock"
 	"github.com/google/uuid"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"github.com/vlad19930514/webApp/internal/app/domain"
 	"github.com/vlad19930514/webApp/internal/app/transport/httpserver/mocks"
@@ -17,6 +20,25 @@ import (
 	"time"
 )
 
+const testAdminToken = "admin-secret"
+
+// newTestServer builds the server like main does and fails the test when the
+// options are rejected
+func newTestServer(t *testing.T, users IUserService, options Options) HttpServer {
+	t.Helper()
+	server, err := NewHttpServer(users, options)
+	require.NoError(t, err)
+	return server
+}
+
+// newMockServer is newTestServer over a mocked user service. Other
+// dependencies, such as their mocks, come in through options.
+func newMockServer(t *testing.T, options Options) (HttpServer, *mocks.MockIUserService) {
+	t.Helper()
+	users := mocks.NewMockIUserService(gomock.NewController(t))
+	return newTestServer(t, users, options), users
+}
+
 type UserTestSuite struct {
 	suite.Suite
 	mCtrl           *gomock.Controller
@@ -29,11 +51,18 @@ func (s *UserTestSuite) SetupTest() {
 	s.mCtrl = gomock.NewController(s.T())
 	s.mockUserService = mocks.NewMockIUserService(s.mCtrl)
 
-	server := NewHttpServer(s.mockUserService)
+	server := newTestServer(s.T(), s.mockUserService, Options{AdminToken: testAdminToken})
 	router := gin.Default()
 	router.POST("/user", server.createUser)
 	router.GET("/user/:id", server.getUser)
-	router.PUT("/user", server.updateUser)
+	router.PATCH("/user/:id", server.patchUser)
+	router.DELETE("/user/:id", server.deleteUser)
+	router.POST("/user/:id/restore", server.restoreUser)
+	router.DELETE("/admin/user/:id", server.requireAdmin, server.purgeUser)
+	router.GET("/users", server.listUsers)
+	router.GET("/users/search", server.searchUsers)
+	router.GET("/users/by-email/:email", server.getUserByEmail)
+	router.GET("/user/:id/audit", server.listAuditRecords)
 	s.router = router
 }
 
@@ -83,6 +112,48 @@ func (s *UserTestSuite) TestCreateUser() {
 			expectedStatus: http.StatusBadRequest,
 			expectCall:     false,
 		},
+		{
+			name: "unicode and punctuated names",
+			input: createUserRequest{
+				FirstName: "Анна",
+				LastName:  "O'Brien-Smith",
+				Email:     "anna@example.com",
+				Age:       28,
+			},
+			mockReturnUser: domain.User{
+				Id:        uuid.New(),
+				FirstName: "Анна",
+				LastName:  "O'Brien-Smith",
+				Email:     "anna@example.com",
+				Age:       28,
+				CreatedAt: time.Now(),
+			},
+			expectedStatus: http.StatusOK,
+			expectCall:     true,
+		},
+		{
+			name: "validation error - digits in name",
+			input: createUserRequest{
+				FirstName: "R2-D2",
+				LastName:  "Droid",
+				Email:     "r2@example.com",
+				Age:       30,
+			},
+			expectedStatus: http.StatusBadRequest,
+			expectCall:     false,
+		},
+		{
+			name: "domain validation error",
+			input: createUserRequest{
+				FirstName: "Jane",
+				LastName:  "Smith",
+				Email:     "jane.smith@example.com",
+				Age:       25,
+			},
+			mockReturnErr:  &domain.ValidationError{Violations: []domain.FieldViolation{{Field: "age", Rule: domain.RuleMax, Param: "130"}}},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectCall:     true,
+		},
 		{
 			name: "server error",
 			input: createUserRequest{
@@ -96,6 +167,19 @@ func (s *UserTestSuite) TestCreateUser() {
 			expectedStatus: http.StatusInternalServerError,
 			expectCall:     true,
 		},
+		{
+			name: "email taken",
+			input: createUserRequest{
+				FirstName: "Jane",
+				LastName:  "Smith",
+				Email:     "jane.smith@example.com",
+				Age:       25,
+			},
+			mockReturnUser: domain.User{},
+			mockReturnErr:  fmt.Errorf("failed to create domain user: %w", domain.ErrEmailTaken),
+			expectedStatus: http.StatusConflict,
+			expectCall:     true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -116,10 +200,10 @@ func (s *UserTestSuite) TestCreateUser() {
 			assert.Equal(s.T(), tt.expectedStatus, w.Code, "Expected status code to be %v", tt.expectedStatus)
 
 			if tt.expectedStatus == http.StatusOK {
-				var createdUser domain.User
+				var createdUser userResponse
 				err := json.Unmarshal(w.Body.Bytes(), &createdUser)
 				assert.NoError(s.T(), err, "Expected no error when unmarshaling response body")
-				assert.Equal(s.T(), tt.mockReturnUser.Id, createdUser.Id, "Expected created user ID to match")
+				assert.Equal(s.T(), tt.mockReturnUser.Id, createdUser.ID, "Expected created user ID to match")
 				assert.Equal(s.T(), tt.mockReturnUser.FirstName, createdUser.FirstName, "Expected created user FirstName to match")
 				assert.Equal(s.T(), tt.mockReturnUser.LastName, createdUser.LastName, "Expected created user LastName to match")
 				assert.Equal(s.T(), tt.mockReturnUser.Email, createdUser.Email, "Expected created user Email to match")
@@ -165,10 +249,18 @@ func (s *UserTestSuite) TestGetUser() {
 			name:           "user not found",
 			userID:         uuid.New().String(),
 			mockReturnUser: domain.User{},
-			mockReturnErr:  errors.New("user not found"),
+			mockReturnErr:  fmt.Errorf("failed to get a user: %w", domain.ErrNotFound),
 			expectedStatus: http.StatusNotFound,
 			expectCall:     true,
 		},
+		{
+			name:           "storage unavailable",
+			userID:         uuid.New().String(),
+			mockReturnUser: domain.User{},
+			mockReturnErr:  fmt.Errorf("failed to get a user: %w", domain.ErrUnavailable),
+			expectedStatus: http.StatusServiceUnavailable,
+			expectCall:     true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -187,10 +279,10 @@ func (s *UserTestSuite) TestGetUser() {
 			assert.Equal(s.T(), tt.expectedStatus, w.Code, "Expected status code to be %v", tt.expectedStatus)
 
 			if tt.expectedStatus == http.StatusAccepted {
-				var retrievedUser domain.User
+				var retrievedUser userResponse
 				err := json.Unmarshal(w.Body.Bytes(), &retrievedUser)
 				assert.NoError(s.T(), err, "Expected no error when unmarshaling response body")
-				assert.Equal(s.T(), tt.mockReturnUser.Id, retrievedUser.Id, "Expected retrieved user ID to match")
+				assert.Equal(s.T(), tt.mockReturnUser.Id, retrievedUser.ID, "Expected retrieved user ID to match")
 				assert.Equal(s.T(), tt.mockReturnUser.FirstName, retrievedUser.FirstName, "Expected retrieved user FirstName to match")
 				assert.Equal(s.T(), tt.mockReturnUser.LastName, retrievedUser.LastName, "Expected retrieved user LastName to match")
 				assert.Equal(s.T(), tt.mockReturnUser.Email, retrievedUser.Email, "Expected retrieved user Email to match")
@@ -200,62 +292,419 @@ func (s *UserTestSuite) TestGetUser() {
 		})
 	}
 }
-func (s *UserTestSuite) TestUpdateUser() {
+func (s *UserTestSuite) TestGetUserByEmail() {
+	user := domain.User{Id: uuid.New(), FirstName: "Bob", LastName: "Smith", Email: "Bob@example.com", Age: 40, Version: 3}
+	s.mockUserService.EXPECT().GetUserByEmail(gomock.Any(), "bob@example.com").Return(user, nil)
+
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, httptest.NewRequest("GET", "/users/by-email/bob%40example.com", nil))
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	assert.Equal(s.T(), `"3"`, w.Header().Get("ETag"))
+	var got userResponse
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &got))
+	assert.Equal(s.T(), user.Id, got.ID)
+
+	s.mockUserService.EXPECT().GetUserByEmail(gomock.Any(), "nobody@example.com").
+		Return(domain.User{}, fmt.Errorf("failed to get a user by email: %w", domain.ErrNotFound))
+	w = httptest.NewRecorder()
+	s.router.ServeHTTP(w, httptest.NewRequest("GET", "/users/by-email/nobody@example.com", nil))
+	assert.Equal(s.T(), http.StatusNotFound, w.Code)
+
+	w = httptest.NewRecorder()
+	s.router.ServeHTTP(w, httptest.NewRequest("GET", "/users/by-email/not-an-email", nil))
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+}
+
+func (s *UserTestSuite) TestPatchUser() {
+	current := domain.User{
+		Id:        uuid.New(),
+		FirstName: "Alice",
+		LastName:  "Johnson",
+		Email:     "alice.johnson@example.com",
+		Age:       28,
+		CreatedAt: time.Now(),
+		Version:   1,
+	}
+
 	tests := []struct {
 		name           string
-		input          updateUserRequest
-		mockReturnUser domain.User
+		userID         string
+		contentType    string
+		body           string
+		ifMatch        string
+		expectCall     bool
+		mockReturnErr  error
+		expectedStatus int
+		expectedCode   string
+		expectedUser   domain.User
+	}{
+		{
+			name:           "merge patch",
+			contentType:    mergePatchContentType,
+			body:           `{"age": 29}`,
+			ifMatch:        `"1"`,
+			expectCall:     true,
+			expectedStatus: http.StatusOK,
+			expectedUser:   domain.User{FirstName: "Alice", LastName: "Johnson", Email: "alice.johnson@example.com", Age: 29},
+		},
+		{
+			name:           "merge patch with charset",
+			contentType:    mergePatchContentType + "; charset=utf-8",
+			body:           `{"first_name": "Alicia", "email": "alicia@example.com"}`,
+			ifMatch:        `"1"`,
+			expectCall:     true,
+			expectedStatus: http.StatusOK,
+			expectedUser:   domain.User{FirstName: "Alicia", LastName: "Johnson", Email: "alicia@example.com", Age: 28},
+		},
+		{
+			name:           "json patch",
+			contentType:    jsonPatchContentType,
+			body:           `[{"op": "test", "path": "/last_name", "value": "Johnson"}, {"op": "replace", "path": "/last_name", "value": "Smith"}]`,
+			ifMatch:        `"1"`,
+			expectCall:     true,
+			expectedStatus: http.StatusOK,
+			expectedUser:   domain.User{FirstName: "Alice", LastName: "Smith", Email: "alice.johnson@example.com", Age: 28},
+		},
+		{
+			name:           "json patch test fails",
+			contentType:    jsonPatchContentType,
+			body:           `[{"op": "test", "path": "/last_name", "value": "Smith"}, {"op": "replace", "path": "/age", "value": 40}]`,
+			ifMatch:        `"1"`,
+			expectCall:     true,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   codePatchFailed,
+		},
+		{
+			name:           "read-only field",
+			contentType:    mergePatchContentType,
+			body:           `{"id": "` + uuid.NewString() + `"}`,
+			ifMatch:        `"1"`,
+			expectCall:     true,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   codePatchFailed,
+		},
+		{
+			name:           "merge patch changes role",
+			contentType:    mergePatchContentType,
+			body:           `{"role": "admin"}`,
+			ifMatch:        `"1"`,
+			expectCall:     true,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   codePatchFailed,
+		},
+		{
+			name:           "json patch changes role",
+			contentType:    jsonPatchContentType,
+			body:           `[{"op": "replace", "path": "/role", "value": "admin"}]`,
+			ifMatch:        `"1"`,
+			expectCall:     true,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   codePatchFailed,
+		},
+		{
+			name:           "unknown field",
+			contentType:    mergePatchContentType,
+			body:           `{"nickname": "ally"}`,
+			ifMatch:        `"1"`,
+			expectCall:     true,
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   codePatchFailed,
+		},
+		{
+			name:           "merged result breaks domain rules",
+			contentType:    mergePatchContentType,
+			body:           `{"age": 200}`,
+			ifMatch:        `"1"`,
+			expectCall:     true,
+			mockReturnErr:  &domain.ValidationError{Violations: []domain.FieldViolation{{Field: "age", Rule: domain.RuleMax, Param: "130", Value: uint8(200)}}},
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedCode:   codeValidation,
+		},
+		{
+			name:           "plain json is not a patch",
+			contentType:    "application/json",
+			body:           `{"age": 29}`,
+			ifMatch:        `"1"`,
+			expectedStatus: http.StatusUnsupportedMediaType,
+			expectedCode:   codeUnsupportedMediaType,
+		},
+		{
+			name:           "malformed merge patch",
+			contentType:    mergePatchContentType,
+			body:           `[{"age": 29}]`,
+			ifMatch:        `"1"`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   codeInvalidPatch,
+		},
+		{
+			name:           "malformed json patch",
+			contentType:    jsonPatchContentType,
+			body:           `{"op": "replace"}`,
+			ifMatch:        `"1"`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   codeInvalidPatch,
+		},
+		{
+			name:           "invalid id",
+			userID:         "invalid-uuid",
+			contentType:    mergePatchContentType,
+			body:           `{"age": 29}`,
+			ifMatch:        `"1"`,
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   codeInvalidRequest,
+		},
+		{
+			name:           "missing If-Match",
+			contentType:    mergePatchContentType,
+			body:           `{"age": 29}`,
+			expectedStatus: http.StatusPreconditionRequired,
+			expectedCode:   codePreconditionRequired,
+		},
+		{
+			name:           "malformed If-Match",
+			contentType:    mergePatchContentType,
+			body:           `{"age": 29}`,
+			ifMatch:        "one",
+			expectedStatus: http.StatusBadRequest,
+			expectedCode:   codeInvalidIfMatch,
+		},
+		{
+			name:           "version mismatch",
+			contentType:    mergePatchContentType,
+			body:           `{"age": 29}`,
+			ifMatch:        `"3"`,
+			expectCall:     true,
+			mockReturnErr:  domain.ErrVersionConflict,
+			expectedStatus: http.StatusPreconditionFailed,
+			expectedCode:   codeVersionConflict,
+		},
+		{
+			name:           "unknown user",
+			contentType:    mergePatchContentType,
+			body:           `{"age": 29}`,
+			ifMatch:        `"1"`,
+			expectCall:     true,
+			mockReturnErr:  fmt.Errorf("failed to get a user: %w", domain.ErrNotFound),
+			expectedStatus: http.StatusNotFound,
+			expectedCode:   codeNotFound,
+		},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			userID := tt.userID
+			if userID == "" {
+				userID = current.Id.String()
+			}
+			if tt.expectCall {
+				s.mockUserService.EXPECT().
+					PatchUser(gomock.Any(), current.Id, gomock.Any(), gomock.Any()).
+					DoAndReturn(func(_ context.Context, _ uuid.UUID, _ int64, patch func(domain.User) (domain.User, error)) (domain.User, error) {
+						if tt.mockReturnErr != nil {
+							return domain.User{}, tt.mockReturnErr
+						}
+						patched, err := patch(current)
+						if err != nil {
+							return domain.User{}, err
+						}
+						patched.Version++
+						return patched, nil
+					})
+			}
+
+			req, _ := http.NewRequest("PATCH", "/user/"+userID, bytes.NewBufferString(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			if tt.ifMatch != "" {
+				req.Header.Set("If-Match", tt.ifMatch)
+			}
+
+			w := httptest.NewRecorder()
+			s.router.ServeHTTP(w, req)
+
+			assert.Equal(s.T(), tt.expectedStatus, w.Code, "Expected status code to be %v", tt.expectedStatus)
+
+			if tt.expectedStatus == http.StatusOK {
+				var updatedUser userResponse
+				err := json.Unmarshal(w.Body.Bytes(), &updatedUser)
+				assert.NoError(s.T(), err, "Expected no error when unmarshaling response body")
+				assert.Equal(s.T(), current.Id, updatedUser.ID, "Expected updated user ID to match")
+				assert.Equal(s.T(), tt.expectedUser.FirstName, updatedUser.FirstName, "Expected updated user FirstName to match")
+				assert.Equal(s.T(), tt.expectedUser.LastName, updatedUser.LastName, "Expected updated user LastName to match")
+				assert.Equal(s.T(), tt.expectedUser.Email, updatedUser.Email, "Expected updated user Email to match")
+				assert.Equal(s.T(), tt.expectedUser.Age, updatedUser.Age, "Expected updated user Age to match")
+				assert.Equal(s.T(), `"2"`, w.Header().Get("ETag"), "Expected ETag to carry the new version")
+				return
+			}
+			var body problem
+			assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &body))
+			assert.Equal(s.T(), tt.expectedCode, body.Code)
+		})
+	}
+}
+
+func (s *UserTestSuite) TestDeleteUser() {
+	tests := []struct {
+		name           string
+		userID         string
 		mockReturnErr  error
 		expectedStatus int
 		expectCall     bool
 	}{
 		{
-			name: "successful update",
-			input: updateUserRequest{
-				ID:        uuid.New(),
-				FirstName: "Alice",
-				LastName:  "Johnson",
-				Email:     "alice.johnson@example.com",
-				Age:       28,
+			name:           "successful deletion",
+			userID:         uuid.New().String(),
+			expectedStatus: http.StatusNoContent,
+			expectCall:     true,
+		},
+		{
+			name:           "validation error - invalid UUID",
+			userID:         "invalid-uuid",
+			expectedStatus: http.StatusBadRequest,
+			expectCall:     false,
+		},
+		{
+			name:           "user not found",
+			userID:         uuid.New().String(),
+			mockReturnErr:  fmt.Errorf("failed to delete a user: %w", domain.ErrNotFound),
+			expectedStatus: http.StatusNotFound,
+			expectCall:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			if tt.expectCall {
+				s.mockUserService.EXPECT().
+					DeleteUser(gomock.Any(), uuid.MustParse(tt.userID)).
+					Return(tt.mockReturnErr)
+			}
+
+			req, _ := http.NewRequest("DELETE", "/user/"+tt.userID, nil)
+
+			w := httptest.NewRecorder()
+			s.router.ServeHTTP(w, req)
+
+			assert.Equal(s.T(), tt.expectedStatus, w.Code, "Expected status code to be %v", tt.expectedStatus)
+		})
+	}
+}
+func (s *UserTestSuite) TestRestoreUser() {
+	user := domain.User{
+		Id:        uuid.New(),
+		FirstName: "John",
+		LastName:  "Doe",
+		Email:     "john.doe@example.com",
+		Age:       30,
+		CreatedAt: time.Now(),
+	}
+	s.mockUserService.EXPECT().
+		RestoreUser(gomock.Any(), user.Id).
+		Return(user, nil)
+
+	req, _ := http.NewRequest("POST", "/user/"+user.Id.String()+"/restore", nil)
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	var restoredUser userResponse
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &restoredUser))
+	assert.Equal(s.T(), user.Id, restoredUser.ID)
+}
+func (s *UserTestSuite) TestPurgeUser() {
+	tests := []struct {
+		name           string
+		token          string
+		expectedStatus int
+		expectCall     bool
+	}{
+		{
+			name:           "admin token",
+			token:          testAdminToken,
+			expectedStatus: http.StatusNoContent,
+			expectCall:     true,
+		},
+		{
+			name:           "missing token",
+			expectedStatus: http.StatusForbidden,
+			expectCall:     false,
+		},
+		{
+			name:           "wrong token",
+			token:          "not-the-token",
+			expectedStatus: http.StatusForbidden,
+			expectCall:     false,
+		},
+	}
+
+	for _, tt := range tests {
+		s.Run(tt.name, func() {
+			id := uuid.New()
+			if tt.expectCall {
+				s.mockUserService.EXPECT().
+					PurgeUser(gomock.Any(), id).
+					Return(nil)
+			}
+
+			req, _ := http.NewRequest("DELETE", "/admin/user/"+id.String(), nil)
+			if tt.token != "" {
+				req.Header.Set(adminTokenHeader, tt.token)
+			}
+
+			w := httptest.NewRecorder()
+			s.router.ServeHTTP(w, req)
+
+			assert.Equal(s.T(), tt.expectedStatus, w.Code, "Expected status code to be %v", tt.expectedStatus)
+		})
+	}
+}
+
+func (s *UserTestSuite) TestListUsers() {
+	tests := []struct {
+		name           string
+		query          string
+		expectedQuery  domain.ListUsersQuery
+		mockReturnPage domain.UserPage
+		mockReturnErr  error
+		expectedStatus int
+		expectCall     bool
+	}{
+		{
+			name:  "successful listing",
+			query: "?name=ann&email_domain=example.com&min_age=18&sort=-age,email&limit=2&cursor=abc",
+			expectedQuery: domain.ListUsersQuery{
+				Filter: domain.UserFilter{Name: "ann", EmailDomain: "example.com", MinAge: 18},
+				Sort: []domain.UserSort{
+					{Field: domain.SortByAge, Desc: true},
+					{Field: domain.SortByEmail},
+				},
+				Limit:  2,
+				Cursor: "abc",
 			},
-			mockReturnUser: domain.User{
-				Id:        uuid.New(),
-				FirstName: "Alice",
-				LastName:  "Johnson",
-				Email:     "alice.johnson@example.com",
-				Age:       28,
-				CreatedAt: time.Now(),
+			mockReturnPage: domain.UserPage{
+				Users:      []domain.User{{Id: uuid.New(), FirstName: "Anna"}},
+				NextCursor: "next",
 			},
-			mockReturnErr:  nil,
 			expectedStatus: http.StatusOK,
 			expectCall:     true,
 		},
 		{
-			name: "validation error - invalid email",
-			input: updateUserRequest{
-				ID:        uuid.New(),
-				FirstName: "John",
-				LastName:  "Doe",
-				Email:     "john.doe",
-				Age:       30,
-			},
-			mockReturnUser: domain.User{},
-			mockReturnErr:  nil,
+			name:           "unknown sort field",
+			query:          "?sort=password",
 			expectedStatus: http.StatusBadRequest,
 			expectCall:     false,
 		},
 		{
-			name: "server error",
-			input: updateUserRequest{
-				ID:        uuid.New(),
-				FirstName: "Jane",
-				LastName:  "Smith",
-				Email:     "jane.smith@example.com",
-				Age:       25,
-			},
-			mockReturnUser: domain.User{},
-			mockReturnErr:  errors.New("internal server error"),
-			expectedStatus: http.StatusInternalServerError,
+			name:           "validation error - limit too large",
+			query:          "?limit=1000",
+			expectedStatus: http.StatusBadRequest,
+			expectCall:     false,
+		},
+		{
+			name:           "invalid cursor",
+			query:          "?cursor=broken",
+			expectedQuery:  domain.ListUsersQuery{Cursor: "broken"},
+			mockReturnErr:  domain.ErrInvalidQuery,
+			expectedStatus: http.StatusBadRequest,
 			expectCall:     true,
 		},
 	}
@@ -264,13 +713,11 @@ func (s *UserTestSuite) TestUpdateUser() {
 		s.Run(tt.name, func() {
 			if tt.expectCall {
 				s.mockUserService.EXPECT().
-					UpdateUser(gomock.Any(), gomock.Any()).
-					Return(tt.mockReturnUser, tt.mockReturnErr)
+					ListUsers(gomock.Any(), tt.expectedQuery).
+					Return(tt.mockReturnPage, tt.mockReturnErr)
 			}
 
-			body, _ := json.Marshal(tt.input)
-			req, _ := http.NewRequest("PUT", "/user", bytes.NewBuffer(body))
-			req.Header.Set("Content-Type", "application/json")
+			req, _ := http.NewRequest("GET", "/users"+tt.query, nil)
 
 			w := httptest.NewRecorder()
 			s.router.ServeHTTP(w, req)
@@ -278,15 +725,98 @@ func (s *UserTestSuite) TestUpdateUser() {
 			assert.Equal(s.T(), tt.expectedStatus, w.Code, "Expected status code to be %v", tt.expectedStatus)
 
 			if tt.expectedStatus == http.StatusOK {
-				var updatedUser domain.User
-				err := json.Unmarshal(w.Body.Bytes(), &updatedUser)
+				var resp listUsersResponse
+				err := json.Unmarshal(w.Body.Bytes(), &resp)
 				assert.NoError(s.T(), err, "Expected no error when unmarshaling response body")
-				assert.Equal(s.T(), tt.mockReturnUser.Id, updatedUser.Id, "Expected updated user ID to match")
-				assert.Equal(s.T(), tt.mockReturnUser.FirstName, updatedUser.FirstName, "Expected updated user FirstName to match")
-				assert.Equal(s.T(), tt.mockReturnUser.LastName, updatedUser.LastName, "Expected updated user LastName to match")
-				assert.Equal(s.T(), tt.mockReturnUser.Email, updatedUser.Email, "Expected updated user Email to match")
-				assert.Equal(s.T(), tt.mockReturnUser.Age, updatedUser.Age, "Expected updated user Age to match")
+				assert.Equal(s.T(), tt.mockReturnPage.NextCursor, resp.NextCursor)
+				assert.Len(s.T(), resp.Users, len(tt.mockReturnPage.Users))
 			}
 		})
 	}
 }
+
+func (s *UserTestSuite) TestSearchUsers() {
+	match := domain.UserMatch{
+		User:  domain.User{Id: uuid.New(), FirstName: "Anna", LastName: "Smith"},
+		Score: 0.7,
+	}
+	s.mockUserService.EXPECT().
+		SearchUsers(gomock.Any(), domain.SearchUsersQuery{Query: "anna smi", Limit: 5}).
+		Return([]domain.UserMatch{match}, nil)
+
+	req, _ := http.NewRequest("GET", "/users/search?q=anna+smi&limit=5", nil)
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	var resp struct {
+		Results []struct {
+			User  userResponse `json:"user"`
+			Score float64      `json:"score"`
+		} `json:"results"`
+	}
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Len(s.T(), resp.Results, 1)
+	assert.Equal(s.T(), match.User.Id, resp.Results[0].User.ID)
+
+	req, _ = http.NewRequest("GET", "/users/search", nil)
+	w = httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+	assert.Equal(s.T(), http.StatusBadRequest, w.Code)
+}
+
+func (s *UserTestSuite) TestListAuditRecords() {
+	userID := uuid.New()
+	record := domain.AuditRecord{
+		Id:        uuid.New(),
+		UserId:    userID,
+		Operation: domain.AuditUpdate,
+		Actor:     "admin",
+		Changes:   []domain.FieldChange{{Field: "email", Before: "a@example.com", After: "b@example.com"}},
+		CreatedAt: time.Now(),
+	}
+	s.mockUserService.EXPECT().
+		ListAuditRecords(gomock.Any(), domain.AuditQuery{UserId: userID, Limit: 10, Cursor: "abc"}).
+		Return(domain.AuditPage{Records: []domain.AuditRecord{record}, NextCursor: "next"}, nil)
+
+	req, _ := http.NewRequest("GET", "/user/"+userID.String()+"/audit?limit=10&cursor=abc", nil)
+	w := httptest.NewRecorder()
+	s.router.ServeHTTP(w, req)
+
+	assert.Equal(s.T(), http.StatusOK, w.Code)
+	var resp listAuditRecordsResponse
+	assert.NoError(s.T(), json.Unmarshal(w.Body.Bytes(), &resp))
+	assert.Equal(s.T(), "next", resp.NextCursor)
+	assert.Len(s.T(), resp.Records, 1)
+	assert.Equal(s.T(), record.Id, resp.Records[0].ID)
+}
+
+func (s *UserTestSuite) TestRequestMeta() {
+	server := newTestServer(s.T(), s.mockUserService, Options{AdminToken: testAdminToken})
+	var meta domain.RequestMeta
+	router := gin.New()
+	router.ContextWithFallback = true
+	router.Use(server.requestMeta)
+	router.GET("/meta", func(ctx *gin.Context) {
+		meta = domain.RequestMetaFromContext(ctx)
+	})
+
+	req := httptest.NewRequest("GET", "/meta", nil)
+	req.Header.Set(requestIDHeader, "req-42")
+	req.Header.Set(sessionIDHeader, "session-7")
+	req.Header.Set(adminTokenHeader, testAdminToken)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.Equal(s.T(), "req-42", w.Header().Get(requestIDHeader))
+	assert.Equal(s.T(), domain.RequestMeta{Actor: adminActor, RequestID: "req-42", SessionID: "session-7", ClientIP: "192.0.2.1"}, meta)
+
+	req = httptest.NewRequest("GET", "/meta", nil)
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+
+	assert.NotEmpty(s.T(), w.Header().Get(requestIDHeader))
+	assert.Equal(s.T(), domain.AnonymousActor, meta.Actor)
+	// without a session header the request is its own session
+	assert.Equal(s.T(), meta.RequestID, meta.SessionID)
+}
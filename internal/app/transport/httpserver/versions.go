@@ -0,0 +1,81 @@
+package httpserver
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+const (
+	apiVersionHeader = "API-Version"
+	userCodecKey     = "httpserver.userCodec"
+)
+
+// apiVersion is one mounted version of the API. A version owns its routes and
+// its user codec, so /v2 can change the wire format while /v1 keeps serving
+// the clients written against it.
+type apiVersion struct {
+	name     string
+	codec    userCodec
+	register func(api *gin.RouterGroup)
+}
+
+// userCodec is the user wire format of one API version. Handlers encode and
+// decode users through the codec of the version serving the request, see
+// codecFrom, so they can be registered again as they are under a new version.
+type userCodec interface {
+	// encodeUser is the representation of a user in responses, patches are
+	// applied to it as well
+	encodeUser(u domain.User) any
+	// decodeUser reads a user back from its representation and fails on
+	// unknown fields
+	decodeUser(doc []byte) (domain.User, error)
+	// newCreateRequest returns the body a create request is bound to
+	newCreateRequest() createRequest
+}
+
+// createRequest is the body of a user create request
+type createRequest interface {
+	user() domain.User
+	password() string
+}
+
+// codecFrom returns the codec of the version serving the request, v1 when the
+// handler was not mounted through mountVersions
+func codecFrom(ctx *gin.Context) userCodec {
+	if value, ok := ctx.Get(userCodecKey); ok {
+		return value.(userCodec)
+	}
+	return userCodecV1{}
+}
+
+// encodeUsers never returns nil, an empty page is [] on the wire
+func encodeUsers(codec userCodec, users []domain.User) []any {
+	out := make([]any, len(users))
+	for i, u := range users {
+		out[i] = codec.encodeUser(u)
+	}
+	return out
+}
+
+// versions lists the API versions served side by side. A new version adds its
+// routes file with its own DTOs and codec and an entry here, handlers that did
+// not change can be registered again as they are.
+func (server *HttpServer) versions() []apiVersion {
+	return []apiVersion{
+		{name: "v1", codec: userCodecV1{}, register: server.registerV1},
+	}
+}
+
+func mountVersions(router *gin.Engine, versions []apiVersion) {
+	for _, v := range versions {
+		v.register(router.Group(v.name, useVersion(v)))
+	}
+}
+
+func useVersion(v apiVersion) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Header(apiVersionHeader, v.name)
+		ctx.Set(userCodecKey, v.codec)
+		ctx.Next()
+	}
+}
@@ -0,0 +1,109 @@
+package httpserver
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"github.com/vlad19930514/webApp/internal/app/transport/httpserver/mocks"
+)
+
+func TestNewHttpServer_MountsV1(t *testing.T) {
+	mockUserService := mocks.NewMockIUserService(gomock.NewController(t))
+	server := newTestServer(t, mockUserService, Options{AdminToken: testAdminToken})
+
+	user := domain.User{Id: uuid.New(), FirstName: "Alice", LastName: "Smith", Email: "alice@example.com", Age: 30, Version: 1}
+	mockUserService.EXPECT().GetUser(gomock.Any(), user.Id).Return(user, nil)
+
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/user/"+user.Id.String(), nil))
+	require.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, "v1", w.Header().Get(apiVersionHeader))
+
+	var body map[string]any
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, user.Id.String(), body["id"])
+	assert.Equal(t, "Alice", body["first_name"])
+	assert.Equal(t, "Smith", body["last_name"])
+	assert.Contains(t, body, "created_at")
+	assert.NotContains(t, body, "Id")
+
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, httptest.NewRequest("GET", "/user/"+user.Id.String(), nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
+
+// userCodecV2 is a made up next version that merges the names
+type userCodecV2 struct{ userCodecV1 }
+
+type userResponseV2 struct {
+	ID       uuid.UUID `json:"id"`
+	FullName string    `json:"full_name"`
+}
+
+func (userCodecV2) encodeUser(u domain.User) any {
+	return userResponseV2{ID: u.Id, FullName: u.FirstName + " " + u.LastName}
+}
+
+func TestMountVersions_SideBySide(t *testing.T) {
+	mockUserService := mocks.NewMockIUserService(gomock.NewController(t))
+	server := newTestServer(t, mockUserService, Options{AdminToken: testAdminToken})
+
+	user := domain.User{Id: uuid.New(), FirstName: "Alice", LastName: "Smith"}
+	mockUserService.EXPECT().GetUser(gomock.Any(), user.Id).Return(user, nil).Times(2)
+
+	// the same handler serves both versions, each in its own wire format
+	router := gin.New()
+	mountVersions(router, []apiVersion{
+		{name: "v1", codec: userCodecV1{}, register: server.registerV1},
+		{name: "v2", codec: userCodecV2{}, register: func(api *gin.RouterGroup) { api.GET("user/:id", server.getUser) }},
+	})
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/v1/user/"+user.Id.String(), nil))
+	require.Equal(t, http.StatusAccepted, w.Code)
+	var v1 userResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &v1))
+	assert.Equal(t, "Alice", v1.FirstName)
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest("GET", "/v2/user/"+user.Id.String(), nil))
+	require.Equal(t, http.StatusAccepted, w.Code)
+	assert.Equal(t, "v2", w.Header().Get(apiVersionHeader))
+	var v2 userResponseV2
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &v2))
+	assert.Equal(t, "Alice Smith", v2.FullName)
+}
+
+func TestCreateUserV1_AcceptsLegacyNames(t *testing.T) {
+	mockUserService := mocks.NewMockIUserService(gomock.NewController(t))
+	server := newTestServer(t, mockUserService, Options{AdminToken: testAdminToken})
+
+	mockUserService.EXPECT().
+		CreateUser(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, u domain.User) (domain.User, error) {
+			assert.Equal(t, "Alice", u.FirstName)
+			assert.Equal(t, "Johnson", u.LastName)
+			return u, nil
+		}).Times(2)
+
+	for _, body := range []string{
+		`{"firstname":"Alice","lastname":"Johnson","email":"alice@example.com","age":28}`,
+		`{"first_name":"Alice","lastname":"Johnson","email":"alice@example.com","age":28}`,
+	} {
+		w := httptest.NewRecorder()
+		req := httptest.NewRequest("POST", "/v1/user", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		server.router.ServeHTTP(w, req)
+		require.Equal(t, http.StatusOK, w.Code, w.Body.String())
+	}
+}
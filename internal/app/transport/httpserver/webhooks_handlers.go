@@ -0,0 +1,167 @@
+package httpserver
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+type webhookRequest struct {
+	URL string `json:"url" binding:"required,http_url,max=2048"`
+	// Secret is generated on create and kept on update when empty
+	Secret     string   `json:"secret" binding:"omitempty,min=16,max=256"`
+	EventTypes []string `json:"event_types" binding:"dive,oneof=UserCreated UserUpdated UserDeleted"`
+	Fields     []string `json:"fields" binding:"dive,oneof=first_name last_name email age"`
+	// Active defaults to true
+	Active *bool `json:"active"`
+}
+
+func (req webhookRequest) webhook(id uuid.UUID) domain.WebhookSubscription {
+	webhook := domain.WebhookSubscription{
+		Id:     id,
+		URL:    req.URL,
+		Secret: req.Secret,
+		Fields: req.Fields,
+		Active: req.Active == nil || *req.Active,
+	}
+	for _, t := range req.EventTypes {
+		webhook.EventTypes = append(webhook.EventTypes, domain.UserEventType(t))
+	}
+	return webhook
+}
+
+func (server *HttpServer) createWebhook(ctx *gin.Context) {
+	var req webhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeBindError(ctx, err)
+		return
+	}
+
+	webhook, err := server.webhooks.CreateWebhook(ctx, req.webhook(uuid.Nil))
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	// the secret is only ever shown here
+	resp := newWebhookResponse(webhook)
+	resp.Secret = webhook.Secret
+	ctx.JSON(http.StatusCreated, resp)
+}
+
+type listWebhooksResponse struct {
+	Webhooks []webhookResponse `json:"webhooks"`
+}
+
+func (server *HttpServer) listWebhooks(ctx *gin.Context) {
+	webhooks, err := server.webhooks.ListWebhooks(ctx)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, listWebhooksResponse{Webhooks: newWebhookResponses(webhooks)})
+}
+
+func (server *HttpServer) getWebhook(ctx *gin.Context) {
+	id, ok := bindUserID(ctx)
+	if !ok {
+		return
+	}
+
+	webhook, err := server.webhooks.GetWebhook(ctx, id)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, newWebhookResponse(webhook))
+}
+
+func (server *HttpServer) updateWebhook(ctx *gin.Context) {
+	id, ok := bindUserID(ctx)
+	if !ok {
+		return
+	}
+	var req webhookRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		writeBindError(ctx, err)
+		return
+	}
+
+	webhook, err := server.webhooks.UpdateWebhook(ctx, req.webhook(id))
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, newWebhookResponse(webhook))
+}
+
+func (server *HttpServer) deleteWebhook(ctx *gin.Context) {
+	id, ok := bindUserID(ctx)
+	if !ok {
+		return
+	}
+
+	if err := server.webhooks.DeleteWebhook(ctx, id); err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.Status(http.StatusNoContent)
+}
+
+type listDeliveriesRequest struct {
+	Status string `form:"status" binding:"omitempty,oneof=pending succeeded dead"`
+	Limit  int    `form:"limit" binding:"omitempty,min=1,max=100"`
+	Cursor string `form:"cursor"`
+}
+
+type listDeliveriesResponse struct {
+	Deliveries []deliveryResponse `json:"deliveries"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
+
+func (server *HttpServer) listWebhookDeliveries(ctx *gin.Context) {
+	id, ok := bindUserID(ctx)
+	if !ok {
+		return
+	}
+	var req listDeliveriesRequest
+	if err := ctx.ShouldBindQuery(&req); err != nil {
+		writeBindError(ctx, err)
+		return
+	}
+
+	page, err := server.webhooks.ListWebhookDeliveries(ctx, domain.DeliveryQuery{
+		SubscriptionId: id,
+		Status:         domain.DeliveryStatus(req.Status),
+		Limit:          req.Limit,
+		Cursor:         req.Cursor,
+	})
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, listDeliveriesResponse{Deliveries: newDeliveryResponses(page.Deliveries), NextCursor: page.NextCursor})
+}
+
+type redeliverRequest struct {
+	ID         string `uri:"id" binding:"required,uuid"`
+	DeliveryID int64  `uri:"delivery_id" binding:"required,min=1"`
+}
+
+// redeliverWebhook queues the delivery again, the worker sends it on its next round
+func (server *HttpServer) redeliverWebhook(ctx *gin.Context) {
+	var req redeliverRequest
+	if err := ctx.ShouldBindUri(&req); err != nil {
+		writeBindError(ctx, err)
+		return
+	}
+	id, _ := uuid.Parse(req.ID)
+
+	delivery, err := server.webhooks.RedeliverWebhook(ctx, id, req.DeliveryID)
+	if err != nil {
+		writeError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusAccepted, newDeliveryResponse(delivery))
+}
@@ -0,0 +1,122 @@
+package httpserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	"github.com/vlad19930514/webApp/internal/app/transport/httpserver/mocks"
+)
+
+func adminRequest(server HttpServer, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, bytes.NewBufferString(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(adminTokenHeader, testAdminToken)
+	w := httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	return w
+}
+
+func TestCreateWebhook(t *testing.T) {
+	webhooks := mocks.NewMockIWebhookService(gomock.NewController(t))
+	server, _ := newMockServer(t, Options{AdminToken: testAdminToken, Webhooks: webhooks})
+
+	webhooks.EXPECT().
+		CreateWebhook(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ any, webhook domain.WebhookSubscription) (domain.WebhookSubscription, error) {
+			assert.Equal(t, "https://example.com/hook", webhook.URL)
+			assert.Equal(t, []domain.UserEventType{domain.UserUpdated}, webhook.EventTypes)
+			assert.Equal(t, []string{"email"}, webhook.Fields)
+			assert.True(t, webhook.Active)
+			webhook.Id = uuid.New()
+			webhook.Secret = "generated"
+			return webhook, nil
+		})
+
+	w := adminRequest(server, "POST", "/v1/admin/webhooks", `{"url":"https://example.com/hook","event_types":["UserUpdated"],"fields":["email"]}`)
+	require.Equal(t, http.StatusCreated, w.Code)
+	var body webhookResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, "generated", body.Secret)
+	assert.Equal(t, []string{"UserUpdated"}, body.EventTypes)
+}
+
+func TestCreateWebhook_Invalid(t *testing.T) {
+	webhooks := mocks.NewMockIWebhookService(gomock.NewController(t))
+	server, _ := newMockServer(t, Options{AdminToken: testAdminToken, Webhooks: webhooks})
+
+	w := adminRequest(server, "POST", "/v1/admin/webhooks", `{"url":"ftp://example.com","event_types":["UserRenamed"]}`)
+	require.Equal(t, http.StatusBadRequest, w.Code)
+	var body problem
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	assert.Equal(t, codeInvalidRequest, body.Code)
+
+	// admin routes need the token
+	req := httptest.NewRequest("GET", "/v1/admin/webhooks", nil)
+	w = httptest.NewRecorder()
+	server.router.ServeHTTP(w, req)
+	assert.Equal(t, http.StatusForbidden, w.Code)
+}
+
+func TestGetWebhook_HidesSecret(t *testing.T) {
+	webhooks := mocks.NewMockIWebhookService(gomock.NewController(t))
+	server, _ := newMockServer(t, Options{AdminToken: testAdminToken, Webhooks: webhooks})
+
+	webhook := domain.WebhookSubscription{Id: uuid.New(), URL: "https://example.com/hook", Secret: "s3cret", Active: true}
+	webhooks.EXPECT().GetWebhook(gomock.Any(), webhook.Id).Return(webhook, nil)
+
+	w := adminRequest(server, "GET", "/v1/admin/webhooks/"+webhook.Id.String(), "")
+	require.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "s3cret")
+}
+
+func TestListWebhookDeliveries(t *testing.T) {
+	webhooks := mocks.NewMockIWebhookService(gomock.NewController(t))
+	server, _ := newMockServer(t, Options{AdminToken: testAdminToken, Webhooks: webhooks})
+
+	id := uuid.New()
+	webhooks.EXPECT().
+		ListWebhookDeliveries(gomock.Any(), domain.DeliveryQuery{SubscriptionId: id, Status: domain.DeliveryDead, Limit: 10}).
+		Return(domain.DeliveryPage{
+			Deliveries: []domain.WebhookDelivery{{Id: 7, SubscriptionId: id, EventId: 3, EventType: domain.UserCreated,
+				Status: domain.DeliveryDead, Attempts: 8, LastStatusCode: 500, NextAttemptAt: time.Now()}},
+			NextCursor: "next",
+		}, nil)
+
+	w := adminRequest(server, "GET", "/v1/admin/webhooks/"+id.String()+"/deliveries?status=dead&limit=10", "")
+	require.Equal(t, http.StatusOK, w.Code)
+	var body listDeliveriesResponse
+	require.NoError(t, json.Unmarshal(w.Body.Bytes(), &body))
+	require.Len(t, body.Deliveries, 1)
+	assert.Equal(t, "dead", body.Deliveries[0].Status)
+	assert.Equal(t, 500, body.Deliveries[0].LastStatusCode)
+	assert.Equal(t, "next", body.NextCursor)
+}
+
+func TestRedeliverWebhook(t *testing.T) {
+	webhooks := mocks.NewMockIWebhookService(gomock.NewController(t))
+	server, _ := newMockServer(t, Options{AdminToken: testAdminToken, Webhooks: webhooks})
+
+	id := uuid.New()
+	webhooks.EXPECT().
+		RedeliverWebhook(gomock.Any(), id, int64(7)).
+		Return(domain.WebhookDelivery{Id: 7, SubscriptionId: id, Status: domain.DeliveryPending}, nil)
+	webhooks.EXPECT().
+		RedeliverWebhook(gomock.Any(), id, int64(8)).
+		Return(domain.WebhookDelivery{}, domain.ErrNotFound)
+
+	w := adminRequest(server, "POST", "/v1/admin/webhooks/"+id.String()+"/deliveries/7/redeliver", "")
+	require.Equal(t, http.StatusAccepted, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"pending"`)
+
+	w = adminRequest(server, "POST", "/v1/admin/webhooks/"+id.String()+"/deliveries/8/redeliver", "")
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
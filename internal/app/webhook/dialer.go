@@ -0,0 +1,39 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"syscall"
+	"time"
+)
+
+// ErrForbiddenAddress fails the attempts whose host resolves into the internal network
+var ErrForbiddenAddress = errors.New("webhook host is not a public address")
+
+// newTransport dials only the addresses that allowed accepts. The check runs
+// on the address actually connected to, after DNS resolution, so a name that
+// resolves to an internal address, or starts to, is refused as well. There is
+// no proxy, it would dial on the worker's behalf.
+func newTransport(allowed func(netip.Addr) bool) *http.Transport {
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(_, address string, _ syscall.RawConn) error {
+			addr, err := netip.ParseAddrPort(address)
+			if err != nil {
+				return err
+			}
+			if !allowed(addr.Addr()) {
+				return fmt.Errorf("%w: %s", ErrForbiddenAddress, addr.Addr())
+			}
+			return nil
+		},
+	}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return transport
+}
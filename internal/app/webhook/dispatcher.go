@@ -0,0 +1,67 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+// Store keeps subscriptions and their deliveries
+type Store interface {
+	ListWebhooks(ctx context.Context) ([]domain.WebhookSubscription, error)
+	GetWebhook(ctx context.Context, id uuid.UUID) (domain.WebhookSubscription, error)
+	AddWebhookDeliveries(ctx context.Context, deliveries []domain.WebhookDelivery) error
+	ClaimDueDeliveries(ctx context.Context, now, leaseUntil time.Time, limit int) ([]domain.WebhookDelivery, error)
+	UpdateWebhookDelivery(ctx context.Context, delivery domain.WebhookDelivery) error
+}
+
+// Dispatcher is the outbox publisher of webhooks. It only queues a delivery
+// per matching subscription, inside a savepoint of the relay transaction, the
+// Worker sends them. When the event fails on another publisher its deliveries
+// are rolled back and queued once it goes through. Neither runs on the path
+// of a UserService call.
+type Dispatcher struct {
+	store Store
+}
+
+func NewDispatcher(store Store) *Dispatcher {
+	return &Dispatcher{store: store}
+}
+
+func (d *Dispatcher) Publish(ctx context.Context, event domain.OutboxEvent) error {
+	webhooks, err := d.store.ListWebhooks(ctx)
+	if err != nil {
+		return err
+	}
+
+	var (
+		deliveries []domain.WebhookDelivery
+		payload    []byte
+		now        = time.Now()
+	)
+	for _, webhook := range webhooks {
+		if !webhook.Match(event) {
+			continue
+		}
+		if payload == nil {
+			if payload, err = json.Marshal(event); err != nil {
+				return fmt.Errorf("failed to encode user event: %w", err)
+			}
+		}
+		deliveries = append(deliveries, domain.WebhookDelivery{
+			SubscriptionId: webhook.Id,
+			EventId:        event.Id,
+			EventType:      event.Type,
+			Payload:        payload,
+			Status:         domain.DeliveryPending,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+			UpdatedAt:      now,
+		})
+	}
+	return d.store.AddWebhookDeliveries(ctx, deliveries)
+}
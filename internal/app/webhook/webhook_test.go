@@ -0,0 +1,202 @@
+package webhook
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+	sqlitemigrations "github.com/vlad19930514/webApp/internal/app/migrations/sqlite"
+	"github.com/vlad19930514/webApp/internal/app/outbox"
+	"github.com/vlad19930514/webApp/internal/app/repository/memrepo"
+	"github.com/vlad19930514/webApp/internal/app/repository/sqliterepo"
+	"github.com/vlad19930514/webApp/internal/pkg/sqlite"
+)
+
+func TestSignature(t *testing.T) {
+	now := time.Now()
+	body := []byte(`{"id":1}`)
+	header := Sign("secret", now, body)
+
+	require.NoError(t, Verify("secret", header, body, now, time.Minute))
+	assert.ErrorIs(t, Verify("other", header, body, now, time.Minute), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("secret", header, []byte(`{"id":2}`), now, time.Minute), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("secret", header, body, now.Add(time.Hour), time.Minute), ErrInvalidSignature)
+	assert.ErrorIs(t, Verify("secret", "v1=abc", body, now, time.Minute), ErrInvalidSignature)
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt := 1; attempt <= 30; attempt++ {
+		d := Backoff(attempt)
+		assert.Greater(t, d, time.Duration(0))
+		assert.LessOrEqual(t, d, maxBackoff)
+	}
+	assert.Greater(t, Backoff(3), baseBackoff)
+}
+
+// receiver answers with the status codes in turn and checks every signature
+type receiver struct {
+	t        *testing.T
+	statuses []int
+	calls    atomic.Int32
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := io.ReadAll(req.Body)
+	assert.NoError(r.t, Verify("secret", req.Header.Get(SignatureHeader), body, time.Now(), time.Minute))
+	assert.Equal(r.t, string(domain.UserCreated), req.Header.Get(EventHeader))
+	assert.NotEmpty(r.t, req.Header.Get(DeliveryHeader))
+	call := int(r.calls.Add(1)) - 1
+	w.WriteHeader(r.statuses[min(call, len(r.statuses)-1)])
+}
+
+func setup(t *testing.T, statuses ...int) (*memrepo.WebhookRepo, *Worker, *receiver, uuid.UUID) {
+	rcv := &receiver{t: t, statuses: statuses}
+	srv := httptest.NewServer(rcv)
+	t.Cleanup(srv.Close)
+
+	store := memrepo.NewWebhookRepo()
+	webhook, err := store.CreateWebhook(context.Background(), domain.WebhookSubscription{
+		Id: uuid.New(), URL: srv.URL, Secret: "secret", Active: true, CreatedAt: time.Now(),
+	})
+	require.NoError(t, err)
+
+	event := domain.NewUserEvent(domain.UserCreated, domain.User{Id: uuid.New(), Email: "alice@example.com"})
+	event.Id = 1
+	require.NoError(t, NewDispatcher(store).Publish(context.Background(), event))
+
+	worker := NewWorker(store, time.Second, 3, time.Millisecond)
+	// retries are due right away so the test does not wait for them
+	worker.backoff = func(int) time.Duration { return 0 }
+	// the receiver listens on loopback
+	worker.allowed = func(netip.Addr) bool { return true }
+	return store, worker, rcv, webhook.Id
+}
+
+func deliveries(t *testing.T, store *memrepo.WebhookRepo, id uuid.UUID) []domain.WebhookDelivery {
+	page, err := store.ListWebhookDeliveries(context.Background(), domain.DeliveryQuery{SubscriptionId: id, Limit: 10})
+	require.NoError(t, err)
+	return page.Deliveries
+}
+
+func TestWorker_Delivers(t *testing.T) {
+	store, worker, rcv, id := setup(t, http.StatusOK)
+
+	n, err := worker.DeliverOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.EqualValues(t, 1, rcv.calls.Load())
+
+	got := deliveries(t, store, id)
+	require.Len(t, got, 1)
+	assert.Equal(t, domain.DeliverySucceeded, got[0].Status)
+	assert.Equal(t, 1, got[0].Attempts)
+
+	// a succeeded delivery is not sent again
+	n, err = worker.DeliverOnce(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+}
+
+func TestWorker_RetriesThenDeadLetters(t *testing.T) {
+	store, worker, rcv, id := setup(t, http.StatusInternalServerError)
+
+	for range 3 {
+		_, err := worker.DeliverOnce(context.Background())
+		require.NoError(t, err)
+	}
+	got := deliveries(t, store, id)
+	require.Len(t, got, 1)
+	assert.Equal(t, domain.DeliveryDead, got[0].Status)
+	assert.Equal(t, 3, got[0].Attempts)
+	assert.Equal(t, http.StatusInternalServerError, got[0].LastStatusCode)
+
+	n, err := worker.DeliverOnce(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+	assert.EqualValues(t, 3, rcv.calls.Load())
+
+	// a redelivered dead letter gets another round of attempts
+	got[0].Redeliver(time.Now())
+	require.NoError(t, store.UpdateWebhookDelivery(context.Background(), got[0]))
+	n, err = worker.DeliverOnce(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, domain.DeliveryPending, deliveries(t, store, id)[0].Status)
+}
+
+func TestWorker_RefusesInternalAddresses(t *testing.T) {
+	store, worker, rcv, id := setup(t, http.StatusOK)
+	worker.allowed = domain.PublicAddr
+
+	_, err := worker.DeliverOnce(context.Background())
+	require.NoError(t, err)
+	assert.Zero(t, rcv.calls.Load())
+	got := deliveries(t, store, id)
+	require.Len(t, got, 1)
+	assert.Equal(t, domain.DeliveryPending, got[0].Status)
+	assert.Contains(t, got[0].LastError, ErrForbiddenAddress.Error())
+}
+
+func TestWorker_RecoversAfterFailure(t *testing.T) {
+	store, worker, rcv, id := setup(t, http.StatusServiceUnavailable, http.StatusNoContent)
+
+	for range 2 {
+		_, err := worker.DeliverOnce(context.Background())
+		require.NoError(t, err)
+	}
+	got := deliveries(t, store, id)
+	require.Len(t, got, 1)
+	assert.Equal(t, domain.DeliverySucceeded, got[0].Status)
+	assert.Equal(t, 2, got[0].Attempts)
+	assert.Empty(t, got[0].LastError)
+	assert.EqualValues(t, 2, rcv.calls.Load())
+}
+
+// failingPublisher fails the events of one user
+type failingPublisher struct {
+	failFor uuid.UUID
+}
+
+func (p failingPublisher) Publish(_ context.Context, event domain.OutboxEvent) error {
+	if event.AggregateId == p.failFor {
+		return errors.New("broker unavailable")
+	}
+	return nil
+}
+
+func TestDispatcher_RolledBackWithFailedEvent(t *testing.T) {
+	ctx := context.Background()
+	db, err := sqlite.Open(":memory:")
+	require.NoError(t, err)
+	require.NoError(t, sqlite.Migrate(ctx, db, sqlitemigrations.FS))
+	events, store := sqliterepo.NewOutboxRepo(db), sqliterepo.NewWebhookRepo(db)
+
+	now := time.Now()
+	webhook, err := store.CreateWebhook(ctx, domain.WebhookSubscription{Id: uuid.New(), URL: "https://example.com/hook", Secret: "secret", Active: true, CreatedAt: now, UpdatedAt: now})
+	require.NoError(t, err)
+	alice, bob := domain.User{Id: uuid.New()}, domain.User{Id: uuid.New()}
+	require.NoError(t, events.AddOutboxEvent(ctx, domain.NewUserEvent(domain.UserCreated, alice)))
+	require.NoError(t, events.AddOutboxEvent(ctx, domain.NewUserEvent(domain.UserCreated, bob)))
+
+	// alice's event fails after the dispatcher queued it, the relay runs it again
+	relay := outbox.NewRelay(events, outbox.FanOut{NewDispatcher(store), failingPublisher{failFor: alice.Id}}, sqlite.NewTxManager(db), 0)
+	for range 2 {
+		_, err := relay.RelayOnce(ctx)
+		require.NoError(t, err)
+	}
+
+	page, err := store.ListWebhookDeliveries(ctx, domain.DeliveryQuery{SubscriptionId: webhook.Id, Limit: 10})
+	require.NoError(t, err)
+	require.Len(t, page.Deliveries, 1)
+	assert.Equal(t, int64(2), page.Deliveries[0].EventId)
+}
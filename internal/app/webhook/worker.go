@@ -0,0 +1,165 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"math/rand/v2"
+	"net/http"
+	"net/netip"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog/log"
+	"github.com/vlad19930514/webApp/internal/app/domain"
+)
+
+const (
+	DefaultMaxAttempts = 8
+	DefaultTimeout     = 10 * time.Second
+	DefaultInterval    = time.Second
+	DefaultBatchSize   = 20
+
+	baseBackoff = 10 * time.Second
+	maxBackoff  = 6 * time.Hour
+	// maxResponseBody is how much of an answer is read, receivers only have to acknowledge
+	maxResponseBody = 64 << 10
+)
+
+// Worker sends the due deliveries. Every attempt is recorded on the delivery,
+// failed ones are retried with exponential backoff until the delivery is dead.
+type Worker struct {
+	store       Store
+	client      *http.Client
+	maxAttempts int
+	interval    time.Duration
+	batchSize   int
+	backoff     func(attempt int) time.Duration
+	now         func() time.Time
+	// allowed are the addresses deliveries may be sent to
+	allowed func(netip.Addr) bool
+}
+
+func NewWorker(store Store, timeout time.Duration, maxAttempts int, interval time.Duration) *Worker {
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	if maxAttempts <= 0 {
+		maxAttempts = DefaultMaxAttempts
+	}
+	if interval <= 0 {
+		interval = DefaultInterval
+	}
+	w := &Worker{
+		store:       store,
+		maxAttempts: maxAttempts,
+		interval:    interval,
+		batchSize:   DefaultBatchSize,
+		backoff:     Backoff,
+		now:         time.Now,
+		allowed:     domain.PublicAddr,
+	}
+	w.client = &http.Client{
+		Timeout:   timeout,
+		Transport: newTransport(func(ip netip.Addr) bool { return w.allowed(ip) }),
+		// a redirect is an answer of its own, the payload is not sent anywhere else
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return w
+}
+
+// Backoff doubles the wait after every failed attempt, up to six hours. Up to
+// a fifth of it is cut off at random so failed receivers are not hit in waves.
+func Backoff(attempt int) time.Duration {
+	d := maxBackoff
+	if attempt < 20 {
+		d = min(baseBackoff<<max(attempt-1, 0), maxBackoff)
+	}
+	return d - rand.N(d/5)
+}
+
+// Run sends deliveries until ctx is cancelled
+func (w *Worker) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		n, err := w.DeliverOnce(ctx)
+		if err != nil {
+			log.Error().Err(err).Msg("webhook delivery failed")
+		}
+		// a full batch means there is probably more waiting
+		if err == nil && n == w.batchSize {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// DeliverOnce sends one batch of due deliveries side by side and returns how many were attempted
+func (w *Worker) DeliverOnce(ctx context.Context) (int, error) {
+	now := w.now()
+	// a delivery whose outcome never gets recorded is tried again after the lease
+	lease := now.Add(w.client.Timeout + time.Minute)
+	deliveries, err := w.store.ClaimDueDeliveries(ctx, now, lease, w.batchSize)
+	if err != nil {
+		return 0, fmt.Errorf("failed to claim webhook deliveries: %w", err)
+	}
+
+	webhooks := make(map[uuid.UUID]domain.WebhookSubscription)
+	var wg sync.WaitGroup
+	for _, delivery := range deliveries {
+		webhook, ok := webhooks[delivery.SubscriptionId]
+		if !ok {
+			if webhook, err = w.store.GetWebhook(ctx, delivery.SubscriptionId); err != nil {
+				log.Warn().Err(err).Int64("delivery_id", delivery.Id).Msg("failed to read webhook")
+				continue
+			}
+			webhooks[webhook.Id] = webhook
+		}
+
+		wg.Add(1)
+		go func(delivery domain.WebhookDelivery) {
+			defer wg.Done()
+			attempt := w.send(ctx, webhook, delivery)
+			if ctx.Err() != nil {
+				// shutting down is not the receiver's fault, the lease brings it back
+				return
+			}
+			delivery.Record(attempt, w.now(), w.maxAttempts, w.backoff(delivery.Attempts+1))
+			if err := w.store.UpdateWebhookDelivery(ctx, delivery); err != nil {
+				log.Warn().Err(err).Int64("delivery_id", delivery.Id).Msg("failed to record webhook attempt")
+			}
+		}(delivery)
+	}
+	wg.Wait()
+	return len(deliveries), nil
+}
+
+func (w *Worker) send(ctx context.Context, webhook domain.WebhookSubscription, delivery domain.WebhookDelivery) domain.DeliveryAttempt {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(delivery.Payload))
+	if err != nil {
+		return domain.DeliveryAttempt{Err: err}
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(DeliveryHeader, fmt.Sprint(delivery.Id))
+	req.Header.Set(EventHeader, string(delivery.EventType))
+	req.Header.Set(SignatureHeader, Sign(webhook.Secret, w.now(), delivery.Payload))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return domain.DeliveryAttempt{Err: err}
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBody))
+	return domain.DeliveryAttempt{StatusCode: resp.StatusCode}
+}
@@ -0,0 +1,43 @@
+// Package gormtx carries a gorm transaction in the context. It is shared by
+// the postgres and sqlite storages, their TxManager and Conn build on it.
+package gormtx
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+type txKey struct{}
+
+// From returns the transaction bound to ctx
+func From(ctx context.Context) (*gorm.DB, bool) {
+	tx, ok := ctx.Value(txKey{}).(*gorm.DB)
+	if !ok {
+		return nil, false
+	}
+	return tx.WithContext(ctx), true
+}
+
+// Within runs fn in a transaction of db and commits if fn returns nil, rolls
+// back otherwise. When ctx already carries a transaction fn runs in a
+// savepoint of it instead, so a failure only rolls back fn's own statements
+// and the outer transaction can go on.
+func Within(ctx context.Context, db *gorm.DB, fn func(ctx context.Context) error) error {
+	if tx, ok := From(ctx); ok {
+		db = tx
+	} else {
+		db = db.WithContext(ctx)
+	}
+	return db.Transaction(func(tx *gorm.DB) error {
+		return fn(context.WithValue(ctx, txKey{}, tx))
+	})
+}
+
+// Conn returns the transaction bound to ctx, or db when there is none
+func Conn(ctx context.Context, db *gorm.DB) *gorm.DB {
+	if tx, ok := From(ctx); ok {
+		return tx
+	}
+	return db.WithContext(ctx)
+}
@@ -0,0 +1,69 @@
+package pg
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/stdlib"
+	"github.com/rs/zerolog/log"
+)
+
+// listenRetryDelay is the pause before a lost LISTEN connection is opened again
+const listenRetryDelay = time.Second
+
+// Listen passes the payload of every notification on channel to handle until
+// ctx is done. It holds one connection of the primary pool and reconnects when
+// the connection is lost. Notifications sent while it reconnects are not
+// delivered, onListen runs after every LISTEN so the caller can catch up.
+func (db *DB) Listen(ctx context.Context, channel string, onListen func(ctx context.Context), handle func(payload string)) {
+	for {
+		err := db.listen(ctx, channel, onListen, handle)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Warn().Err(err).Str("channel", channel).Msg("listen connection lost")
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetryDelay):
+		}
+	}
+}
+
+func (db *DB) listen(ctx context.Context, channel string, onListen func(ctx context.Context), handle func(payload string)) error {
+	sqlDB, err := db.DB.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get listen connection: %w", err)
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		stdConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return errors.New("listen needs a pgx connection")
+		}
+		pgxConn := stdConn.Conn()
+		if _, err := pgxConn.Exec(ctx, "LISTEN "+pgx.Identifier{channel}.Sanitize()); err != nil {
+			return fmt.Errorf("unable to listen: %w", err)
+		}
+		// the connection goes back to the pool, it must not keep listening there
+		defer pgxConn.Exec(context.WithoutCancel(ctx), "UNLISTEN *")
+
+		onListen(ctx)
+		for {
+			notification, err := pgxConn.WaitForNotification(ctx)
+			if err != nil {
+				return err
+			}
+			handle(notification.Payload)
+		}
+	})
+}
@@ -0,0 +1,173 @@
+package pg
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/postgres"
+	"github.com/golang-migrate/migrate/v4/source/iofs"
+)
+
+// migrationLockID is the key of the advisory lock that serializes migrations
+// when several replicas start at the same time.
+const migrationLockID int64 = 7_301_946_205
+
+// Migrator applies golang-migrate migrations from an fs.FS
+type Migrator struct {
+	db     *sql.DB
+	source fs.FS
+}
+
+// MigrationStatus is the schema version currently recorded in the database
+// and the latest one the migrations source knows of
+type MigrationStatus struct {
+	Version uint
+	Dirty   bool
+	Applied bool
+	Latest  uint
+}
+
+// Current tells whether every migration of the source is applied
+func (s MigrationStatus) Current() bool {
+	return s.Applied && !s.Dirty && s.Version >= s.Latest
+}
+
+// NewMigrator opens a separate lib/pq connection pool, golang-migrate
+// understands its errors better than the pgx ones used by gorm.
+func NewMigrator(dsn string, source fs.FS) (*Migrator, error) {
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open migration connection: %w", err)
+	}
+	return &Migrator{db: db, source: source}, nil
+}
+
+func (m *Migrator) Close() error {
+	return m.db.Close()
+}
+
+// Up applies all pending migrations
+func (m *Migrator) Up(ctx context.Context) error {
+	return m.run(ctx, func(mg *migrate.Migrate) error {
+		return ignoreNoChange(mg.Up())
+	})
+}
+
+// Down reverts the given number of migrations
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	if steps < 1 {
+		return fmt.Errorf("invalid number of steps %d", steps)
+	}
+	return m.run(ctx, func(mg *migrate.Migrate) error {
+		return ignoreNoChange(mg.Steps(-steps))
+	})
+}
+
+// DownAll reverts every migration, which drops all tables
+func (m *Migrator) DownAll(ctx context.Context) error {
+	return m.run(ctx, func(mg *migrate.Migrate) error {
+		return ignoreNoChange(mg.Down())
+	})
+}
+
+// Force sets the version without running migrations, used to recover a dirty state
+func (m *Migrator) Force(ctx context.Context, version int) error {
+	return m.run(ctx, func(mg *migrate.Migrate) error {
+		return mg.Force(version)
+	})
+}
+
+func (m *Migrator) Status(ctx context.Context) (MigrationStatus, error) {
+	latest, err := latestVersion(m.source)
+	if err != nil {
+		return MigrationStatus{}, err
+	}
+	status := MigrationStatus{Latest: latest}
+	err = m.run(ctx, func(mg *migrate.Migrate) error {
+		version, dirty, err := mg.Version()
+		if errors.Is(err, migrate.ErrNilVersion) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		status.Version, status.Dirty, status.Applied = version, dirty, true
+		return nil
+	})
+	return status, err
+}
+
+// run executes fn while holding the migration advisory lock. The lock is
+// session level, so the lock and the migration share one connection.
+func (m *Migrator) run(ctx context.Context, fn func(mg *migrate.Migrate) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("unable to get migration connection: %w", err)
+	}
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", migrationLockID); err != nil {
+		conn.Close()
+		return fmt.Errorf("unable to acquire migration lock: %w", err)
+	}
+
+	driver, err := postgres.WithConnection(ctx, conn, &postgres.Config{})
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("unable to create migration driver: %w", err)
+	}
+	source, err := iofs.New(m.source, ".")
+	if err != nil {
+		driver.Close()
+		return fmt.Errorf("unable to read migrations: %w", err)
+	}
+	mg, err := migrate.NewWithInstance("iofs", source, "postgres", driver)
+	if err != nil {
+		source.Close()
+		driver.Close()
+		return fmt.Errorf("unable to create migrator: %w", err)
+	}
+	// closing the driver closes conn and with it releases the lock
+	defer mg.Close()
+
+	err = fn(mg)
+
+	if _, unlockErr := conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", migrationLockID); unlockErr != nil && err == nil {
+		err = fmt.Errorf("unable to release migration lock: %w", unlockErr)
+	}
+	return err
+}
+
+// latestVersion returns the version of the last migration in source
+func latestVersion(source fs.FS) (uint, error) {
+	driver, err := iofs.New(source, ".")
+	if err != nil {
+		return 0, fmt.Errorf("unable to read migrations: %w", err)
+	}
+	defer driver.Close()
+
+	version, err := driver.First()
+	if err != nil {
+		return 0, fmt.Errorf("unable to read migrations: %w", err)
+	}
+	for {
+		next, err := driver.Next(version)
+		if errors.Is(err, fs.ErrNotExist) {
+			return version, nil
+		}
+		if err != nil {
+			return 0, fmt.Errorf("unable to read migrations: %w", err)
+		}
+		version = next
+	}
+}
+
+func ignoreNoChange(err error) error {
+	if errors.Is(err, migrate.ErrNoChange) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,27 @@
+package pg
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLatestVersion(t *testing.T) {
+	source := fstest.MapFS{
+		"000001_add_users.up.sql":      {Data: []byte("SELECT 1")},
+		"000001_add_users.down.sql":    {Data: []byte("SELECT 1")},
+		"000010_add_sessions.up.sql":   {Data: []byte("SELECT 1")},
+		"000002_add_search.up.sql":     {Data: []byte("SELECT 1")},
+		"000010_add_sessions.down.sql": {Data: []byte("SELECT 1")},
+	}
+	version, err := latestVersion(source)
+	require.NoError(t, err)
+	assert.Equal(t, uint(10), version)
+
+	assert.True(t, MigrationStatus{Version: 10, Applied: true, Latest: 10}.Current())
+	assert.False(t, MigrationStatus{Version: 2, Applied: true, Latest: 10}.Current())
+	assert.False(t, MigrationStatus{Version: 10, Dirty: true, Applied: true, Latest: 10}.Current())
+	assert.False(t, MigrationStatus{Latest: 10}.Current())
+}
@@ -1,17 +1,54 @@
 package pg
 
 import (
+	"context"
 	"fmt"
+	"sync/atomic"
+	"time"
 
+	"github.com/rs/zerolog/log"
+	"github.com/vlad19930514/webApp/internal/pkg/gormtx"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
 )
 
+const (
+	DefaultReadYourWritesWindow = 5 * time.Second
+	DefaultHealthCheckInterval  = 10 * time.Second
+)
+
+// Options configure read replicas, the zero value gives a single primary
+type Options struct {
+	// ReplicaDSNs are the read replicas, reads fall back to the primary when none is healthy
+	ReplicaDSNs []string
+	// ReadYourWritesWindow is how long reads of a session stay on the primary after it wrote
+	ReadYourWritesWindow time.Duration
+	// HealthCheckInterval is how often replicas are pinged
+	HealthCheckInterval time.Duration
+	// SessionKey identifies the session of a context for read-your-writes,
+	// contexts with an empty key are only pinned to the primary inside a transaction
+	SessionKey func(ctx context.Context) string
+}
+
+// DB embeds the primary. Writes go through Conn, reads that tolerate
+// replication lag go through Reader.
 type DB struct {
 	*gorm.DB
+
+	replicas   []*replica
+	next       atomic.Uint64
+	writes     *writeTracker
+	sessionKey func(ctx context.Context) string
+	stop       chan struct{}
 }
 
-func Dial(dsn string) (*DB, error) {
+type replica struct {
+	index   int // identifies the replica in logs without leaking its DSN
+	db      *gorm.DB
+	healthy atomic.Bool
+}
+
+func Dial(dsn string, opts Options) (*DB, error) {
 
 	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
 	if err != nil {
@@ -19,5 +56,128 @@ func Dial(dsn string) (*DB, error) {
 		return nil, err
 	}
 
-	return &DB{db}, nil
+	pgDB := newDB(db, opts)
+	for i, replicaDSN := range opts.ReplicaDSNs {
+		// a replica that is down must not prevent the start, the health check picks it up later
+		replicaDB, err := gorm.Open(postgres.Open(replicaDSN), &gorm.Config{DisableAutomaticPing: true})
+		if err != nil {
+			pgDB.Close()
+			return nil, fmt.Errorf("unable to create replica connection pool: %w", err)
+		}
+		pgDB.replicas = append(pgDB.replicas, &replica{index: i, db: replicaDB})
+	}
+
+	if len(pgDB.replicas) > 0 {
+		pgDB.checkReplicas(context.Background())
+		go pgDB.healthLoop(opts.HealthCheckInterval)
+	}
+	return pgDB, nil
+}
+
+func newDB(db *gorm.DB, opts Options) *DB {
+	window := opts.ReadYourWritesWindow
+	if window <= 0 {
+		window = DefaultReadYourWritesWindow
+	}
+	return &DB{
+		DB:         db,
+		writes:     newWriteTracker(window),
+		sessionKey: opts.SessionKey,
+		stop:       make(chan struct{}),
+	}
+}
+
+// Close stops the health checks and closes every pool
+func (db *DB) Close() error {
+	close(db.stop)
+	for _, r := range db.replicas {
+		if sqlDB, err := r.db.DB(); err == nil {
+			sqlDB.Close()
+		}
+	}
+	sqlDB, err := db.DB.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
+
+// Reader returns a connection for reads: the transaction bound to ctx, the
+// primary while the session is inside its read-your-writes window, otherwise
+// the next healthy replica.
+func (db *DB) Reader(ctx context.Context) *gorm.DB {
+	if tx, ok := gormtx.From(ctx); ok {
+		return tx
+	}
+	return db.reader(ctx).WithContext(ctx)
+}
+
+func (db *DB) reader(ctx context.Context) *gorm.DB {
+	if len(db.replicas) == 0 || db.writes.recent(db.session(ctx), time.Now()) {
+		return db.DB
+	}
+	start := db.next.Add(1)
+	for i := range db.replicas {
+		r := db.replicas[(start+uint64(i))%uint64(len(db.replicas))]
+		if r.healthy.Load() {
+			return r.db
+		}
+	}
+	return db.DB
+}
+
+func (db *DB) session(ctx context.Context) string {
+	if db.sessionKey == nil {
+		return ""
+	}
+	return db.sessionKey(ctx)
+}
+
+// markWrite pins the session of ctx to the primary for the read-your-writes window
+func (db *DB) markWrite(ctx context.Context) {
+	if len(db.replicas) == 0 {
+		return
+	}
+	db.writes.mark(db.session(ctx), time.Now())
+}
+
+func (db *DB) healthLoop(interval time.Duration) {
+	if interval <= 0 {
+		interval = DefaultHealthCheckInterval
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-db.stop:
+			return
+		case <-ticker.C:
+			db.checkReplicas(context.Background())
+		}
+	}
+}
+
+// checkReplicas pings every replica, a failing one is out of rotation until it answers again
+func (db *DB) checkReplicas(ctx context.Context) {
+	for _, r := range db.replicas {
+		err := ping(ctx, r.db)
+		healthy := err == nil
+		if r.healthy.Swap(healthy) != healthy {
+			if healthy {
+				log.Info().Int("replica", r.index).Msg("replica is back in rotation")
+			} else {
+				log.Warn().Err(err).Int("replica", r.index).Msg("replica is out of rotation")
+			}
+		}
+	}
+}
+
+func ping(ctx context.Context, db *gorm.DB) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
 }
@@ -0,0 +1,85 @@
+package pg
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type sessionKey struct{}
+
+// unreachable opens a pool that never connects, routing only compares pools
+func unreachable(t *testing.T) *gorm.DB {
+	t.Helper()
+	db, err := gorm.Open(postgres.Open("host=127.0.0.1 port=1 connect_timeout=1 sslmode=disable"), &gorm.Config{DisableAutomaticPing: true})
+	require.NoError(t, err)
+	return db
+}
+
+func newTestDB(t *testing.T, replicas int) *DB {
+	db := newDB(unreachable(t), Options{
+		ReadYourWritesWindow: time.Minute,
+		SessionKey: func(ctx context.Context) string {
+			key, _ := ctx.Value(sessionKey{}).(string)
+			return key
+		},
+	})
+	for i := 0; i < replicas; i++ {
+		r := &replica{index: i, db: unreachable(t)}
+		r.healthy.Store(true)
+		db.replicas = append(db.replicas, r)
+	}
+	return db
+}
+
+func TestDB_ReaderRoundRobin(t *testing.T) {
+	db := newTestDB(t, 2)
+	ctx := context.Background()
+
+	first := db.reader(ctx)
+	second := db.reader(ctx)
+	assert.NotSame(t, db.DB, first)
+	assert.NotSame(t, db.DB, second)
+	assert.NotSame(t, first, second)
+	assert.Same(t, first, db.reader(ctx))
+}
+
+func TestDB_ReaderSkipsUnhealthyReplicas(t *testing.T) {
+	db := newTestDB(t, 2)
+	ctx := context.Background()
+
+	db.replicas[0].healthy.Store(false)
+	for i := 0; i < 4; i++ {
+		assert.Same(t, db.replicas[1].db, db.reader(ctx))
+	}
+
+	db.replicas[1].healthy.Store(false)
+	assert.Same(t, db.DB, db.reader(ctx))
+}
+
+func TestDB_ReadYourWrites(t *testing.T) {
+	db := newTestDB(t, 1)
+	writer := context.WithValue(context.Background(), sessionKey{}, "writer")
+	other := context.WithValue(context.Background(), sessionKey{}, "other")
+
+	db.Conn(writer)
+	assert.Same(t, db.DB, db.reader(writer))
+	assert.Same(t, db.replicas[0].db, db.reader(other))
+
+	// the window is over
+	db.writes.mark("writer", time.Now().Add(-time.Hour))
+	assert.Same(t, db.replicas[0].db, db.reader(writer))
+}
+
+func TestDB_CheckReplicas(t *testing.T) {
+	db := newTestDB(t, 1)
+
+	db.checkReplicas(context.Background())
+	assert.False(t, db.replicas[0].healthy.Load())
+	assert.Same(t, db.DB, db.reader(context.Background()))
+}
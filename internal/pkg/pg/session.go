@@ -0,0 +1,53 @@
+package pg
+
+import (
+	"sync"
+	"time"
+)
+
+// writeTracker remembers when each session last wrote to the primary
+type writeTracker struct {
+	mu        sync.Mutex
+	window    time.Duration
+	writes    map[string]time.Time
+	nextSweep time.Time
+}
+
+func newWriteTracker(window time.Duration) *writeTracker {
+	return &writeTracker{
+		window: window,
+		writes: make(map[string]time.Time),
+	}
+}
+
+func (t *writeTracker) mark(session string, now time.Time) {
+	if session == "" {
+		return
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.writes[session] = now
+	// expired sessions are dropped lazily, at most once per window
+	if now.After(t.nextSweep) {
+		for key, at := range t.writes {
+			if now.Sub(at) >= t.window {
+				delete(t.writes, key)
+			}
+		}
+		t.nextSweep = now.Add(t.window)
+	}
+}
+
+func (t *writeTracker) recent(session string, now time.Time) bool {
+	if session == "" {
+		return false
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	at, ok := t.writes[session]
+	return ok && now.Sub(at) < t.window
+}
@@ -0,0 +1,68 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// Migrate applies the pending "<version>_<name>.up.sql" files of source in
+// version order, each in its own transaction. golang-migrate is not used here
+// because its sqlite driver registers a second "sqlite" database/sql driver.
+func Migrate(ctx context.Context, db *DB, source fs.FS) error {
+	names, err := fs.Glob(source, "*.up.sql")
+	if err != nil {
+		return fmt.Errorf("unable to read migrations: %w", err)
+	}
+
+	type migration struct {
+		version uint64
+		name    string
+	}
+	migrations := make([]migration, 0, len(names))
+	for _, name := range names {
+		prefix, _, ok := strings.Cut(name, "_")
+		version, err := strconv.ParseUint(prefix, 10, 64)
+		if !ok || err != nil {
+			return fmt.Errorf("migration %s has no version prefix", name)
+		}
+		migrations = append(migrations, migration{version: version, name: name})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].version < migrations[j].version })
+
+	conn := db.DB.WithContext(ctx)
+	err = conn.Exec("CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY, applied_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP)").Error
+	if err != nil {
+		return fmt.Errorf("unable to create schema_migrations: %w", err)
+	}
+
+	var current uint64
+	if err := conn.Raw("SELECT coalesce(max(version), 0) FROM schema_migrations").Scan(&current).Error; err != nil {
+		return fmt.Errorf("unable to read schema version: %w", err)
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		script, err := fs.ReadFile(source, m.name)
+		if err != nil {
+			return fmt.Errorf("unable to read migration %s: %w", m.name, err)
+		}
+		err = conn.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Exec(string(script)).Error; err != nil {
+				return err
+			}
+			return tx.Exec("INSERT INTO schema_migrations (version) VALUES (?)", m.version).Error
+		})
+		if err != nil {
+			return fmt.Errorf("migration %s failed: %w", m.name, err)
+		}
+	}
+	return nil
+}
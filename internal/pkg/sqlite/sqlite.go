@@ -0,0 +1,41 @@
+package sqlite
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/glebarez/sqlite"
+	"gorm.io/gorm"
+)
+
+// pragmas applied to every connection
+const pragmas = "_pragma=foreign_keys(1)&_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+
+type DB struct {
+	*gorm.DB
+}
+
+// Open opens the database file at path, ":memory:" gives a throwaway database
+func Open(path string) (*DB, error) {
+	dsn := path
+	if strings.Contains(dsn, "?") {
+		dsn += "&" + pragmas
+	} else {
+		dsn += "?" + pragmas
+	}
+
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	if err != nil {
+		return nil, fmt.Errorf("unable to open sqlite database: %w", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, fmt.Errorf("unable to open sqlite database: %w", err)
+	}
+	// SQLite has a single writer anyway, one connection avoids SQLITE_BUSY on
+	// lock upgrades and keeps a ":memory:" database alive between calls
+	sqlDB.SetMaxOpenConns(1)
+
+	return &DB{db}, nil
+}
@@ -0,0 +1,29 @@
+package sqlite
+
+import (
+	"context"
+
+	"github.com/vlad19930514/webApp/internal/pkg/gormtx"
+	"gorm.io/gorm"
+)
+
+// TxManager runs functions inside a database transaction. The transaction
+// travels in the context, repositories pick it up through DB.Conn.
+type TxManager struct {
+	db *DB
+}
+
+func NewTxManager(db *DB) *TxManager {
+	return &TxManager{db: db}
+}
+
+// WithinTx commits if fn returns nil and rolls back otherwise. A nested call
+// runs in a savepoint of the transaction already in ctx, see gormtx.Within.
+func (m *TxManager) WithinTx(ctx context.Context, fn func(ctx context.Context) error) error {
+	return gormtx.Within(ctx, m.db.DB, fn)
+}
+
+// Conn returns the transaction bound to ctx, or the pool when there is none
+func (db *DB) Conn(ctx context.Context) *gorm.DB {
+	return gormtx.Conn(ctx, db.DB)
+}
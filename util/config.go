@@ -1,19 +1,85 @@
 package util
 
-import "github.com/spf13/viper"
+import (
+	"time"
+
+	"github.com/spf13/viper"
+)
 
 type Config struct {
 	DBSource      string `mapstructure:"DB_SOURCE"`
 	ServerAddress string `mapstructure:"SERVER_PORT"`
-	MigrationURL  string `mapstructure:"MIGRATION_URL"`
 	DSN           string `mapstructure:"DSN"`
+	// ReplicaDSNs are comma separated read replicas of DSN
+	ReplicaDSNs          []string      `mapstructure:"REPLICA_DSNS"`
+	ReadYourWritesWindow time.Duration `mapstructure:"READ_YOUR_WRITES_WINDOW"`
+	ReplicaCheckInterval time.Duration `mapstructure:"REPLICA_CHECK_INTERVAL"`
+	Storage              string        `mapstructure:"STORAGE"`
+	// SQLitePath is the database file used by the sqlite storage
+	SQLitePath string `mapstructure:"SQLITE_PATH"`
+	AdminToken string `mapstructure:"ADMIN_TOKEN"`
+	// TrustedProxies are comma separated IPs and CIDRs of the reverse proxies
+	// whose X-Forwarded-For gives the client IP, none are trusted when empty
+	TrustedProxies []string `mapstructure:"TRUSTED_PROXIES"`
+	// MigrateOnStart applies pending migrations before the server starts
+	MigrateOnStart bool `mapstructure:"MIGRATE_ON_START"`
+	// OutboxPublisher is where user events go: none, memory or ndjson
+	OutboxPublisher string        `mapstructure:"OUTBOX_PUBLISHER"`
+	OutboxFile      string        `mapstructure:"OUTBOX_FILE"`
+	OutboxInterval  time.Duration `mapstructure:"OUTBOX_INTERVAL"`
+	// EventStream serves the live feed of user events at GET /users/events
+	EventStream bool `mapstructure:"EVENT_STREAM"`
+	// IdempotencyTTL is how long a response is replayed for a repeated Idempotency-Key
+	IdempotencyTTL time.Duration `mapstructure:"IDEMPOTENCY_TTL"`
+	// MaxBatchSize caps the items of one batch request
+	MaxBatchSize int `mapstructure:"MAX_BATCH_SIZE"`
+	// WebhookTimeout bounds one delivery attempt, WebhookMaxAttempts failed
+	// attempts make a delivery dead
+	WebhookTimeout     time.Duration `mapstructure:"WEBHOOK_TIMEOUT"`
+	WebhookMaxAttempts int           `mapstructure:"WEBHOOK_MAX_ATTEMPTS"`
+	WebhookInterval    time.Duration `mapstructure:"WEBHOOK_INTERVAL"`
+	// AuthSigningKeys are comma separated "kid:base64secret" HMAC keys of the
+	// access tokens. The first one signs, all of them verify, see auth.ParseKeys.
+	AuthSigningKeys []string      `mapstructure:"AUTH_SIGNING_KEYS"`
+	AccessTokenTTL  time.Duration `mapstructure:"ACCESS_TOKEN_TTL"`
+	// RefreshTokenTTL is how long a session lasts without being refreshed
+	RefreshTokenTTL time.Duration `mapstructure:"REFRESH_TOKEN_TTL"`
+	// PasswordHashConcurrency caps the passwords hashed at once, each one
+	// takes 64 MiB
+	PasswordHashConcurrency int `mapstructure:"PASSWORD_HASH_CONCURRENCY"`
 }
 
+const (
+	StoragePostgres = "postgres"
+	StorageMemory   = "memory"
+	StorageSQLite   = "sqlite"
+
+	PublisherNone   = "none"
+	PublisherMemory = "memory"
+	PublisherNDJSON = "ndjson"
+)
+
 func LoadConfig(path string) (config Config, err error) {
 	viper.AddConfigPath(path)
 	viper.SetConfigName("app")
 	viper.SetConfigType("env")
 
+	viper.SetDefault("STORAGE", StoragePostgres)
+	viper.SetDefault("SQLITE_PATH", "webapp.db")
+	viper.SetDefault("READ_YOUR_WRITES_WINDOW", 5*time.Second)
+	viper.SetDefault("REPLICA_CHECK_INTERVAL", 10*time.Second)
+	viper.SetDefault("OUTBOX_PUBLISHER", PublisherNone)
+	viper.SetDefault("OUTBOX_FILE", "events.ndjson")
+	viper.SetDefault("OUTBOX_INTERVAL", time.Second)
+	viper.SetDefault("EVENT_STREAM", true)
+	viper.SetDefault("IDEMPOTENCY_TTL", 24*time.Hour)
+	viper.SetDefault("MAX_BATCH_SIZE", 100)
+	viper.SetDefault("WEBHOOK_TIMEOUT", 10*time.Second)
+	viper.SetDefault("WEBHOOK_MAX_ATTEMPTS", 8)
+	viper.SetDefault("WEBHOOK_INTERVAL", time.Second)
+	viper.SetDefault("ACCESS_TOKEN_TTL", 15*time.Minute)
+	viper.SetDefault("REFRESH_TOKEN_TTL", 30*24*time.Hour)
+	viper.SetDefault("PASSWORD_HASH_CONCURRENCY", 4)
 	viper.AutomaticEnv()
 
 	err = viper.ReadInConfig()
@@ -3,47 +3,93 @@ package util
 import (
 	"errors"
 	"fmt"
+	"reflect"
+	"strings"
 
+	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
 )
 
+// ErrorMsg describes one failed validation rule. Field is the path of the
+// field as the client sent it, Tag and Param are the rule, e.g. max and 130.
 type ErrorMsg struct {
 	Field   string `json:"field"`
+	Tag     string `json:"tag"`
+	Param   string `json:"param,omitempty"`
 	Message string `json:"message"`
 }
 
-func GetValidationErrors(err *error) ([]ErrorMsg, bool) {
+// GetValidationErrors converts validator errors into messages in the language
+// preferred by the Accept-Language header value
+func GetValidationErrors(err *error, acceptLanguage string) ([]ErrorMsg, bool) {
 	var ve validator.ValidationErrors
-	errors.As(*err, &ve)
-	fmt.Println(ve)
+	if !errors.As(*err, &ve) {
+		return nil, false
+	}
 
-	if errors.As(*err, &ve) {
+	trans := Translator(acceptLanguage)
+	out := make([]ErrorMsg, len(ve))
+	for i, fe := range ve {
+		field := fieldPath(fe)
+		out[i] = ErrorMsg{Field: field, Tag: fe.Tag(), Param: fe.Param(), Message: getErrorMsg(trans, field, fe)}
+	}
+	return out, true
+}
 
-		out := make([]ErrorMsg, len(ve))
-		for i, fe := range ve {
-			out[i] = ErrorMsg{Field: fe.Field(), Message: getErrorMsg(fe)}
+func getErrorMsg(trans ut.Translator, field string, fe validator.FieldError) string {
+	key := fe.Tag()
+	if key == "min" || key == "max" {
+		switch fe.Kind() {
+		case reflect.String, reflect.Slice, reflect.Map, reflect.Array:
+			key += "_len"
 		}
-		return out, true
 	}
-	return nil, false
+
+	return translateRule(trans, key, field, fe.Param(), fe.Value())
+}
+
+// ValidationMessage translates a rule broken outside of the validator, e.g. by
+// a domain invariant. The rule names follow the validator tags.
+func ValidationMessage(acceptLanguage, field, rule, param string, value any) string {
+	return translateRule(Translator(acceptLanguage), rule, field, param, value)
+}
+
+func translateRule(trans ut.Translator, key, field, param string, value any) string {
+	// {0} is the field, {1} the rule param or, for rules without one, the rejected value
+	arg := param
+	if arg == "" {
+		arg = fmt.Sprint(value)
+	}
+	msg, err := trans.T(key, field, arg)
+	if err != nil {
+		msg, _ = trans.T("unknown", field, key)
+	}
+	return msg
 }
-func getErrorMsg(fe validator.FieldError) string {
-
-	switch fe.Tag() {
-	case "required":
-		return "Это обязательное поле"
-	case "alpha":
-		return fmt.Sprintf("Передаем только буквы - %v", fe.Value())
-	case "email":
-		return fmt.Sprintf("Это не email - %v?", fe.Value())
-	case "age":
-		return fmt.Sprintf("Это не возраст - %v?", fe.Value())
-	case "max":
-		return fmt.Sprintf("Многовато будет - %v", fe.Value())
-	case "min":
-		return fmt.Sprintf("Маловато будет - %v", fe.Value())
-	case "uuid":
-		return fmt.Sprintf("Это что - %v?", fe.Value())
+
+// fieldPath drops the name of the validated struct from the namespace,
+// createUserRequest.firstname becomes firstname
+func fieldPath(fe validator.FieldError) string {
+	_, path, found := strings.Cut(fe.Namespace(), ".")
+	if !found {
+		return fe.Field()
 	}
-	return "Unknown error"
+	return path
+}
+
+// UseRequestFieldNames makes the validator report fields by their json, form
+// or uri names instead of the Go struct field names
+func UseRequestFieldNames(v *validator.Validate) {
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		for _, tag := range []string{"json", "form", "uri"} {
+			name, _, _ := strings.Cut(f.Tag.Get(tag), ",")
+			if name == "-" {
+				return ""
+			}
+			if name != "" {
+				return name
+			}
+		}
+		return ""
+	})
 }
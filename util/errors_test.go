@@ -1,46 +1,84 @@
 package util
 
 import (
+	"encoding/json"
 	"errors"
 	"testing"
 
 	"github.com/go-playground/validator/v10"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
-func TestGetValidationErrors(t *testing.T) {
+type testAddress struct {
+	City string `json:"city" validate:"required"`
+}
+
+type testRequest struct {
+	FirstName string        `json:"firstname" validate:"required,alpha"`
+	Email     string        `json:"email" validate:"required,email"`
+	Age       int           `json:"age" validate:"required,min=1,max=130"`
+	Query     string        `form:"q" validate:"max=5"`
+	ID        string        `uri:"id" validate:"omitempty,uuid"`
+	Domain    string        `json:"-" validate:"omitempty,fqdn"`
+	Addresses []testAddress `json:"addresses" validate:"dive"`
+}
+
+func newTestValidator() *validator.Validate {
 	validate := validator.New()
-	type TestStruct struct {
-		FirstName string `validate:"required,alpha"`
-		Email     string `validate:"required,email"`
-		Age       int    `validate:"required,min=1,max=130"`
-	}
+	UseRequestFieldNames(validate)
+	return validate
+}
+
+func TestGetValidationErrors(t *testing.T) {
+	validate := newTestValidator()
 
 	testCases := []struct {
-		name     string
-		input    TestStruct
-		expected []ErrorMsg
+		name           string
+		input          testRequest
+		acceptLanguage string
+		expected       []ErrorMsg
 	}{
 		{
 			name:     "valid input",
-			input:    TestStruct{FirstName: "John", Email: "john.doe@example.com", Age: 30},
+			input:    testRequest{FirstName: "John", Email: "john.doe@example.com", Age: 30},
 			expected: nil,
 		},
 		{
-			name:  "missing required fields",
-			input: TestStruct{FirstName: "", Email: "", Age: 0},
+			name:  "missing required fields default to russian",
+			input: testRequest{},
+			expected: []ErrorMsg{
+				{Field: "firstname", Tag: "required", Message: "Поле firstname обязательно"},
+				{Field: "email", Tag: "required", Message: "Поле email обязательно"},
+				{Field: "age", Tag: "required", Message: "Поле age обязательно"},
+			},
+		},
+		{
+			name:           "english",
+			input:          testRequest{FirstName: "John1", Email: "invalid-email", Age: 150},
+			acceptLanguage: "en-US,en;q=0.9,ru;q=0.8",
+			expected: []ErrorMsg{
+				{Field: "firstname", Tag: "alpha", Message: `firstname must contain only letters, got "John1"`},
+				{Field: "email", Tag: "email", Message: `email must be a valid email address, got "invalid-email"`},
+				{Field: "age", Tag: "max", Param: "130", Message: "age must be at most 130"},
+			},
+		},
+		{
+			name:           "string length, uri and form names",
+			input:          testRequest{FirstName: "John", Email: "john@example.com", Age: 30, Query: "abcdefgh", ID: "42", Domain: "not a domain"},
+			acceptLanguage: "en",
 			expected: []ErrorMsg{
-				{Field: "FirstName", Message: "Это обязательное поле"},
-				{Field: "Email", Message: "Это обязательное поле"},
-				{Field: "Age", Message: "Это обязательное поле"},
+				{Field: "q", Tag: "max", Param: "5", Message: "q must be at most 5 characters long"},
+				{Field: "id", Tag: "uuid", Message: `id must be a UUID, got "42"`},
+				{Field: "Domain", Tag: "fqdn", Message: `Domain must be a domain name, got "not a domain"`},
 			},
 		},
 		{
-			name:  "invalid email and age",
-			input: TestStruct{FirstName: "John", Email: "invalid-email", Age: -1},
+			name:           "nested path",
+			input:          testRequest{FirstName: "John", Email: "john@example.com", Age: 30, Addresses: []testAddress{{City: "Riga"}, {}}},
+			acceptLanguage: "ru-RU",
 			expected: []ErrorMsg{
-				{Field: "Email", Message: "Это не email - invalid-email?"},
-				{Field: "Age", Message: "Маловато будет - -1"},
+				{Field: "addresses[1].city", Tag: "required", Message: "Поле addresses[1].city обязательно"},
 			},
 		},
 	}
@@ -48,7 +86,7 @@ func TestGetValidationErrors(t *testing.T) {
 	for _, tc := range testCases {
 		t.Run(tc.name, func(t *testing.T) {
 			err := validate.Struct(tc.input)
-			customErrors, errorsExist := GetValidationErrors(&err)
+			customErrors, errorsExist := GetValidationErrors(&err, tc.acceptLanguage)
 
 			if tc.expected == nil {
 				assert.False(t, errorsExist)
@@ -61,60 +99,47 @@ func TestGetValidationErrors(t *testing.T) {
 	}
 }
 
-func TestGetErrorMsg(t *testing.T) {
-	validate := validator.New()
-	type TestStruct struct {
-		FirstName string `validate:"required,alpha"`
-		Email     string `validate:"required,email"`
-		Age       int    `validate:"required,min=1,max=130"`
-		UUID      string `validate:"required,uuid"`
+func TestGetValidationErrors_UnknownTag(t *testing.T) {
+	validate := newTestValidator()
+	type request struct {
+		Code string `json:"code" validate:"hexadecimal"`
 	}
 
-	testCases := []struct {
-		name     string
-		input    TestStruct
-		expected []ErrorMsg
-	}{
-		{
-			name:  "missing required fields",
-			input: TestStruct{FirstName: "", Email: "", Age: 0, UUID: ""},
-			expected: []ErrorMsg{
-				{Field: "FirstName", Message: "Это обязательное поле"},
-				{Field: "Email", Message: "Это обязательное поле"},
-				{Field: "Age", Message: "Это обязательное поле"},
-				{Field: "UUID", Message: "Это обязательное поле"},
-			},
-		},
-		{
-			name:  "invalid alpha and email",
-			input: TestStruct{FirstName: "John123", Email: "invalid-email", Age: 30, UUID: "123"},
-			expected: []ErrorMsg{
-				{Field: "FirstName", Message: "Передаем только буквы - John123"},
-				{Field: "Email", Message: "Это не email - invalid-email?"},
-				{Field: "UUID", Message: "Это что - 123?"},
-			},
-		},
-		{
-			name:  "age out of range",
-			input: TestStruct{FirstName: "John", Email: "john.doe@example.com", Age: 150, UUID: "123e4567-e89b-12d3-a456-426614174000"},
-			expected: []ErrorMsg{
-				{Field: "Age", Message: "Многовато будет - 150"},
-			},
-		},
-	}
+	err := validate.Struct(request{Code: "xyz"})
+	customErrors, ok := GetValidationErrors(&err, "en")
+	require.True(t, ok)
+	assert.Equal(t, `code failed the "hexadecimal" check`, customErrors[0].Message)
 
-	for _, tc := range testCases {
-		t.Run(tc.name, func(t *testing.T) {
-			err := validate.Struct(tc.input)
-			var ve validator.ValidationErrors
-			errors.As(err, &ve)
-
-			var customErrors []ErrorMsg
-			for _, fe := range ve {
-				customErrors = append(customErrors, ErrorMsg{Field: fe.Field(), Message: getErrorMsg(fe)})
-			}
+	other := errors.New("boom")
+	_, ok = GetValidationErrors(&other, "en")
+	assert.False(t, ok)
+}
 
-			assert.ElementsMatch(t, tc.expected, customErrors)
-		})
+// every catalogue has to translate every key the default one does
+func TestTranslationsComplete(t *testing.T) {
+	keys := func(locale string) []string {
+		data, err := translations.ReadFile("translations/" + locale + ".json")
+		require.NoError(t, err)
+		var entries []struct {
+			Locale string `json:"locale"`
+			Key    string `json:"key"`
+		}
+		require.NoError(t, json.Unmarshal(data, &entries))
+		var out []string
+		for _, e := range entries {
+			assert.Equal(t, locale, e.Locale)
+			out = append(out, e.Key)
+		}
+		return out
 	}
+
+	assert.ElementsMatch(t, keys(DefaultLocale), keys("en"))
+}
+
+func TestParseAcceptLanguage(t *testing.T) {
+	assert.Equal(t, []string{"en_US", "en", "ru"}, ParseAcceptLanguage("ru;q=0.5, en-US"))
+	assert.Equal(t, []string{"de", "en"}, ParseAcceptLanguage("fr;q=0, de, *;q=0.1, en;q=0.3"))
+	assert.Empty(t, ParseAcceptLanguage(""))
+	assert.Equal(t, "en", Translator("de, en;q=0.5").Locale())
+	assert.Equal(t, DefaultLocale, Translator("de").Locale())
 }
@@ -0,0 +1,88 @@
+package util
+
+import (
+	"bytes"
+	"embed"
+	"io/fs"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/go-playground/locales/en"
+	"github.com/go-playground/locales/ru"
+	ut "github.com/go-playground/universal-translator"
+)
+
+// translations holds one universal-translator JSON file per locale
+//
+//go:embed translations/*.json
+var translations embed.FS
+
+// DefaultLocale answers requests without a supported Accept-Language
+const DefaultLocale = "ru"
+
+var universalTranslator = mustLoadTranslations()
+
+func mustLoadTranslations() *ut.UniversalTranslator {
+	uni := ut.New(ru.New(), ru.New(), en.New())
+	files, err := fs.Glob(translations, "translations/*.json")
+	if err != nil {
+		panic(err)
+	}
+	for _, file := range files {
+		data, err := translations.ReadFile(file)
+		if err != nil {
+			panic(err)
+		}
+		if err := uni.ImportByReader(ut.FormatJSON, bytes.NewReader(data)); err != nil {
+			panic(file + ": " + err.Error())
+		}
+	}
+	return uni
+}
+
+// Translator picks the catalogue for an Accept-Language header value,
+// falling back to DefaultLocale
+func Translator(acceptLanguage string) ut.Translator {
+	trans, _ := universalTranslator.FindTranslator(ParseAcceptLanguage(acceptLanguage)...)
+	return trans
+}
+
+// ParseAcceptLanguage returns the locales of an Accept-Language header by
+// preference, a regional tag like en-US is followed by its base language
+func ParseAcceptLanguage(header string) []string {
+	type weighted struct {
+		tag string
+		q   float64
+	}
+
+	var tags []weighted
+	for _, part := range strings.Split(header, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		if tag == "" || tag == "*" {
+			continue
+		}
+		q := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			q = parsed
+		}
+		if q > 0 {
+			tags = append(tags, weighted{tag: tag, q: q})
+		}
+	}
+	sort.SliceStable(tags, func(i, j int) bool { return tags[i].q > tags[j].q })
+
+	locales := make([]string, 0, len(tags)*2)
+	for _, t := range tags {
+		locale := strings.ReplaceAll(t.tag, "-", "_")
+		locales = append(locales, locale)
+		if base, _, regional := strings.Cut(locale, "_"); regional {
+			locales = append(locales, base)
+		}
+	}
+	return locales
+}